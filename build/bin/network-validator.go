@@ -1,19 +1,25 @@
 package main
 
 // Usage
-// $ network-validator --timeout=1s --config=config/config.yaml
+// $ network-validator --timeout=1s --config=config/config.yaml [--platform=gcp]
 
 import (
+	"bufio"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/net/dns/dnsmessage"
 	"gopkg.in/yaml.v2"
 )
 
@@ -21,13 +27,32 @@ var (
 	maxRetries     = flag.Int("max-retries", 3, "Maximum connection attempts per endpoint")
 	timeout        = flag.Duration("timeout", 2000*time.Millisecond, "Timeout for each dial request made")
 	configFilePath = flag.String("config", "config.yaml", "Path to configuration file")
+	resolver       = flag.String("resolver", "", "DNS resolver to query, defaults to the first nameserver of /etc/resolv.conf")
+	platform       = flag.String("platform", defaultPlatform(), "Platform the validator runs on, selecting the endpoint profile of the configuration file, defaults to $PLATFORM or aws")
 )
 
+// awsVPCResolver is the link-local address of the VPC resolver, used when /etc/resolv.conf has no nameserver
+const awsVPCResolver = "169.254.169.253"
+
+// privateNetworks are the ranges a public name is not expected to resolve to,
+// DNS firewalls and sinkholes usually answer with one of them
+var privateNetworks = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+}
+
 type reachabilityConfig struct {
-	Endpoints []endpoint `yaml:"endpoints"`
+	Endpoints []endpoint            `yaml:"endpoints"`
+	Profiles  map[string][]endpoint `yaml:"profiles"`
 }
 
-func (c *reachabilityConfig) LoadFromYaml(filePath string) error {
+// LoadFromYaml reads the endpoints shared by every platform and the ones of the platform's profile
+func (c *reachabilityConfig) LoadFromYaml(filePath, platform string) error {
 	buf, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return err
@@ -38,9 +63,22 @@ func (c *reachabilityConfig) LoadFromYaml(filePath string) error {
 	if err != nil {
 		return err
 	}
+	profile, ok := c.Profiles[platform]
+	if !ok {
+		return fmt.Errorf("no endpoint profile for platform %q", platform)
+	}
+	c.Endpoints = append(c.Endpoints, profile...)
 	return nil
 }
 
+// defaultPlatform returns the platform given by the probe in the PLATFORM environment variable, or aws
+func defaultPlatform() string {
+	if p := os.Getenv("PLATFORM"); p != "" {
+		return p
+	}
+	return "aws"
+}
+
 type endpoint struct {
 	Host        string `yaml:"host"`
 	Ports       []int  `yaml:"ports"`
@@ -50,16 +88,179 @@ type endpoint struct {
 func main() {
 	flag.Parse()
 	config := reachabilityConfig{}
-	err := config.LoadFromYaml(*configFilePath)
+	err := config.LoadFromYaml(*configFilePath, *platform)
 	if err != nil {
 		err = fmt.Errorf("unable to reach config file %v: %v", configFilePath, err)
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	// the probes check this line, to tell images verifying the profiles from older ones
+	fmt.Printf("Using the %s endpoint profile\n", *platform)
 
+	TestResolution(config, resolverAddress())
 	TestEndpoints(config)
 }
 
+// resolverAddress returns the host:port of the resolver given on the command line,
+// or of the first nameserver of /etc/resolv.conf
+func resolverAddress() string {
+	address := *resolver
+	if address == "" {
+		address = awsVPCResolver
+		if f, err := os.Open("/etc/resolv.conf"); err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) >= 2 && fields[0] == "nameserver" {
+					address = fields[1]
+					break
+				}
+			}
+		}
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+
+	return address
+}
+
+// TestResolution resolves every host through the resolver and prints one result per host:
+// DNS result <host> via <resolver>: <OK|PRIVATE|NXDOMAIN|SERVFAIL|REFUSED|NOANSWER|TIMEOUT|ERROR> [addresses or error]
+func TestResolution(config reachabilityConfig, resolver string) {
+	fmt.Printf("DNS resolver: %s\n", resolver)
+
+	var waitGroup sync.WaitGroup
+	results := make(chan string, len(config.Endpoints))
+	for _, e := range config.Endpoints {
+		waitGroup.Add(1)
+		go func(host string) {
+			defer waitGroup.Done()
+			result, detail := ValidateResolution(host, resolver)
+			results <- strings.TrimSpace(fmt.Sprintf("DNS result %s via %s: %s %s", host, resolver, result, detail))
+		}(e.Host)
+	}
+	waitGroup.Wait()
+	close(results)
+
+	for r := range results {
+		fmt.Println(r)
+	}
+}
+
+// ValidateResolution queries the A records of the host and classifies the answer
+func ValidateResolution(host, resolver string) (string, string) {
+	var (
+		answer *dnsmessage.Message
+		err    error
+	)
+	// Retry up to maxRetries times, only timeouts are retried
+	for i := 0; i < *maxRetries; i++ {
+		answer, err = queryA(host, resolver)
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			break
+		}
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return "TIMEOUT", fmt.Sprintf("no answer within %s after %d retries", *timeout, *maxRetries)
+	}
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+
+	switch answer.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN", ""
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL", ""
+	case dnsmessage.RCodeRefused:
+		return "REFUSED", ""
+	default:
+		return "ERROR", answer.Header.RCode.String()
+	}
+
+	var addresses, private []string
+	for _, a := range answer.Answers {
+		record, ok := a.Body.(*dnsmessage.AResource)
+		if !ok {
+			continue
+		}
+		ip := net.IP(record.A[:])
+		addresses = append(addresses, ip.String())
+		if isPrivate(ip) {
+			private = append(private, ip.String())
+		}
+	}
+	switch {
+	case len(addresses) == 0:
+		return "NOANSWER", ""
+	case len(private) > 0:
+		return "PRIVATE", strings.Join(private, ",")
+	}
+
+	return "OK", strings.Join(addresses, ",")
+}
+
+// queryA sends a recursive A query for the host to the resolver over UDP
+func queryA(host, resolver string) (*dnsmessage.Message, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+	query, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("udp", resolver, *timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(*timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var answer dnsmessage.Message
+		if err := answer.Unpack(buf[:n]); err != nil {
+			return nil, err
+		}
+		// ignore stray answers to other queries
+		if answer.Header.ID == id {
+			return &answer, nil
+		}
+	}
+}
+
+func isPrivate(ip net.IP) bool {
+	for _, cidr := range privateNetworks {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func TestEndpoints(config reachabilityConfig) {
 	// TODO how would we check for wildcard entries like the `.quay.io` entry, where we
 	// need to validate any CDN such as `cdn01.quay.io` should be available?
@@ -108,7 +309,7 @@ func TestEndpoints(config reachabilityConfig) {
 
 func ValidateReachability(host string, port int, tlsDisabled bool) error {
 	var err error
-	endpoint := fmt.Sprintf("%s:%d", host, port)
+	endpoint := net.JoinHostPort(host, strconv.Itoa(port))
 	httpClient := http.Client{
 		Timeout: *timeout,
 	}
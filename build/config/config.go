@@ -0,0 +1,9 @@
+// Package config embeds the egress endpoint list, so that it can be evaluated without the network validator image
+package config
+
+import (
+	_ "embed"
+)
+
+//go:embed config.yaml
+var EndpointsYAML []byte
@@ -5,22 +5,66 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/cmd/creds"
 	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 	"github.com/spf13/cobra"
 )
 
-var debug bool
+var (
+	regionEnvVarStr string = "AWS_REGION"
+	regionDefault   string = "us-east-2"
+)
+
+type byovpcConfig struct {
+	vpcID       string
+	subnetIDs   []string
+	private     bool
+	machineCIDR string
+	serviceCIDR string
+	podCIDR     string
+	nodes       int
+	lbIPs       int
+	region      string
+	debug       bool
+	platform    string
+	awsFlags    creds.AWSFlags
+	gcpFlags    creds.GCPFlags
+	azureFlags  creds.AzureFlags
+}
+
+func getDefaultRegion() string {
+	val, present := os.LookupEnv(regionEnvVarStr)
+	if present {
+		return val
+	} else {
+		return regionDefault
+	}
+}
 
 func NewCmdByovpc() *cobra.Command {
+	config := byovpcConfig{}
+
 	byovpcCmd := &cobra.Command{
 		Use:   "byovpc",
 		Short: "Verify subnet configuration of a specific VPC",
+		Long:  `Verify that the given subnets meet the BYOVPC requirements for OSD/ROSA clusters.`,
+		Example: `# Verify the subnets of a public cluster spread across two availability zones
+./osd-network-verifier byovpc --subnet-ids $(PUBLIC_SUBNET_A),$(PRIVATE_SUBNET_A),$(PUBLIC_SUBNET_B),$(PRIVATE_SUBNET_B)
+
+# Verify the subnets of a PrivateLink cluster with a custom machine CIDR
+./osd-network-verifier byovpc --subnet-ids $(PRIVATE_SUBNET_A) --private --machine-cidr 10.0.0.0/16
+
+# Verify the control plane and compute subnetworks of an OSD cluster on GCP
+./osd-network-verifier byovpc --platform gcp --project $(PROJECT) --region us-east1 --subnet $(CONTROL_PLANE_SUBNETWORK),$(COMPUTE_SUBNETWORK)
+
+# Verify the control plane and compute subnets of a cluster on Azure, given by their resource IDs
+./osd-network-verifier byovpc --platform azure --subscription-id $(SUBSCRIPTION) --region eastus --subnet-ids $(CONTROL_PLANE_SUBNET_ID),$(COMPUTE_SUBNET_ID)`,
 		Run: func(cmd *cobra.Command, args []string) {
 			// Create logger
 			builder := ocmlog.NewStdLoggerBuilder()
-			builder.Debug(debug)
+			builder.Debug(config.debug)
 			logger, err := builder.Build()
 			if err != nil {
 				fmt.Printf("Unable to build logger: %s\n", err.Error())
@@ -29,23 +73,71 @@ func NewCmdByovpc() *cobra.Command {
 
 			ctx := context.TODO()
 
-			creds := credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"))
-
-			// TODO when this command is actually used, most if not all of the following should be command line options
-			region := os.Getenv("AWS_REGION")
-			instanceType := "t3.micro"
-			tags := map[string]string{}
+			if len(config.subnetIDs) == 0 {
+				logger.Error(ctx, "At least one subnet is required, use --subnet-ids (or --subnet on GCP)")
+				os.Exit(1)
+			}
 
-			var cli cloudclient.CloudClient
-			cli, err = cloudclient.NewClient(ctx, logger, creds, region, instanceType, tags)
+			var credentials cloudclient.Credentials
+			var instanceType string
+			switch config.platform {
+			case cloudclient.PlatformAWS:
+				credentials = config.awsFlags.Credentials()
+				// The use of t3.micro here is arbitrary; we just need to provide any valid machine type
+				instanceType = "t3.micro"
+			case cloudclient.PlatformGCP:
+				gcpCreds, err := config.gcpFlags.Credentials(ctx)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				logger.Info(ctx, "Using GCP project: %s", gcpCreds.Credentials.ProjectID)
+				if config.region, err = config.gcpFlags.Region(cmd.Flags()); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				credentials = gcpCreds
+			case cloudclient.PlatformAzure:
+				azureCreds, err := config.azureFlags.Credentials(ctx)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				logger.Info(ctx, "Using Azure subscription: %s", azureCreds.SubscriptionID)
+				if config.region, err = config.azureFlags.Region(cmd.Flags()); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				credentials = azureCreds
+			default:
+				logger.Error(ctx, "Platform %s is not supported by this command yet", config.platform)
+				os.Exit(1)
+			}
+			logger.Info(ctx, "Using region: %s", config.region)
+			cli, err := cloudclient.NewClient(ctx, logger, config.platform, credentials, cloudclient.Config{
+				Region:       config.region,
+				Zone:         config.gcpFlags.Zone(),
+				HostProject:  config.gcpFlags.HostProject(),
+				InstanceType: instanceType,
+			})
 			if err != nil {
 				logger.Error(ctx, err.Error())
 				os.Exit(1)
 			}
 
-			err = cli.ByoVPCValidator(ctx)
-			if err != nil {
-				logger.Error(ctx, err.Error())
+			out := cli.ByoVPCValidator(ctx, options.ByoVPCOptions{
+				VpcID:           config.vpcID,
+				SubnetIDs:       config.subnetIDs,
+				Private:         config.private,
+				MachineCIDR:     config.machineCIDR,
+				ServiceCIDR:     config.serviceCIDR,
+				PodCIDR:         config.podCIDR,
+				ExpectedNodes:   config.nodes,
+				LoadBalancerIPs: config.lbIPs,
+			})
+			out.Summary()
+			if !out.IsSuccessful() {
+				logger.Error(ctx, "Failure!")
 				os.Exit(1)
 			}
 
@@ -53,7 +145,22 @@ func NewCmdByovpc() *cobra.Command {
 		},
 	}
 
-	byovpcCmd.Flags().BoolVar(&debug, "debug", false, "If true, enable additional debug-level logging")
+	byovpcCmd.Flags().StringSliceVar(&config.subnetIDs, "subnet-ids", nil, "comma-separated list of the subnet IDs the cluster will use")
+	byovpcCmd.Flags().StringVar(&config.vpcID, "vpc-id", "", "(optional) ID of the VPC the subnets must belong to. Defaults to the VPC of the first subnet")
+	byovpcCmd.Flags().BoolVar(&config.private, "private", false, "(optional) if true, verify the subnets of a PrivateLink cluster, which only uses private subnets")
+	byovpcCmd.Flags().StringVar(&config.machineCIDR, "machine-cidr", "", "(optional) machine CIDR of the cluster, it must contain every subnet")
+	byovpcCmd.Flags().StringVar(&config.serviceCIDR, "service-cidr", options.DefaultServiceCIDR, "(optional) service CIDR of the cluster")
+	byovpcCmd.Flags().StringVar(&config.podCIDR, "pod-cidr", options.DefaultPodCIDR, "(optional) pod CIDR of the cluster")
+	byovpcCmd.Flags().IntVar(&config.nodes, "expected-nodes", options.DefaultExpectedNodes, "(optional) number of nodes the cluster is expected to scale to, they are spread across the private subnets")
+	byovpcCmd.Flags().IntVar(&config.lbIPs, "load-balancer-ips", options.DefaultLoadBalancerIPs, "(optional) number of addresses to keep free in every subnet for load balancers")
+	byovpcCmd.Flags().StringVar(&config.region, "region", getDefaultRegion(), fmt.Sprintf("(optional) region of the VPC. Defaults to exported var %[1]v or '%[2]v' if not %[1]v set", regionEnvVarStr, regionDefault))
+	byovpcCmd.Flags().StringVar(&config.platform, "platform", cloudclient.PlatformAWS, fmt.Sprintf("(optional) cloud platform of the VPC, one of %v", cloudclient.Platforms()))
+	byovpcCmd.Flags().StringVar(&config.vpcID, "network", "", "(optional) GCP network name or URL, same as --vpc-id")
+	byovpcCmd.Flags().StringSliceVar(&config.subnetIDs, "subnet", nil, "GCP subnetwork names, same as --subnet-ids. The first one hosts the control plane, the others the compute nodes")
+	config.awsFlags.AddFlags(byovpcCmd.Flags())
+	config.gcpFlags.AddFlags(byovpcCmd.Flags())
+	config.azureFlags.AddFlags(byovpcCmd.Flags())
+	byovpcCmd.Flags().BoolVar(&config.debug, "debug", false, "(optional) if true, enable additional debug-level logging")
 
 	return byovpcCmd
 }
@@ -0,0 +1,36 @@
+package creds
+
+import (
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/spf13/pflag"
+)
+
+// AWSFlags holds the command line options shared by every command to select AWS credentials
+type AWSFlags struct {
+	profile     string
+	roleARNs    []string
+	externalID  string
+	sessionName string
+}
+
+// AddFlags registers the AWS credential flags on the given flag set
+func (f *AWSFlags) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.profile, "profile", "", "(optional) AWS profile. If absent, the default credential chain is used (environment, SSO, web identity, instance role)")
+	flags.StringSliceVar(&f.roleARNs, "role-arn", nil, "(optional) ARN of an IAM role to assume. Repeat the flag to chain roles, they are assumed in the given order")
+	flags.StringVar(&f.externalID, "external-id", "", "(optional) external ID used when assuming the last role given with --role-arn")
+	flags.StringVar(&f.sessionName, "role-session-name", cloudclient.DefaultAWSRoleSessionName, "(optional) session name used when assuming roles given with --role-arn")
+}
+
+// Credentials builds the AWS credentials described by the flags
+func (f *AWSFlags) Credentials() cloudclient.AWSCredentials {
+	creds := cloudclient.AWSCredentials{Profile: f.profile}
+	for i, arn := range f.roleARNs {
+		role := cloudclient.AWSRole{ARN: arn, SessionName: f.sessionName}
+		if i == len(f.roleARNs)-1 {
+			role.ExternalID = f.externalID
+		}
+		creds.RoleChain = append(creds.RoleChain, role)
+	}
+
+	return creds
+}
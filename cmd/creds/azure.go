@@ -0,0 +1,57 @@
+package creds
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/spf13/pflag"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// azureScope covers the Azure Resource Manager API
+const azureScope = "https://management.azure.com/.default"
+
+// AzureFlags holds the command line options shared by every command to select the Azure service principal and subscription.
+// The client secret is only read from the AZURE_CLIENT_SECRET environment variable, to keep it out of the process list.
+type AzureFlags struct {
+	tenantID       string
+	clientID       string
+	subscriptionID string
+}
+
+// AddFlags registers the Azure credential flags on the given flag set
+func (f *AzureFlags) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.tenantID, "tenant-id", os.Getenv("AZURE_TENANT_ID"), "(optional) Azure tenant of the service principal. Defaults to $AZURE_TENANT_ID")
+	flags.StringVar(&f.clientID, "client-id", os.Getenv("AZURE_CLIENT_ID"), "(optional) Azure service principal client ID, its secret is read from $AZURE_CLIENT_SECRET. Defaults to $AZURE_CLIENT_ID")
+	flags.StringVar(&f.subscriptionID, "subscription-id", os.Getenv("AZURE_SUBSCRIPTION_ID"), "(optional) Azure subscription. Defaults to $AZURE_SUBSCRIPTION_ID")
+}
+
+// Credentials builds the Azure credentials of the service principal described by the flags
+func (f *AzureFlags) Credentials(ctx context.Context) (cloudclient.AzureCredentials, error) {
+	secret := os.Getenv("AZURE_CLIENT_SECRET")
+	if f.tenantID == "" || f.clientID == "" || secret == "" {
+		return cloudclient.AzureCredentials{}, fmt.Errorf("Azure credentials require --tenant-id, --client-id and the AZURE_CLIENT_SECRET environment variable")
+	}
+	if f.subscriptionID == "" {
+		return cloudclient.AzureCredentials{}, fmt.Errorf("an Azure subscription is required, use --subscription-id")
+	}
+	config := clientcredentials.Config{
+		ClientID:     f.clientID,
+		ClientSecret: secret,
+		TokenURL:     fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", f.tenantID),
+		Scopes:       []string{azureScope},
+	}
+
+	return cloudclient.AzureCredentials{TokenSource: config.TokenSource(ctx), SubscriptionID: f.subscriptionID}, nil
+}
+
+// Region returns the region given with --region, whose default is an AWS one
+func (f *AzureFlags) Region(flags *pflag.FlagSet) (string, error) {
+	if !flags.Changed("region") {
+		return "", fmt.Errorf("--region is required on Azure, e.g. eastus")
+	}
+
+	return flags.GetString("region")
+}
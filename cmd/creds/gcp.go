@@ -0,0 +1,87 @@
+package creds
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/spf13/pflag"
+	"golang.org/x/oauth2/google"
+)
+
+// gcpScopes covers the Compute Engine and Cloud DNS APIs
+var gcpScopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+
+// GCPFlags holds the command line options shared by every command to select GCP credentials, project and zone
+type GCPFlags struct {
+	credentialsFile string
+	project         string
+	hostProject     string
+	zone            string
+}
+
+// AddFlags registers the GCP credential flags on the given flag set
+func (f *GCPFlags) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&f.credentialsFile, "gcp-credentials-file", "", "(optional) GCP service account key file. If absent, Application Default Credentials are used")
+	flags.StringVar(&f.project, "project", "", "(optional) GCP project. Defaults to the project of the credentials")
+	flags.StringVar(&f.hostProject, "host-project", "", "(optional) GCP Shared VPC host project owning the network. Defaults to --project")
+	flags.StringVar(&f.zone, "zone", "", "(optional) GCP zone of the probe instance. Defaults to the first zone of the region")
+}
+
+// Credentials builds the GCP credentials described by the flags, for the project given with --project if any
+func (f *GCPFlags) Credentials(ctx context.Context) (cloudclient.GCPCredentials, error) {
+	var creds *google.Credentials
+	if f.credentialsFile != "" {
+		data, err := ioutil.ReadFile(f.credentialsFile)
+		if err != nil {
+			return cloudclient.GCPCredentials{}, fmt.Errorf("unable to read GCP credentials file: %w", err)
+		}
+		if creds, err = google.CredentialsFromJSON(ctx, data, gcpScopes...); err != nil {
+			return cloudclient.GCPCredentials{}, fmt.Errorf("unable to parse GCP credentials file %s: %w", f.credentialsFile, err)
+		}
+	} else {
+		var err error
+		if creds, err = google.FindDefaultCredentials(ctx, gcpScopes...); err != nil {
+			return cloudclient.GCPCredentials{}, fmt.Errorf("unable to find GCP Application Default Credentials: %w", err)
+		}
+	}
+
+	if f.project != "" {
+		creds.ProjectID = f.project
+	}
+	if creds.ProjectID == "" {
+		return cloudclient.GCPCredentials{}, fmt.Errorf("the GCP credentials don't name a project, use --project")
+	}
+
+	return cloudclient.GCPCredentials{Credentials: creds}, nil
+}
+
+// HostProject returns the Shared VPC host project given with --host-project, if any
+func (f *GCPFlags) HostProject() string {
+	return f.hostProject
+}
+
+// Zone returns the zone given with --zone, if any
+func (f *GCPFlags) Zone() string {
+	return f.zone
+}
+
+// Region returns the region given with --region, or else the region of the zone given with --zone
+func (f *GCPFlags) Region(flags *pflag.FlagSet) (string, error) {
+	// the default region is an AWS one
+	if flags.Changed("region") {
+		return flags.GetString("region")
+	}
+	if f.zone == "" {
+		return "", fmt.Errorf("either --region or --zone is required on GCP")
+	}
+	// zones are named after their region, e.g. us-east1-b
+	i := strings.LastIndex(f.zone, "-")
+	if i <= 0 {
+		return "", fmt.Errorf("invalid GCP zone %q", f.zone)
+	}
+
+	return f.zone[:i], nil
+}
@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/cmd/creds"
 	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 	"github.com/spf13/cobra"
 )
 
@@ -17,9 +18,16 @@ var (
 )
 
 type dnsConfig struct {
-	vpcID  string
-	debug  bool
-	region string
+	vpcID      string
+	subnetID   string
+	imageID    string
+	kmsKeyID   string
+	debug      bool
+	region     string
+	platform   string
+	awsFlags   creds.AWSFlags
+	gcpFlags   creds.GCPFlags
+	azureFlags creds.AzureFlags
 }
 
 func getDefaultRegion() string {
@@ -48,16 +56,64 @@ func NewCmdValidateDns() *cobra.Command {
 				os.Exit(1)
 			}
 
+			if config.vpcID == "" {
+				logger.Error(ctx, "A VPC is required, use --vpc-id (or --network on GCP)")
+				os.Exit(1)
+			}
+
+			var credentials cloudclient.Credentials
+			var instanceType string
+			switch config.platform {
+			case cloudclient.PlatformAWS:
+				credentials = config.awsFlags.Credentials()
+				// The use of t3.micro here is arbitrary; we just need to provide any valid machine type
+				instanceType = "t3.micro"
+			case cloudclient.PlatformGCP:
+				gcpCreds, err := config.gcpFlags.Credentials(ctx)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				logger.Info(ctx, "Using GCP project: %s", gcpCreds.Credentials.ProjectID)
+				if config.region, err = config.gcpFlags.Region(cmd.Flags()); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				credentials = gcpCreds
+			case cloudclient.PlatformAzure:
+				azureCreds, err := config.azureFlags.Credentials(ctx)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				logger.Info(ctx, "Using Azure subscription: %s", azureCreds.SubscriptionID)
+				if config.region, err = config.azureFlags.Region(cmd.Flags()); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				credentials = azureCreds
+			default:
+				logger.Error(ctx, "Platform %s is not supported by this command yet", config.platform)
+				os.Exit(1)
+			}
 			logger.Warn(ctx, "Using region: %s", config.region)
-			creds := credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"))
-			// The use of t3.micro here is arbitrary; we just need to provide any valid machine type
-			cli, err := cloudclient.NewClient(ctx, logger, creds, config.region, "t3.micro", nil)
+			cli, err := cloudclient.NewClient(ctx, logger, config.platform, credentials, cloudclient.Config{
+				Region:       config.region,
+				Zone:         config.gcpFlags.Zone(),
+				HostProject:  config.gcpFlags.HostProject(),
+				InstanceType: instanceType,
+			})
 			if err != nil {
 				logger.Error(ctx, err.Error())
 				os.Exit(1)
 			}
 
-			out := cli.VerifyDns(ctx, config.vpcID)
+			out := cli.VerifyDns(ctx, options.DnsOptions{
+				VpcID:        config.vpcID,
+				SubnetID:     config.subnetID,
+				CloudImageID: config.imageID,
+				KmsKeyID:     config.kmsKeyID,
+			})
 			out.Summary()
 			if !out.IsSuccessful() {
 				logger.Error(ctx, "Failure!")
@@ -69,14 +125,18 @@ func NewCmdValidateDns() *cobra.Command {
 	}
 
 	validateDnsCmd.Flags().StringVar(&config.vpcID, "vpc-id", "", "ID of the VPC under test")
+	validateDnsCmd.Flags().StringVar(&config.subnetID, "subnet-id", "", "ID of a subnet of the VPC, if set the egress endpoints are resolved through the VPC resolver from a probe instance in it")
+	validateDnsCmd.Flags().StringVar(&config.imageID, "image-id", "", "(optional) cloud image for the probe instance")
+	validateDnsCmd.Flags().StringVar(&config.kmsKeyID, "kms-key-id", "", "(optional) ID of KMS key used to encrypt root volumes of the probe instance")
 	validateDnsCmd.Flags().StringVar(&config.region, "region", getDefaultRegion(), fmt.Sprintf("Region to validate. Defaults to exported var %[1]v or '%[2]v' if not %[1]v set", regionEnvVarStr, regionDefault))
+	validateDnsCmd.Flags().StringVar(&config.platform, "platform", cloudclient.PlatformAWS, fmt.Sprintf("Cloud platform of the VPC, one of %v", cloudclient.Platforms()))
+	validateDnsCmd.Flags().StringVar(&config.vpcID, "network", "", "GCP network name or URL, same as --vpc-id")
+	validateDnsCmd.Flags().StringVar(&config.subnetID, "subnet", "", "GCP subnetwork name or URL, same as --subnet-id")
+	config.awsFlags.AddFlags(validateDnsCmd.Flags())
+	config.gcpFlags.AddFlags(validateDnsCmd.Flags())
+	config.azureFlags.AddFlags(validateDnsCmd.Flags())
 	validateDnsCmd.Flags().BoolVar(&config.debug, "debug", false, "If true, enable additional debug-level logging")
 
-	if err := validateDnsCmd.MarkFlagRequired("vpc-id"); err != nil {
-		validateDnsCmd.PrintErr(err)
-		os.Exit(1)
-	}
-
 	return validateDnsCmd
 
 }
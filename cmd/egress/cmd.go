@@ -6,9 +6,10 @@ import (
 	"os"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/cmd/creds"
 	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 	"github.com/spf13/cobra"
 )
 
@@ -19,15 +20,20 @@ var (
 )
 
 type egressConfig struct {
-	vpcSubnetID  string
-	cloudImageID string
-	instanceType string
-	cloudTags    map[string]string
-	debug        bool
-	region       string
-	timeout      time.Duration
-	kmsKeyID     string
-	awsProfile   string
+	vpcSubnetID      string
+	cloudImageID     string
+	instanceType     string
+	cloudTags        map[string]string
+	debug            bool
+	region           string
+	timeout          time.Duration
+	kmsKeyID         string
+	mode             string
+	securityGroupIDs []string
+	awsFlags         creds.AWSFlags
+	gcpFlags         creds.GCPFlags
+	azureFlags       creds.AzureFlags
+	platform         string
 }
 
 func getDefaultRegion() string {
@@ -45,12 +51,20 @@ func NewCmdValidateEgress() *cobra.Command {
 		Use:   "egress",
 		Short: "Verify essential openshift domains are reachable from given subnet ID.",
 		Long:  `Verify essential openshift domains are reachable from given subnet ID.`,
-		Example: `For AWS, ensure your credential environment vars 
-AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY (also AWS_SESSION_TOKEN for STS credentials) 
-are set correctly before execution.
+		Example: `For AWS, credentials are taken from --profile or the default credential chain
+(e.g. AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, SSO, web identity or instance role).
+Use --role-arn (repeatable) with --external-id to reach an account through a chain of roles.
+For GCP, credentials are taken from --gcp-credentials-file or the Application Default Credentials.
+For Azure, credentials are a service principal given with --tenant-id, --client-id and AZURE_CLIENT_SECRET.
 
 # Verify that essential openshift domains are reachable from a given SUBNET_ID
-./osd-network-verifier egress --subnet-id $(SUBNET_ID) --image-id $(IMAGE_ID)`,
+./osd-network-verifier egress --subnet-id $(SUBNET_ID) --image-id $(IMAGE_ID)
+
+# Verify that essential openshift domains are reachable from a given GCP subnetwork
+./osd-network-verifier egress --platform gcp --project $(PROJECT) --region us-east1 --subnet $(SUBNETWORK)
+
+# Verify that essential openshift domains are reachable from a given Azure subnet, given by its resource ID
+./osd-network-verifier egress --platform azure --subscription-id $(SUBSCRIPTION) --region eastus --subnet-id $(SUBNET_RESOURCE_ID)`,
 		Run: func(cmd *cobra.Command, args []string) {
 			// ctx
 			ctx := context.TODO()
@@ -63,20 +77,78 @@ are set correctly before execution.
 				fmt.Printf("Unable to build logger: %s\n", err.Error())
 				os.Exit(1)
 			}
-			logger.Info(ctx, "Using region: %s", config.region)
-			var creds interface{}
-			if config.awsProfile != "" {
-				creds = config.awsProfile
-				logger.Info(ctx, "Using AWS profile: %s", config.awsProfile)
-			} else {
-				creds = credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"))
+			if config.vpcSubnetID == "" {
+				logger.Error(ctx, "A subnet is required, use --subnet-id (or --subnet on GCP)")
+				os.Exit(1)
+			}
+
+			var credentials cloudclient.Credentials
+			switch config.platform {
+			case cloudclient.PlatformAWS:
+				awsCreds := config.awsFlags.Credentials()
+				if awsCreds.Profile != "" {
+					logger.Info(ctx, "Using AWS profile: %s", awsCreds.Profile)
+				}
+				for _, role := range awsCreds.RoleChain {
+					logger.Info(ctx, "Assuming AWS role: %s", role.ARN)
+				}
+				credentials = awsCreds
+			case cloudclient.PlatformGCP:
+				gcpCreds, err := config.gcpFlags.Credentials(ctx)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				logger.Info(ctx, "Using GCP project: %s", gcpCreds.Credentials.ProjectID)
+				if config.region, err = config.gcpFlags.Region(cmd.Flags()); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				// the default instance type is an AWS one, the GCP client picks its own
+				if !cmd.Flags().Changed("instance-type") {
+					config.instanceType = ""
+				}
+				credentials = gcpCreds
+			case cloudclient.PlatformAzure:
+				azureCreds, err := config.azureFlags.Credentials(ctx)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				logger.Info(ctx, "Using Azure subscription: %s", azureCreds.SubscriptionID)
+				if config.region, err = config.azureFlags.Region(cmd.Flags()); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				// the default instance type is an AWS one, the Azure client picks its own
+				if !cmd.Flags().Changed("instance-type") {
+					config.instanceType = ""
+				}
+				credentials = azureCreds
+			default:
+				logger.Error(ctx, "Platform %s is not supported by this command yet", config.platform)
+				os.Exit(1)
 			}
-			cli, err := cloudclient.NewClient(ctx, logger, creds, config.region, config.instanceType, config.cloudTags)
+			logger.Info(ctx, "Using region: %s", config.region)
+			cli, err := cloudclient.NewClient(ctx, logger, config.platform, credentials, cloudclient.Config{
+				Region:       config.region,
+				Zone:         config.gcpFlags.Zone(),
+				HostProject:  config.gcpFlags.HostProject(),
+				InstanceType: config.instanceType,
+				Tags:         config.cloudTags,
+			})
 			if err != nil {
 				logger.Error(ctx, err.Error())
 				os.Exit(1)
 			}
-			out := cli.ValidateEgress(ctx, config.vpcSubnetID, config.cloudImageID, config.kmsKeyID, config.timeout)
+			out := cli.ValidateEgress(ctx, options.EgressOptions{
+				Mode:             options.EgressMode(config.mode),
+				SubnetID:         config.vpcSubnetID,
+				SecurityGroupIDs: config.securityGroupIDs,
+				CloudImageID:     config.cloudImageID,
+				KmsKeyID:         config.kmsKeyID,
+				Timeout:          config.timeout,
+			})
 			out.Summary()
 			if !out.IsSuccessful() {
 				logger.Error(ctx, "Failure!")
@@ -93,14 +165,15 @@ are set correctly before execution.
 	validateEgressCmd.Flags().StringVar(&config.region, "region", getDefaultRegion(), fmt.Sprintf("(optional) compute instance region. If absent, environment var %[1]v will be used, if set", regionEnvVarStr, regionDefault))
 	validateEgressCmd.Flags().StringToStringVar(&config.cloudTags, "cloud-tags", defaultTags, "(optional) comma-seperated list of tags to assign to cloud resources e.g. --cloud-tags key1=value1,key2=value2")
 	validateEgressCmd.Flags().BoolVar(&config.debug, "debug", false, "(optional) if true, enable additional debug-level logging")
-	validateEgressCmd.Flags().DurationVar(&config.timeout, "timeout", 2*time.Second, "(optional) timeout for individual egress verification requests")
+	validateEgressCmd.Flags().DurationVar(&config.timeout, "timeout", options.DefaultEgressTimeout, "(optional) timeout for individual egress verification requests")
 	validateEgressCmd.Flags().StringVar(&config.kmsKeyID, "kms-key-id", "", "(optional) ID of KMS key used to encrypt root volumes of compute instances. Defaults to cloud account default key")
-	validateEgressCmd.Flags().StringVar(&config.awsProfile, "profile", "", "(optional) AWS profile. If present, any credentials passed with CLI will be ignored.")
-
-	if err := validateEgressCmd.MarkFlagRequired("subnet-id"); err != nil {
-		validateEgressCmd.PrintErr(err)
-		os.Exit(1)
-	}
+	validateEgressCmd.Flags().StringVar(&config.mode, "mode", string(options.EgressModeInstance), "(optional) 'instance' launches a probe instance, 'static' only evaluates the routes, network ACL and security groups of the subnet, 'reachability' runs the AWS Reachability Analyzer")
+	validateEgressCmd.Flags().StringSliceVar(&config.securityGroupIDs, "security-group-ids", nil, "(optional) comma-separated list of security group IDs to verify. Defaults to the VPC's default security group")
+	validateEgressCmd.Flags().StringVar(&config.platform, "platform", cloudclient.PlatformAWS, fmt.Sprintf("(optional) cloud platform of the subnet, one of %v", cloudclient.Platforms()))
+	validateEgressCmd.Flags().StringVar(&config.vpcSubnetID, "subnet", "", "GCP subnetwork name or URL, same as --subnet-id")
+	config.awsFlags.AddFlags(validateEgressCmd.Flags())
+	config.gcpFlags.AddFlags(validateEgressCmd.Flags())
+	config.azureFlags.AddFlags(validateEgressCmd.Flags())
 
 	return validateEgressCmd
 
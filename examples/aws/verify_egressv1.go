@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
 	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 )
 
 func extendValidateEgressV1() {
@@ -18,8 +19,13 @@ func extendValidateEgressV1() {
 	secret, _ := os.LookupEnv("AWS_SECRET_ACCESS_KEY")
 	session, _ := os.LookupEnv("AWS_SESSION_TOKEN")
 
-	// Build the v1 credentials
-	creds := credentials.NewStaticCredentials(key, secret, session)
+	// Build the v1 credentials and hand their value over to the verifier
+	value, _ := credentials.NewStaticCredentials(key, secret, session).Get()
+	creds := cloudclient.AWSCredentials{
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+	}
 
 	// Example required values
 	logger, _ := ocmlog.NewStdLoggerBuilder().Debug(true).Build()
@@ -28,9 +34,18 @@ func extendValidateEgressV1() {
 	tags := map[string]string{"key1": "val1"}
 
 	//---------ONV egress verifier usage---------
-	cli, _ := cloudclient.NewClient(context.TODO(), logger, *creds, region, instanceType, tags)
+	cli, _ := cloudclient.NewClient(context.TODO(), logger, cloudclient.PlatformAWS, creds, cloudclient.Config{
+		Region:       region,
+		InstanceType: instanceType,
+		Tags:         tags,
+	})
 	// Call egress validator
-	out := cli.ValidateEgress(context.TODO(), "vpcSubnetID", "cloudImageID", "kmsKeyID", 3*time.Second)
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{
+		SubnetID:     "vpcSubnetID",
+		CloudImageID: "cloudImageID",
+		KmsKeyID:     "kmsKeyID",
+		Timeout:      3 * time.Second,
+	})
 	if !out.IsSuccessful() {
 		// Retrieve errors
 		failures, exceptions, errors := out.Parse()
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
 	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 )
 
 func extendValidateEgressV2() {
@@ -19,8 +20,13 @@ func extendValidateEgressV2() {
 	secret, _ := os.LookupEnv("AWS_SECRET_ACCESS_KEY")
 	session, _ := os.LookupEnv("AWS_SESSION_TOKEN")
 
-	// Build the v2 credentials provider
-	creds := credentials.NewStaticCredentialsProvider(key, secret, session)
+	// Build the v2 credentials provider and hand its value over to the verifier
+	provider := credentials.NewStaticCredentialsProvider(key, secret, session)
+	creds := cloudclient.AWSCredentials{
+		AccessKeyID:     provider.Value.AccessKeyID,
+		SecretAccessKey: provider.Value.SecretAccessKey,
+		SessionToken:    provider.Value.SessionToken,
+	}
 
 	// Example required values
 	logger, _ := ocmlog.NewStdLoggerBuilder().Debug(true).Build()
@@ -29,9 +35,18 @@ func extendValidateEgressV2() {
 	tags := map[string]string{"key1": "val1"}
 
 	//---------ONV egress verifier usage---------
-	cli, _ := cloudclient.NewClient(ctx, logger, creds, region, instanceType, tags)
+	cli, _ := cloudclient.NewClient(ctx, logger, cloudclient.PlatformAWS, creds, cloudclient.Config{
+		Region:       region,
+		InstanceType: instanceType,
+		Tags:         tags,
+	})
 	// Call egress validator
-	out := cli.ValidateEgress(context.TODO(), "vpcSubnetID", "cloudImageID", "kmsKeyID", 3*time.Second)
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{
+		SubnetID:     "vpcSubnetID",
+		CloudImageID: "cloudImageID",
+		KmsKeyID:     "kmsKeyID",
+		Timeout:      3 * time.Second,
+	})
 	if !out.IsSuccessful() {
 		// Retrieve errors
 		failures, exceptions, errors := out.Parse()
@@ -3,12 +3,16 @@ package aws
 import (
 	"context"
 	"fmt"
-	"time"
 
 	awscredsv2 "github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
 	awscredsv1 "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53resolver"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 	"github.com/openshift/osd-network-verifier/pkg/output"
 )
 
@@ -17,12 +21,14 @@ const ClientIdentifier string = "AWS"
 
 // Client represents an AWS Client
 type Client struct {
-	ec2Client    EC2Client
-	region       string
-	instanceType string
-	tags         map[string]string
-	logger       ocmlog.Logger
-	output       output.Output
+	ec2Client      EC2Client
+	route53Client  Route53Client
+	resolverClient ResolverClient
+	region         string
+	instanceType   string
+	tags           map[string]string
+	logger         ocmlog.Logger
+	output         output.Output
 }
 
 // Extend EC2Client so that we can mock them all for testing
@@ -35,68 +41,129 @@ type EC2Client interface {
 	GetConsoleOutput(ctx context.Context, input *ec2.GetConsoleOutputInput, optFns ...func(*ec2.Options)) (*ec2.GetConsoleOutputOutput, error)
 	TerminateInstances(ctx context.Context, input *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
 	DescribeVpcAttribute(ctx context.Context, input *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error)
+	DescribeSubnets(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+	DescribeVpcs(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
+	DescribeRouteTables(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error)
+	DescribeNetworkAcls(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error)
+	DescribeSecurityGroups(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	GetManagedPrefixListEntries(ctx context.Context, input *ec2.GetManagedPrefixListEntriesInput, optFns ...func(*ec2.Options)) (*ec2.GetManagedPrefixListEntriesOutput, error)
+	DescribeInternetGateways(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error)
+	CreateNetworkInterface(ctx context.Context, input *ec2.CreateNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkInterfaceOutput, error)
+	DeleteNetworkInterface(ctx context.Context, input *ec2.DeleteNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error)
+	CreateNetworkInsightsPath(ctx context.Context, input *ec2.CreateNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkInsightsPathOutput, error)
+	DeleteNetworkInsightsPath(ctx context.Context, input *ec2.DeleteNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsPathOutput, error)
+	StartNetworkInsightsAnalysis(ctx context.Context, input *ec2.StartNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.StartNetworkInsightsAnalysisOutput, error)
+	DescribeNetworkInsightsAnalyses(ctx context.Context, input *ec2.DescribeNetworkInsightsAnalysesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInsightsAnalysesOutput, error)
+	DeleteNetworkInsightsAnalysis(ctx context.Context, input *ec2.DeleteNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsAnalysisOutput, error)
+	DescribeDhcpOptions(ctx context.Context, input *ec2.DescribeDhcpOptionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeDhcpOptionsOutput, error)
+	DescribeVpcEndpoints(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error)
 }
 
-func (c *Client) ByoVPCValidator(ctx context.Context) error {
-	c.logger.Info(ctx, "interface executed: %s", ClientIdentifier)
-	return nil
+// Route53Client is the subset of the Route 53 API used to verify the private hosted zones of a VPC
+type Route53Client interface {
+	ListHostedZonesByVPCWithContext(ctx awsv1.Context, input *route53.ListHostedZonesByVPCInput, opts ...request.Option) (*route53.ListHostedZonesByVPCOutput, error)
 }
 
-func (c *Client) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID string, kmsKeyID string, timeout time.Duration) *output.Output {
-	return c.validateEgress(ctx, vpcSubnetID, cloudImageID, kmsKeyID, timeout)
+// ResolverClient is the subset of the Route 53 Resolver API used to verify the resolver rules of a VPC
+type ResolverClient interface {
+	ListResolverRuleAssociationsWithContext(ctx awsv1.Context, input *route53resolver.ListResolverRuleAssociationsInput, opts ...request.Option) (*route53resolver.ListResolverRuleAssociationsOutput, error)
+	GetResolverRuleWithContext(ctx awsv1.Context, input *route53resolver.GetResolverRuleInput, opts ...request.Option) (*route53resolver.GetResolverRuleOutput, error)
 }
 
-func (c *Client) VerifyDns(ctx context.Context, vpcID string) *output.Output {
-	return c.verifyDns(ctx, vpcID)
+func (c *Client) ByoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.byoVPCValidator(ctx, opts)
+}
+
+func (c *Client) ValidateEgress(ctx context.Context, opts options.EgressOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.validateEgress(ctx, opts)
+}
+
+func (c *Client) VerifyDns(ctx context.Context, opts options.DnsOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.verifyDns(ctx, opts)
+}
+
+// VerifyRoutes reports, for each subnet, where its default routes send egress traffic
+func (c *Client) VerifyRoutes(ctx context.Context, opts options.RoutesOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.verifyRoutes(ctx, opts.SubnetIDs)
+}
+
+// VerifyNetworkACLs evaluates the network ACLs of the subnets against the egress endpoints
+// without launching an instance
+func (c *Client) VerifyNetworkACLs(ctx context.Context, opts options.NetworkACLOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.verifyNetworkACLs(ctx, opts)
+}
+
+// VerifySecurityGroups evaluates the egress rules of the security groups against the egress endpoints
+// without launching an instance
+func (c *Client) VerifySecurityGroups(ctx context.Context, opts options.SecurityGroupOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.verifySecurityGroups(ctx, opts)
+}
+
+// VerifyVpcEndpoints maps the VPC endpoints of the VPC to the egress endpoints they serve and
+// reports the configurations and policies breaking the cluster's traffic to those services
+func (c *Client) VerifyVpcEndpoints(ctx context.Context, opts options.VpcEndpointOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.verifyVpcEndpoints(ctx, opts)
 }
 
 // NewClient creates a new CloudClient for use with AWS.
+// creds may be a Credentials value, a profile name, or static SDK v1/v2 credentials.
 func NewClient(ctx context.Context, logger ocmlog.Logger, creds interface{}, region, instanceType string, tags map[string]string) (client *Client, err error) {
+	var resolved Credentials
 	switch c := creds.(type) {
+	case Credentials:
+		resolved = c
 	case string:
-		client, err = newClient(
-			ctx,
-			logger,
-			"",
-			"",
-			"",
-			region,
-			instanceType,
-			tags,
-			creds.(string),
-		)
+		resolved = Credentials{Profile: c}
 	case awscredsv1.Credentials:
 		var value awscredsv1.Value
 		if value, err = c.Get(); err == nil {
-			client, err = newClient(
-				ctx,
-				logger,
-				value.AccessKeyID,
-				value.SecretAccessKey,
-				value.SessionToken,
-				region,
-				instanceType,
-				tags,
-				"",
-			)
+			resolved = Credentials{
+				AccessKeyID:     value.AccessKeyID,
+				SecretAccessKey: value.SecretAccessKey,
+				SessionToken:    value.SessionToken,
+			}
 		}
 	case awscredsv2.StaticCredentialsProvider:
-		client, err = newClient(
-			ctx,
-			logger,
-			c.Value.AccessKeyID,
-			c.Value.SecretAccessKey,
-			c.Value.SessionToken,
-			region,
-			instanceType,
-			tags,
-			"",
-		)
-
+		resolved = Credentials{
+			AccessKeyID:     c.Value.AccessKeyID,
+			SecretAccessKey: c.Value.SecretAccessKey,
+			SessionToken:    c.Value.SessionToken,
+		}
 	default:
 		err = fmt.Errorf("unsupported credentials type %T", c)
 	}
 
+	if err == nil {
+		client, err = newClient(ctx, logger, resolved, region, instanceType, tags)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create AWS client: %w", err)
 	}
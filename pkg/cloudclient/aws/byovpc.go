@@ -0,0 +1,556 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// byoVPCValidator verifies the documented BYOVPC requirements for the given subnets
+// Basic workflow is:
+// - ask AWS API for the subnets, their VPC and the VPC's route tables
+// - ensure every subnet is in the expected VPC and region, and within the VPC CIDR blocks
+// - classify subnets as public or private and ensure the layout matches the cluster type
+// - ensure the cluster networks don't overlap with the VPC, its subnets or the networks its route tables send out of it
+// - ensure every subnet has enough free addresses for its share of the nodes and the load balancers
+// - ensure the load balancer role and cluster tags of the subnets match their kind
+// - return `c.output` holding a finding for every requirement
+func (c *Client) byoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output {
+	c.logger.Info(ctx, "Verifying BYOVPC configuration for subnets %v", opts.SubnetIDs)
+	// unlike SubnetIds, which fails with InvalidSubnetID.NotFound if any subnet is missing, the filter returns the subnets found
+	subnetsOut, err := c.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("subnet-id"), Values: opts.SubnetIDs}},
+	})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	subnets := subnetsOut.Subnets
+	c.checkSubnetsExist(opts.SubnetIDs, subnets)
+	if len(subnets) == 0 {
+		return c.output.AddError(fmt.Errorf("none of the subnets %v were found", opts.SubnetIDs)) // fatal
+	}
+
+	vpcID := opts.VpcID
+	if vpcID == "" {
+		vpcID = aws.ToString(subnets[0].VpcId)
+		c.logger.Debug(ctx, "No VPC given, using VPC %s of subnet %s", vpcID, aws.ToString(subnets[0].SubnetId))
+	}
+	vpcsOut, err := c.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpcID},
+	})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	if len(vpcsOut.Vpcs) != 1 {
+		return c.output.AddError(fmt.Errorf("VPC %s not found", vpcID)) // fatal
+	}
+	vpcCIDRs := vpcCIDRBlocks(vpcsOut.Vpcs[0])
+
+	routeTables, err := c.subnetRouteTables(ctx, vpcID)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+
+	for _, subnet := range subnets {
+		c.checkSubnetPlacement(subnet, vpcID, vpcCIDRs)
+	}
+	c.checkSubnetLayout(subnets, routeTables, opts.Private)
+	c.checkClusterCIDRs(subnets, vpcID, vpcCIDRs, routeTables, opts)
+	c.checkSubnetCapacity(subnets, routeTables, opts)
+	c.checkSubnetTags(subnets, routeTables, opts.Private)
+
+	return &c.output
+}
+
+// vpcCIDRBlocks returns the IPv4 CIDR blocks currently associated with the VPC
+func vpcCIDRBlocks(vpc ec2Types.Vpc) []*net.IPNet {
+	var cidrs []*net.IPNet
+	for _, assoc := range vpc.CidrBlockAssociationSet {
+		if assoc.CidrBlockState != nil && assoc.CidrBlockState.State != ec2Types.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(aws.ToString(assoc.CidrBlock)); err == nil {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	// older VPCs may only report the primary block
+	if len(cidrs) == 0 {
+		if _, cidr, err := net.ParseCIDR(aws.ToString(vpc.CidrBlock)); err == nil {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	return cidrs
+}
+
+// subnetRouteTables returns a lookup of the route table used by each subnet of the VPC,
+// subnets without an explicit association use the VPC's main route table (key "")
+func (c *Client) subnetRouteTables(ctx context.Context, vpcID string) (map[string]ec2Types.RouteTable, error) {
+	out, err := c.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []ec2Types.Filter{{
+			Name:   aws.String("vpc-id"),
+			Values: []string{vpcID},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tables := map[string]ec2Types.RouteTable{}
+	for _, rt := range out.RouteTables {
+		for _, assoc := range rt.Associations {
+			if aws.ToBool(assoc.Main) {
+				tables[""] = rt
+			} else if assoc.SubnetId != nil {
+				tables[*assoc.SubnetId] = rt
+			}
+		}
+	}
+
+	return tables, nil
+}
+
+// routeTableForSubnet returns the route table of the subnet, falling back to the main route table
+func routeTableForSubnet(tables map[string]ec2Types.RouteTable, subnetID string) (ec2Types.RouteTable, bool) {
+	if rt, ok := tables[subnetID]; ok {
+		return rt, true
+	}
+	rt, ok := tables[""]
+
+	return rt, ok
+}
+
+// isPublicRouteTable returns true if the route table sends default traffic to an internet gateway
+func isPublicRouteTable(rt ec2Types.RouteTable) bool {
+	for _, route := range rt.Routes {
+		dest := aws.ToString(route.DestinationCidrBlock)
+		if dest == "" {
+			dest = aws.ToString(route.DestinationIpv6CidrBlock)
+		}
+		if (dest == "0.0.0.0/0" || dest == "::/0") && strings.HasPrefix(aws.ToString(route.GatewayId), "igw-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Client) checkSubnetsExist(subnetIDs []string, subnets []ec2Types.Subnet) {
+	found := map[string]bool{}
+	for _, s := range subnets {
+		found[aws.ToString(s.SubnetId)] = true
+	}
+	for _, id := range subnetIDs {
+		f := output.Finding{Check: "subnet-exists", Resource: id, Status: output.StatusPassed, Message: "subnet found"}
+		if !found[id] {
+			f.Status = output.StatusFailed
+			f.Message = "subnet not found"
+		}
+		c.output.AddFinding(f)
+	}
+}
+
+func (c *Client) checkSubnetPlacement(subnet ec2Types.Subnet, vpcID string, vpcCIDRs []*net.IPNet) {
+	subnetID := aws.ToString(subnet.SubnetId)
+
+	f := output.Finding{Check: "subnet-in-vpc", Resource: subnetID, Status: output.StatusPassed,
+		Message: fmt.Sprintf("subnet belongs to VPC %s", vpcID)}
+	if aws.ToString(subnet.VpcId) != vpcID {
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("subnet belongs to VPC %s instead of %s", aws.ToString(subnet.VpcId), vpcID)
+	}
+	c.output.AddFinding(f)
+
+	az := aws.ToString(subnet.AvailabilityZone)
+	f = output.Finding{Check: "subnet-in-region", Resource: subnetID, Status: output.StatusPassed,
+		Message: fmt.Sprintf("availability zone %s is in region %s", az, c.region)}
+	if !strings.HasPrefix(az, c.region) {
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("availability zone %s is not in region %s", az, c.region)
+	}
+	c.output.AddFinding(f)
+
+	f = output.Finding{Check: "subnet-cidr-in-vpc", Resource: subnetID, Status: output.StatusFailed,
+		Message: fmt.Sprintf("subnet CIDR %s is outside of the VPC CIDR blocks", aws.ToString(subnet.CidrBlock))}
+	if _, subnetCIDR, err := net.ParseCIDR(aws.ToString(subnet.CidrBlock)); err == nil {
+		for _, vpcCIDR := range vpcCIDRs {
+			if helpers.CIDRContains(vpcCIDR, subnetCIDR) {
+				f.Status = output.StatusPassed
+				f.Message = fmt.Sprintf("subnet CIDR %s is within VPC CIDR %s", subnetCIDR, vpcCIDR)
+				break
+			}
+		}
+	}
+	c.output.AddFinding(f)
+}
+
+// checkSubnetLayout ensures every availability zone has a private subnet, and a public one for public clusters.
+// PrivateLink clusters must not be given public subnets.
+func (c *Client) checkSubnetLayout(subnets []ec2Types.Subnet, routeTables map[string]ec2Types.RouteTable, private bool) {
+	type azSubnets struct{ public, private []string }
+	zones := map[string]*azSubnets{}
+	for _, subnet := range subnets {
+		subnetID := aws.ToString(subnet.SubnetId)
+		az := aws.ToString(subnet.AvailabilityZone)
+		if zones[az] == nil {
+			zones[az] = &azSubnets{}
+		}
+		rt, ok := routeTableForSubnet(routeTables, subnetID)
+		if !ok {
+			c.output.AddFinding(output.Finding{Check: "subnet-route-table", Resource: subnetID, Status: output.StatusFailed,
+				Message: "no route table is associated with the subnet and the VPC has no main route table"})
+			continue
+		}
+		if isPublicRouteTable(rt) {
+			zones[az].public = append(zones[az].public, subnetID)
+		} else {
+			zones[az].private = append(zones[az].private, subnetID)
+		}
+	}
+
+	azs := make([]string, 0, len(zones))
+	for az := range zones {
+		azs = append(azs, az)
+	}
+	sort.Strings(azs)
+	for _, az := range azs {
+		z := zones[az]
+		f := output.Finding{Check: "subnet-layout", Resource: az, Status: output.StatusPassed,
+			Message: fmt.Sprintf("public subnets %v, private subnets %v", z.public, z.private)}
+		switch {
+		case len(z.private) == 0:
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("no private subnet given, public subnets %v", z.public)
+		case private && len(z.public) > 0:
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("PrivateLink clusters must only use private subnets, got public subnets %v", z.public)
+		case !private && len(z.public) == 0:
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("no public subnet given, private subnets %v", z.private)
+		}
+		c.output.AddFinding(f)
+	}
+}
+
+// cidrOwner is a network the cluster networks must not overlap with, and the resource it belongs to
+type cidrOwner struct {
+	cidr        *net.IPNet
+	resource    string
+	description string
+}
+
+// checkClusterCIDRs ensures the machine CIDR contains every subnet, that the service and pod CIDRs overlap
+// neither the VPC, its subnets, the machine CIDR nor each other, and that none of the cluster networks overlaps
+// with the destinations the route tables send to peered VPCs, transit gateways or VPN gateways.
+// Every overlap is reported with the resource causing it.
+func (c *Client) checkClusterCIDRs(subnets []ec2Types.Subnet, vpcID string, vpcCIDRs []*net.IPNet,
+	routeTables map[string]ec2Types.RouteTable, opts options.ByoVPCOptions) {
+	var machineCIDR *net.IPNet
+	if opts.MachineCIDR != "" {
+		_, machineCIDR, _ = net.ParseCIDR(opts.MachineCIDR)
+		for _, subnet := range subnets {
+			subnetID := aws.ToString(subnet.SubnetId)
+			f := output.Finding{Check: "machine-cidr-contains-subnet", Resource: subnetID, Status: output.StatusFailed,
+				Message: fmt.Sprintf("subnet CIDR %s is outside of machine CIDR %s", aws.ToString(subnet.CidrBlock), machineCIDR)}
+			if _, subnetCIDR, err := net.ParseCIDR(aws.ToString(subnet.CidrBlock)); err == nil && helpers.CIDRContains(machineCIDR, subnetCIDR) {
+				f.Status = output.StatusPassed
+				f.Message = fmt.Sprintf("subnet CIDR %s is within machine CIDR %s", subnetCIDR, machineCIDR)
+			}
+			c.output.AddFinding(f)
+		}
+	}
+
+	var subnetOwners, vpcOwners []cidrOwner
+	for _, subnet := range subnets {
+		if _, cidr, err := net.ParseCIDR(aws.ToString(subnet.CidrBlock)); err == nil {
+			subnetOwners = append(subnetOwners, cidrOwner{cidr: cidr, resource: aws.ToString(subnet.SubnetId),
+				description: fmt.Sprintf("subnet CIDR %s", cidr)})
+		}
+	}
+	for _, cidr := range vpcCIDRs {
+		vpcOwners = append(vpcOwners, cidrOwner{cidr: cidr, resource: vpcID, description: fmt.Sprintf("VPC CIDR %s", cidr)})
+	}
+	routeOwners := routedCIDROwners(routeTables)
+
+	clusterCIDRs := map[string]*net.IPNet{}
+	for name, cidr := range map[string]string{"service": opts.ServiceCIDR, "pod": opts.PodCIDR} {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			clusterCIDRs[name] = ipNet
+		}
+	}
+
+	for _, name := range []string{"machine", "service", "pod"} {
+		cidr := clusterCIDRs[name]
+		if name == "machine" {
+			cidr = machineCIDR
+		}
+		if cidr == nil {
+			continue
+		}
+
+		// the machine network is the VPC's own, only the networks routed out of the VPC must not overlap with it
+		owners := routeOwners
+		if name != "machine" {
+			owners = overlappingOwners(cidr, subnetOwners)
+			// the VPC is only reported when no given subnet explains the overlap
+			if len(owners) == 0 {
+				owners = overlappingOwners(cidr, vpcOwners)
+			}
+			if machineCIDR != nil {
+				owners = append(owners, cidrOwner{cidr: machineCIDR, resource: vpcID, description: fmt.Sprintf("machine CIDR %s", machineCIDR)})
+			}
+			if other := clusterCIDRs["pod"]; name == "service" && other != nil {
+				owners = append(owners, cidrOwner{cidr: other, resource: vpcID, description: fmt.Sprintf("pod CIDR %s", other)})
+			}
+			owners = append(owners, routeOwners...)
+		}
+
+		overlaps := overlappingOwners(cidr, owners)
+		for _, owner := range overlaps {
+			c.output.AddFinding(output.Finding{Check: name + "-cidr-overlap", Resource: owner.resource, Status: output.StatusFailed,
+				Message: fmt.Sprintf("%s CIDR %s overlaps with %s", name, cidr, owner.description)})
+		}
+		if len(overlaps) == 0 {
+			c.output.AddFinding(output.Finding{Check: name + "-cidr-overlap", Resource: vpcID, Status: output.StatusPassed,
+				Message: fmt.Sprintf("%s CIDR %s does not overlap with the VPC, its subnets, the other cluster networks or the networks routed out of the VPC", name, cidr)})
+		}
+	}
+}
+
+// overlappingOwners returns the owners of the networks overlapping with the CIDR
+func overlappingOwners(cidr *net.IPNet, owners []cidrOwner) []cidrOwner {
+	var overlapping []cidrOwner
+	for _, owner := range owners {
+		if helpers.CIDROverlaps(cidr, owner.cidr) {
+			overlapping = append(overlapping, owner)
+		}
+	}
+
+	return overlapping
+}
+
+// routedCIDROwners returns the IPv4 destinations the route tables send out of the VPC, owned by the route targets.
+// Local and default routes are skipped, the VPC CIDRs are checked on their own and default routes overlap with everything.
+func routedCIDROwners(routeTables map[string]ec2Types.RouteTable) []cidrOwner {
+	unique := map[string]ec2Types.RouteTable{}
+	for _, rt := range routeTables {
+		unique[aws.ToString(rt.RouteTableId)] = rt
+	}
+	rtIDs := make([]string, 0, len(unique))
+	for id := range unique {
+		rtIDs = append(rtIDs, id)
+	}
+	sort.Strings(rtIDs)
+
+	var owners []cidrOwner
+	for _, rtID := range rtIDs {
+		for _, route := range unique[rtID].Routes {
+			_, cidr, err := net.ParseCIDR(aws.ToString(route.DestinationCidrBlock))
+			if err != nil || aws.ToString(route.GatewayId) == "local" {
+				continue
+			}
+			if ones, _ := cidr.Mask.Size(); ones == 0 {
+				continue
+			}
+			target, description := routeTarget(route)
+			owners = append(owners, cidrOwner{cidr: cidr, resource: target,
+				description: fmt.Sprintf("%s, routed to %s by route table %s", cidr, description, rtID)})
+		}
+	}
+
+	return owners
+}
+
+// routeTarget returns the ID and a description of the target of a route
+func routeTarget(route ec2Types.Route) (string, string) {
+	switch {
+	case route.VpcPeeringConnectionId != nil:
+		return *route.VpcPeeringConnectionId, fmt.Sprintf("VPC peering connection %s", *route.VpcPeeringConnectionId)
+	case route.TransitGatewayId != nil:
+		return *route.TransitGatewayId, fmt.Sprintf("transit gateway %s", *route.TransitGatewayId)
+	case strings.HasPrefix(aws.ToString(route.GatewayId), "vgw-"):
+		return *route.GatewayId, fmt.Sprintf("VPN gateway %s", *route.GatewayId)
+	case route.NatGatewayId != nil:
+		return *route.NatGatewayId, fmt.Sprintf("NAT gateway %s", *route.NatGatewayId)
+	case route.NetworkInterfaceId != nil:
+		return *route.NetworkInterfaceId, fmt.Sprintf("network interface %s", *route.NetworkInterfaceId)
+	case route.GatewayId != nil:
+		return *route.GatewayId, fmt.Sprintf("gateway %s", *route.GatewayId)
+	}
+
+	return "unknown", "an unknown target"
+}
+
+const (
+	// tagRoleELB and tagRoleInternalELB mark the subnets the cloud provider places
+	// internet-facing and internal load balancers into
+	tagRoleELB         = "kubernetes.io/role/elb"
+	tagRoleInternalELB = "kubernetes.io/role/internal-elb"
+	tagClusterPrefix   = "kubernetes.io/cluster/"
+)
+
+// checkSubnetTags ensures the load balancer role tags of the subnets match their kind, so that the routers are
+// placed into the right subnets: internet-facing load balancers into public subnets, internal ones into private subnets.
+// Role tags are optional, subnets without them are still discovered, but each subnet carries at most one role.
+func (c *Client) checkSubnetTags(subnets []ec2Types.Subnet, routeTables map[string]ec2Types.RouteTable, private bool) {
+	// subnets carrying each role in every availability zone, the cloud provider only uses one of them
+	roleSubnets := map[string]map[string][]string{tagRoleELB: {}, tagRoleInternalELB: {}}
+	for _, subnet := range subnets {
+		subnetID := aws.ToString(subnet.SubnetId)
+		rt, ok := routeTableForSubnet(routeTables, subnetID)
+		public := ok && isPublicRouteTable(rt)
+
+		tags := map[string]string{}
+		for _, tag := range subnet.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		roles := map[string]bool{}
+		var issues []output.Finding
+		addIssue := func(status output.Status, message string) {
+			issues = append(issues, output.Finding{Check: "subnet-lb-tags", Resource: subnetID, Status: status, Message: message})
+		}
+		for _, role := range []string{tagRoleELB, tagRoleInternalELB} {
+			value, ok := tags[role]
+			if !ok {
+				continue
+			}
+			// the cloud provider ignores role tags with any other value
+			if value != "" && value != "1" {
+				addIssue(output.StatusWarning, fmt.Sprintf("tag %s has value %q, it is ignored unless it is empty or \"1\"", role, value))
+				continue
+			}
+			roles[role] = true
+			az := aws.ToString(subnet.AvailabilityZone)
+			roleSubnets[role][az] = append(roleSubnets[role][az], subnetID)
+		}
+
+		switch {
+		case roles[tagRoleELB] && roles[tagRoleInternalELB]:
+			addIssue(output.StatusFailed, fmt.Sprintf("subnet has both the %s and %s tags", tagRoleELB, tagRoleInternalELB))
+		case roles[tagRoleELB] && !public && private:
+			addIssue(output.StatusWarning, fmt.Sprintf("private subnet has the %s tag, PrivateLink clusters only use internal load balancers", tagRoleELB))
+		case roles[tagRoleELB] && !public:
+			addIssue(output.StatusFailed, fmt.Sprintf("private subnet has the %s tag, internet-facing load balancers placed into it are unreachable", tagRoleELB))
+		case roles[tagRoleInternalELB] && public:
+			addIssue(output.StatusWarning, fmt.Sprintf("public subnet has the %s tag, internal load balancers are expected in private subnets", tagRoleInternalELB))
+		case public && !roles[tagRoleELB]:
+			addIssue(output.StatusWarning, fmt.Sprintf("public subnet is missing the %s tag", tagRoleELB))
+		case !public && !roles[tagRoleInternalELB]:
+			addIssue(output.StatusWarning, fmt.Sprintf("private subnet is missing the %s tag", tagRoleInternalELB))
+		}
+
+		var keys []string
+		for key := range tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := tags[key]
+			if !strings.HasPrefix(key, tagClusterPrefix) {
+				continue
+			}
+			cluster := strings.TrimPrefix(key, tagClusterPrefix)
+			switch value {
+			case "shared":
+			case "owned":
+				addIssue(output.StatusWarning, fmt.Sprintf("subnet is owned by cluster %s, which deletes it when uninstalled", cluster))
+			default:
+				addIssue(output.StatusFailed, fmt.Sprintf("tag %s has value %q instead of \"shared\" or \"owned\"", key, value))
+			}
+		}
+
+		if len(issues) == 0 {
+			kind := "private"
+			if public {
+				kind = "public"
+			}
+			issues = append(issues, output.Finding{Check: "subnet-lb-tags", Resource: subnetID, Status: output.StatusPassed,
+				Message: fmt.Sprintf("role tags match the %s subnet", kind)})
+		}
+		for _, f := range issues {
+			c.output.AddFinding(f)
+		}
+	}
+
+	for _, role := range []string{tagRoleELB, tagRoleInternalELB} {
+		azs := make([]string, 0, len(roleSubnets[role]))
+		for az := range roleSubnets[role] {
+			azs = append(azs, az)
+		}
+		sort.Strings(azs)
+		for _, az := range azs {
+			if ids := roleSubnets[role][az]; len(ids) > 1 {
+				c.output.AddFinding(output.Finding{Check: "subnet-lb-tags", Resource: az, Status: output.StatusWarning,
+					Message: fmt.Sprintf("subnets %v all have the %s tag, load balancers only use the first of them in lexical order", ids, role)})
+			}
+		}
+	}
+}
+
+// capacityHeadroomFactor is the ratio between the free and the needed addresses of a subnet under which
+// its capacity is reported as a warning, as it leaves little room to scale the cluster
+const capacityHeadroomFactor = 2
+
+// checkSubnetCapacity ensures every subnet has enough free addresses for the expected nodes and the load balancers.
+// Nodes are spread across the private subnets, public subnets only host load balancers.
+func (c *Client) checkSubnetCapacity(subnets []ec2Types.Subnet, routeTables map[string]ec2Types.RouteTable, opts options.ByoVPCOptions) {
+	private := map[string]bool{}
+	for _, subnet := range subnets {
+		subnetID := aws.ToString(subnet.SubnetId)
+		if rt, ok := routeTableForSubnet(routeTables, subnetID); !ok || !isPublicRouteTable(rt) {
+			private[subnetID] = true
+		}
+	}
+	nodesPerSubnet := 0
+	if len(private) > 0 {
+		nodesPerSubnet = (opts.ExpectedNodes + len(private) - 1) / len(private)
+	}
+
+	for _, subnet := range subnets {
+		subnetID := aws.ToString(subnet.SubnetId)
+		needed, usage := opts.LoadBalancerIPs, "load balancers"
+		if private[subnetID] {
+			needed += nodesPerSubnet
+			usage = fmt.Sprintf("%d nodes and load balancers", nodesPerSubnet)
+		}
+		available := int(aws.ToInt32(subnet.AvailableIpAddressCount))
+
+		f := output.Finding{Check: "subnet-capacity", Resource: subnetID, Status: output.StatusPassed,
+			Message: fmt.Sprintf("%d addresses available, %d needed for %s", available, needed, usage)}
+		_, cidr, err := net.ParseCIDR(aws.ToString(subnet.CidrBlock))
+		switch {
+		case err == nil && subnetUsableAddresses(cidr) < needed:
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("subnet CIDR %s only has %d usable addresses, %d are needed for %s",
+				cidr, subnetUsableAddresses(cidr), needed, usage)
+		case available < needed:
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("only %d addresses available, %d are needed for %s", available, needed, usage)
+		case available < needed*capacityHeadroomFactor:
+			f.Status = output.StatusWarning
+			f.Message = fmt.Sprintf("%d addresses available, %d needed for %s, leaving little room to scale",
+				available, needed, usage)
+		}
+		c.output.AddFinding(f)
+	}
+}
+
+// subnetUsableAddresses returns the number of addresses of an IPv4 subnet, minus the 5 AWS reserves
+func subnetUsableAddresses(cidr *net.IPNet) int {
+	ones, bits := cidr.Mask.Size()
+	if bits-ones >= 31 {
+		return math.MaxInt32
+	}
+
+	return 1<<(bits-ones) - 5
+}
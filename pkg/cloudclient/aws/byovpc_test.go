@@ -0,0 +1,330 @@
+package aws
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/golang/mock/gomock"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSubnet returns an empty subnet, every usable address of its CIDR is available
+func testSubnet(id, vpcID, az, cidr string) types.Subnet {
+	subnet := types.Subnet{
+		SubnetId:         aws.String(id),
+		VpcId:            aws.String(vpcID),
+		AvailabilityZone: aws.String(az),
+		CidrBlock:        aws.String(cidr),
+	}
+	if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+		subnet.AvailableIpAddressCount = aws.Int32(int32(subnetUsableAddresses(ipNet)))
+	}
+
+	return subnet
+}
+
+// testRouteTables returns a main route table to a NAT gateway and a public route table for the given subnets
+func testRouteTables(vpcID string, publicSubnetIDs ...string) []types.RouteTable {
+	public := types.RouteTable{
+		RouteTableId: aws.String("rtb-public"),
+		VpcId:        aws.String(vpcID),
+		Routes: []types.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1")},
+		},
+	}
+	for _, id := range publicSubnetIDs {
+		public.Associations = append(public.Associations, types.RouteTableAssociation{SubnetId: aws.String(id)})
+	}
+	private := types.RouteTable{
+		RouteTableId: aws.String("rtb-main"),
+		VpcId:        aws.String(vpcID),
+		Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+		Routes: []types.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1")},
+		},
+	}
+
+	return []types.RouteTable{public, private}
+}
+
+func TestByoVPCValidator(t *testing.T) {
+	vpcID := "vpc-1"
+	vpc := types.Vpc{
+		VpcId:     aws.String(vpcID),
+		CidrBlock: aws.String("10.0.0.0/16"),
+	}
+	tests := []struct {
+		name          string
+		subnets       []types.Subnet
+		publicSubnets []string
+		opts          options.ByoVPCOptions
+		failedChecks  []string
+	}{
+		{
+			name: "valid public cluster",
+			subnets: []types.Subnet{
+				testSubnet("subnet-pub-a", vpcID, "us-east-1a", "10.0.0.0/24"),
+				testSubnet("subnet-priv-a", vpcID, "us-east-1a", "10.0.1.0/24"),
+			},
+			publicSubnets: []string{"subnet-pub-a"},
+			opts:          options.ByoVPCOptions{MachineCIDR: "10.0.0.0/16"},
+		},
+		{
+			name: "public cluster without public subnet",
+			subnets: []types.Subnet{
+				testSubnet("subnet-priv-a", vpcID, "us-east-1a", "10.0.1.0/24"),
+			},
+			failedChecks: []string{"subnet-layout"},
+		},
+		{
+			name: "private cluster with public subnet",
+			subnets: []types.Subnet{
+				testSubnet("subnet-pub-a", vpcID, "us-east-1a", "10.0.0.0/24"),
+				testSubnet("subnet-priv-a", vpcID, "us-east-1a", "10.0.1.0/24"),
+			},
+			publicSubnets: []string{"subnet-pub-a"},
+			opts:          options.ByoVPCOptions{Private: true},
+			failedChecks:  []string{"subnet-layout"},
+		},
+		{
+			name: "subnet in another vpc and region",
+			subnets: []types.Subnet{
+				testSubnet("subnet-priv-a", "vpc-2", "us-west-2a", "10.1.1.0/24"),
+			},
+			opts:         options.ByoVPCOptions{Private: true},
+			failedChecks: []string{"subnet-in-vpc", "subnet-in-region", "subnet-cidr-in-vpc"},
+		},
+		{
+			name: "overlapping cluster networks",
+			subnets: []types.Subnet{
+				testSubnet("subnet-priv-a", vpcID, "us-east-1a", "10.0.1.0/24"),
+			},
+			opts:         options.ByoVPCOptions{Private: true, MachineCIDR: "10.0.0.0/24", PodCIDR: "10.0.0.0/14"},
+			failedChecks: []string{"machine-cidr-contains-subnet", "pod-cidr-overlap", "pod-cidr-overlap"}, // with the subnet and the machine CIDR
+		},
+		{
+			name: "subnets too small for the expected nodes",
+			subnets: []types.Subnet{
+				testSubnet("subnet-pub-a", vpcID, "us-east-1a", "10.0.0.0/28"),
+				testSubnet("subnet-priv-a", vpcID, "us-east-1a", "10.0.1.0/28"),
+			},
+			publicSubnets: []string{"subnet-pub-a"},
+			opts:          options.ByoVPCOptions{ExpectedNodes: 6},
+			failedChecks:  []string{"subnet-capacity"},
+		},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+		FakeEC2Cli.EXPECT().DescribeSubnets(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeSubnetsOutput{
+			Subnets: test.subnets,
+		}, nil)
+		FakeEC2Cli.EXPECT().DescribeVpcs(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeVpcsOutput{
+			Vpcs: []types.Vpc{vpc},
+		}, nil)
+		FakeEC2Cli.EXPECT().DescribeRouteTables(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeRouteTablesOutput{
+			RouteTables: testRouteTables(vpcID, test.publicSubnets...),
+		}, nil)
+
+		cli := Client{
+			ec2Client: FakeEC2Cli,
+			region:    "us-east-1",
+			logger:    &logging.GlogLogger{},
+		}
+		opts := test.opts
+		opts.VpcID = vpcID
+		for _, s := range test.subnets {
+			opts.SubnetIDs = append(opts.SubnetIDs, aws.ToString(s.SubnetId))
+		}
+		out := cli.ByoVPCValidator(context.TODO(), opts)
+
+		var failedChecks []string
+		for _, f := range out.Findings() {
+			if f.Status == output.StatusFailed {
+				failedChecks = append(failedChecks, f.Check)
+			}
+		}
+		assert.ElementsMatch(t, test.failedChecks, failedChecks, test.name)
+		assert.Equal(t, len(test.failedChecks) == 0, out.IsSuccessful(), test.name)
+		ctrl.Finish()
+	}
+}
+
+func TestByoVPCValidatorMissingSubnets(t *testing.T) {
+	vpcID := "vpc-1"
+	existing := testSubnet("subnet-priv-a", vpcID, "us-east-1a", "10.0.1.0/24")
+	tests := []struct {
+		name        string
+		subnetIDs   []string
+		subnets     []types.Subnet
+		expectError bool
+	}{
+		{name: "one of several subnets missing", subnetIDs: []string{"subnet-priv-a", "subnet-missing"}, subnets: []types.Subnet{existing}},
+		{name: "every subnet missing", subnetIDs: []string{"subnet-missing"}, expectError: true},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+		FakeEC2Cli.EXPECT().DescribeSubnets(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+			func(_ context.Context, input *ec2.DescribeSubnetsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+				// the subnets are filtered, listing them by ID fails if any is missing
+				assert.Empty(t, input.SubnetIds, test.name)
+				assert.Equal(t, []types.Filter{{Name: aws.String("subnet-id"), Values: test.subnetIDs}}, input.Filters, test.name)
+				return &ec2.DescribeSubnetsOutput{Subnets: test.subnets}, nil
+			})
+		if !test.expectError {
+			FakeEC2Cli.EXPECT().DescribeVpcs(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeVpcsOutput{
+				Vpcs: []types.Vpc{{VpcId: aws.String(vpcID), CidrBlock: aws.String("10.0.0.0/16")}},
+			}, nil)
+			FakeEC2Cli.EXPECT().DescribeRouteTables(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeRouteTablesOutput{
+				RouteTables: testRouteTables(vpcID),
+			}, nil)
+		}
+
+		cli := Client{
+			ec2Client: FakeEC2Cli,
+			region:    "us-east-1",
+			logger:    &logging.GlogLogger{},
+		}
+		out := cli.ByoVPCValidator(context.TODO(), options.ByoVPCOptions{VpcID: vpcID, SubnetIDs: test.subnetIDs, Private: true})
+
+		assert.Contains(t, out.Findings(), output.Finding{Check: "subnet-exists", Resource: "subnet-missing", Status: output.StatusFailed,
+			Message: "subnet not found"}, test.name)
+		_, _, errs := out.Parse()
+		assert.Equal(t, test.expectError, len(errs) > 0, test.name)
+		ctrl.Finish()
+	}
+}
+
+func TestCheckSubnetCapacity(t *testing.T) {
+	full := testSubnet("subnet-full", "vpc-1", "us-east-1a", "10.0.0.0/24")
+	full.AvailableIpAddressCount = aws.Int32(10)
+	busy := testSubnet("subnet-busy", "vpc-1", "us-east-1b", "10.0.1.0/24")
+	busy.AvailableIpAddressCount = aws.Int32(20)
+	subnets := []types.Subnet{full, busy, testSubnet("subnet-free", "vpc-1", "us-east-1c", "10.0.2.0/24")}
+
+	cli := Client{logger: &logging.GlogLogger{}}
+	opts := options.ByoVPCOptions{ExpectedNodes: 9, LoadBalancerIPs: 8}
+	cli.checkSubnetCapacity(subnets, map[string]types.RouteTable{}, opts)
+
+	// 3 nodes per subnet and 8 addresses for load balancers
+	assert.Equal(t, []output.Finding{
+		{Check: "subnet-capacity", Resource: "subnet-full", Status: output.StatusFailed,
+			Message: "only 10 addresses available, 11 are needed for 3 nodes and load balancers"},
+		{Check: "subnet-capacity", Resource: "subnet-busy", Status: output.StatusWarning,
+			Message: "20 addresses available, 11 needed for 3 nodes and load balancers, leaving little room to scale"},
+		{Check: "subnet-capacity", Resource: "subnet-free", Status: output.StatusPassed,
+			Message: "251 addresses available, 11 needed for 3 nodes and load balancers"},
+	}, cli.output.Findings())
+}
+
+func TestCheckSubnetTags(t *testing.T) {
+	tagged := func(subnet types.Subnet, tags map[string]string) types.Subnet {
+		for k, v := range tags {
+			subnet.Tags = append(subnet.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		return subnet
+	}
+	subnets := []types.Subnet{
+		tagged(testSubnet("subnet-pub-a", "vpc-1", "us-east-1a", "10.0.0.0/24"), map[string]string{"kubernetes.io/role/elb": "1"}),
+		tagged(testSubnet("subnet-pub-b", "vpc-1", "us-east-1a", "10.0.1.0/24"), map[string]string{"kubernetes.io/role/elb": ""}),
+		tagged(testSubnet("subnet-priv-a", "vpc-1", "us-east-1a", "10.0.2.0/24"), map[string]string{
+			"kubernetes.io/role/elb":          "1",
+			"kubernetes.io/cluster/other-abc": "owned",
+		}),
+		tagged(testSubnet("subnet-priv-b", "vpc-1", "us-east-1b", "10.0.3.0/24"), map[string]string{
+			"kubernetes.io/role/elb":          "1",
+			"kubernetes.io/role/internal-elb": "1",
+		}),
+		tagged(testSubnet("subnet-priv-c", "vpc-1", "us-east-1c", "10.0.4.0/24"), map[string]string{
+			"kubernetes.io/role/internal-elb": "true",
+			"kubernetes.io/cluster/mine":      "yes",
+		}),
+		tagged(testSubnet("subnet-priv-d", "vpc-1", "us-east-1d", "10.0.5.0/24"), map[string]string{
+			"kubernetes.io/role/internal-elb": "",
+			"kubernetes.io/cluster/mine":      "shared",
+		}),
+	}
+	routeTables := map[string]types.RouteTable{}
+	for _, rt := range testRouteTables("vpc-1", "subnet-pub-a", "subnet-pub-b") {
+		for _, assoc := range rt.Associations {
+			if aws.ToBool(assoc.Main) {
+				routeTables[""] = rt
+			} else {
+				routeTables[aws.ToString(assoc.SubnetId)] = rt
+			}
+		}
+	}
+
+	cli := Client{logger: &logging.GlogLogger{}}
+	cli.checkSubnetTags(subnets, routeTables, false)
+
+	assert.Equal(t, []output.Finding{
+		{Check: "subnet-lb-tags", Resource: "subnet-pub-a", Status: output.StatusPassed, Message: "role tags match the public subnet"},
+		{Check: "subnet-lb-tags", Resource: "subnet-pub-b", Status: output.StatusPassed, Message: "role tags match the public subnet"},
+		{Check: "subnet-lb-tags", Resource: "subnet-priv-a", Status: output.StatusFailed,
+			Message: "private subnet has the kubernetes.io/role/elb tag, internet-facing load balancers placed into it are unreachable"},
+		{Check: "subnet-lb-tags", Resource: "subnet-priv-a", Status: output.StatusWarning,
+			Message: "subnet is owned by cluster other-abc, which deletes it when uninstalled"},
+		{Check: "subnet-lb-tags", Resource: "subnet-priv-b", Status: output.StatusFailed,
+			Message: "subnet has both the kubernetes.io/role/elb and kubernetes.io/role/internal-elb tags"},
+		{Check: "subnet-lb-tags", Resource: "subnet-priv-c", Status: output.StatusWarning,
+			Message: `tag kubernetes.io/role/internal-elb has value "true", it is ignored unless it is empty or "1"`},
+		{Check: "subnet-lb-tags", Resource: "subnet-priv-c", Status: output.StatusWarning,
+			Message: "private subnet is missing the kubernetes.io/role/internal-elb tag"},
+		{Check: "subnet-lb-tags", Resource: "subnet-priv-c", Status: output.StatusFailed,
+			Message: `tag kubernetes.io/cluster/mine has value "yes" instead of "shared" or "owned"`},
+		{Check: "subnet-lb-tags", Resource: "subnet-priv-d", Status: output.StatusPassed, Message: "role tags match the private subnet"},
+		{Check: "subnet-lb-tags", Resource: "us-east-1a", Status: output.StatusWarning,
+			Message: "subnets [subnet-pub-a subnet-pub-b subnet-priv-a] all have the kubernetes.io/role/elb tag, load balancers only use the first of them in lexical order"},
+	}, cli.output.Findings())
+}
+
+func TestCheckClusterCIDRs(t *testing.T) {
+	vpcCIDRs, _ := helpers.ParseCIDRs("10.0.0.0/16")
+	subnets := []types.Subnet{testSubnet("subnet-priv-a", "vpc-1", "us-east-1a", "10.0.1.0/24")}
+	routeTables := map[string]types.RouteTable{
+		"": {
+			RouteTableId: aws.String("rtb-main"),
+			Routes: []types.Route{
+				{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+				{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1")},
+				{DestinationCidrBlock: aws.String("10.128.0.0/16"), VpcPeeringConnectionId: aws.String("pcx-1")},
+				{DestinationCidrBlock: aws.String("192.168.0.0/16"), TransitGatewayId: aws.String("tgw-1")},
+			},
+		},
+	}
+
+	cli := Client{logger: &logging.GlogLogger{}}
+	cli.checkClusterCIDRs(subnets, "vpc-1", vpcCIDRs, routeTables, options.ByoVPCOptions{
+		MachineCIDR: "192.168.128.0/20",
+		ServiceCIDR: "10.0.0.0/23",
+		PodCIDR:     "10.128.0.0/14",
+	})
+
+	var failed []output.Finding
+	for _, f := range cli.output.Findings() {
+		if f.Status == output.StatusFailed && f.Check != "machine-cidr-contains-subnet" {
+			failed = append(failed, f)
+		}
+	}
+	assert.Equal(t, []output.Finding{
+		{Check: "machine-cidr-overlap", Resource: "tgw-1", Status: output.StatusFailed,
+			Message: "machine CIDR 192.168.128.0/20 overlaps with 192.168.0.0/16, routed to transit gateway tgw-1 by route table rtb-main"},
+		{Check: "service-cidr-overlap", Resource: "subnet-priv-a", Status: output.StatusFailed,
+			Message: "service CIDR 10.0.0.0/23 overlaps with subnet CIDR 10.0.1.0/24"},
+		{Check: "pod-cidr-overlap", Resource: "pcx-1", Status: output.StatusFailed,
+			Message: "pod CIDR 10.128.0.0/14 overlaps with 10.128.0.0/16, routed to VPC peering connection pcx-1 by route table rtb-main"},
+	}, failed)
+}
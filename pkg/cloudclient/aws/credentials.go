@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	awscredsv1 "github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// PlatformName is the name the AWS provider is registered under
+const PlatformName string = "aws"
+
+// DefaultRoleSessionName is used for assumed roles when no session name is given
+const DefaultRoleSessionName string = "osd-network-verifier"
+
+// Role is an IAM role assumed on top of the credentials resolved before it
+type Role struct {
+	ARN         string
+	ExternalID  string
+	SessionName string
+}
+
+// Credentials describes how the AWS client authenticates. The base credentials are, in order:
+// - the static keys, if set
+// - the shared config Profile, if set
+// - otherwise the SDK's default chain (environment, SSO, web identity, instance role)
+// Every role in RoleChain is then assumed in order, each one using the credentials of the previous.
+type Credentials struct {
+	Profile         string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	RoleChain       []Role
+}
+
+func (Credentials) Platform() string { return PlatformName }
+
+// newSTSClient can be replaced in tests to avoid calling the real STS API
+var newSTSClient = func(cfg aws.Config) stscreds.AssumeRoleAPIClient {
+	return sts.NewFromConfig(cfg)
+}
+
+// Validate returns an error describing the first inconsistent field, if any
+func (c Credentials) Validate() error {
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return fmt.Errorf("both an access key ID and a secret access key are required for static credentials")
+	}
+	for i, role := range c.RoleChain {
+		if role.ARN == "" {
+			return fmt.Errorf("role %d in the role chain has no ARN", i)
+		}
+	}
+
+	return nil
+}
+
+// LoadConfig resolves the credentials into an aws.Config for the given region
+func (c Credentials) LoadConfig(ctx context.Context, region string) (aws.Config, error) {
+	if err := c.Validate(); err != nil {
+		return aws.Config{}, err
+	}
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	switch {
+	case c.AccessKeyID != "":
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, c.SessionToken),
+		))
+	case c.Profile != "":
+		opts = append(opts, config.WithSharedConfigProfile(c.Profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	for _, role := range c.RoleChain {
+		role := role
+		sessionName := role.SessionName
+		if sessionName == "" {
+			sessionName = DefaultRoleSessionName
+		}
+		provider := stscreds.NewAssumeRoleProvider(newSTSClient(cfg), role.ARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if role.ExternalID != "" {
+				o.ExternalID = aws.String(role.ExternalID)
+			}
+		})
+		// the next role in the chain is assumed using the credentials of this one
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cfg, nil
+}
+
+// v1CredentialsProvider exposes credentials resolved by LoadConfig to the SDK v1 clients,
+// the Route 53 APIs are used through the SDK v1 the project already depends on
+type v1CredentialsProvider struct {
+	ctx       context.Context
+	provider  aws.CredentialsProvider
+	canExpire bool
+	expires   time.Time
+}
+
+func (p *v1CredentialsProvider) Retrieve() (awscredsv1.Value, error) {
+	creds, err := p.provider.Retrieve(p.ctx)
+	if err != nil {
+		return awscredsv1.Value{}, err
+	}
+	p.canExpire, p.expires = creds.CanExpire, creds.Expires
+
+	return awscredsv1.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    creds.Source,
+	}, nil
+}
+
+func (p *v1CredentialsProvider) IsExpired() bool {
+	return p.canExpire && time.Now().After(p.expires)
+}
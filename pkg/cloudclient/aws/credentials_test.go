@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	stsTypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSTSClient hands out credentials named after the assumed role and records
+// which credentials were used to call it
+type fakeSTSClient struct {
+	callerCreds aws.CredentialsProvider
+	calls       *[]assumeRoleCall
+}
+
+type assumeRoleCall struct {
+	callerKeyID string
+	input       sts.AssumeRoleInput
+}
+
+func (f fakeSTSClient) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	caller, err := f.callerCreds.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	*f.calls = append(*f.calls, assumeRoleCall{callerKeyID: caller.AccessKeyID, input: *params})
+
+	return &sts.AssumeRoleOutput{
+		Credentials: &stsTypes.Credentials{
+			AccessKeyId:     params.RoleArn,
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+			Expiration:      aws.Time(time.Now().Add(stscreds.DefaultDuration)),
+		},
+	}, nil
+}
+
+func TestLoadConfigStatic(t *testing.T) {
+	creds := Credentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "token"}
+	cfg, err := creds.LoadConfig(context.TODO(), "us-east-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "us-east-1", cfg.Region)
+
+	value, err := cfg.Credentials.Retrieve(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "id", value.AccessKeyID)
+	assert.Equal(t, "token", value.SessionToken)
+}
+
+func TestLoadConfigRoleChain(t *testing.T) {
+	var calls []assumeRoleCall
+	defer func(orig func(aws.Config) stscreds.AssumeRoleAPIClient) { newSTSClient = orig }(newSTSClient)
+	newSTSClient = func(cfg aws.Config) stscreds.AssumeRoleAPIClient {
+		return fakeSTSClient{callerCreds: cfg.Credentials, calls: &calls}
+	}
+
+	creds := Credentials{
+		AccessKeyID:     "base",
+		SecretAccessKey: "secret",
+		RoleChain: []Role{
+			{ARN: "arn:aws:iam::111111111111:role/jump"},
+			{ARN: "arn:aws:iam::222222222222:role/customer", ExternalID: "ext", SessionName: "sre"},
+		},
+	}
+	cfg, err := creds.LoadConfig(context.TODO(), "us-east-1")
+	assert.NoError(t, err)
+
+	value, err := cfg.Credentials.Retrieve(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::222222222222:role/customer", value.AccessKeyID)
+
+	if assert.Len(t, calls, 2) {
+		// the first role is assumed with the base credentials, the second with the first role
+		assert.Equal(t, "base", calls[0].callerKeyID)
+		assert.Equal(t, DefaultRoleSessionName, aws.ToString(calls[0].input.RoleSessionName))
+		assert.Nil(t, calls[0].input.ExternalId)
+		assert.Equal(t, "arn:aws:iam::111111111111:role/jump", calls[1].callerKeyID)
+		assert.Equal(t, "sre", aws.ToString(calls[1].input.RoleSessionName))
+		assert.Equal(t, "ext", aws.ToString(calls[1].input.ExternalId))
+	}
+}
+
+func TestCredentialsValidate(t *testing.T) {
+	assert.Error(t, Credentials{AccessKeyID: "id"}.Validate())
+	assert.Error(t, Credentials{RoleChain: []Role{{}}}.Validate())
+	assert.NoError(t, Credentials{Profile: "default", RoleChain: []Role{{ARN: "arn"}}}.Validate())
+}
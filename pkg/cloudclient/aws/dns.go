@@ -0,0 +1,359 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53resolver"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// amazonProvidedDNS is the DHCP options value pointing instances to the VPC resolver
+const amazonProvidedDNS = "AmazonProvidedDNS"
+
+// dnsAttributeChecks names the check of each VPC attribute required by OSD
+var dnsAttributeChecks = map[ec2Types.VpcAttributeName]string{
+	ec2Types.VpcAttributeNameEnableDnsSupport:   "dns-support",
+	ec2Types.VpcAttributeNameEnableDnsHostnames: "dns-hostnames",
+}
+
+// criticalDomains returns the domains the cluster must resolve through the VPC resolver:
+// the AWS API endpoints and the internal hostnames of the nodes
+func criticalDomains(region string) []string {
+	return []string{
+		"amazonaws.com",
+		region + ".amazonaws.com",
+		"ec2.internal",
+		"compute.internal",
+		region + ".compute.internal",
+	}
+}
+
+// verifyDns performs verification process for VPC's DNS
+// Basic workflow is:
+// - ask AWS API for VPC attributes
+// - report each of them as verified, failed, or unknown when AWS API does not return it
+// - verify the DHCP options set, private hosted zones and resolver rules of the VPC
+// - if a subnet is given, resolve every egress endpoint through the VPC resolver from a probe instance
+func (c *Client) verifyDns(ctx context.Context, opts options.DnsOptions) *output.Output {
+	vpcID := opts.VpcID
+	c.logger.Info(ctx, "Verifying DNS config for VPC %s", vpcID)
+	for _, attribute := range []ec2Types.VpcAttributeName{ec2Types.VpcAttributeNameEnableDnsSupport, ec2Types.VpcAttributeNameEnableDnsHostnames} {
+		if class := c.verifyVpcAttribute(ctx, vpcID, attribute); class == errorClassNotFound {
+			// the other checks would fail the same way
+			return &c.output
+		}
+	}
+
+	eps, err := endpoints.Default(PlatformName, map[string]string{"AWS_REGION": c.region})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	c.verifyDhcpOptions(ctx, vpcID)
+	c.verifyPrivateHostedZones(ctx, vpcID, eps)
+	c.verifyResolverRules(ctx, vpcID, eps)
+
+	if opts.SubnetID != "" {
+		c.logger.Info(ctx, "Verifying DNS resolution from subnet %s", opts.SubnetID)
+		probeOutput, err := c.runProbe(ctx, options.EgressOptions{
+			SubnetID:     opts.SubnetID,
+			CloudImageID: opts.CloudImageID,
+			KmsKeyID:     opts.KmsKeyID,
+			Timeout:      options.DefaultEgressTimeout,
+		})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+		c.addResolutionResults(opts.SubnetID, probeOutput)
+	}
+
+	return &c.output
+}
+
+// reResolutionResult matches the resolution results printed by the network validator, e.g.
+// "DNS result quay.io via 10.0.0.2:53: NXDOMAIN"
+var reResolutionResult = regexp.MustCompile(`(?m)DNS result (\S+) via (\S+): ([A-Z]+) ?(.*)$`)
+
+// addResolutionResults reports the hosts the probe in the subnet could not resolve, or resolved to suspicious addresses,
+// through the VPC resolver. The resolution is unknown if the network validator printed no results, e.g. it did not run.
+func (c *Client) addResolutionResults(subnetID, probeOutput string) {
+	results := reResolutionResult.FindAllStringSubmatch(probeOutput, -1)
+	if len(results) == 0 {
+		c.output.AddFinding(output.Finding{Check: "dns-resolution", Resource: subnetID, Status: output.StatusUnknown,
+			Message: "the network validator printed no DNS results, the resolution through the VPC resolver was not verified"})
+		return
+	}
+
+	resolved := 0
+	resolvers := map[string]bool{}
+	for _, m := range results {
+		host, resolver, result, detail := m[1], m[2], m[3], strings.TrimSpace(m[4])
+		resolvers[resolver] = true
+		switch result {
+		case "OK":
+			resolved++
+		case "PRIVATE":
+			c.output.AddFinding(output.Finding{Check: "dns-resolution", Resource: host, Status: output.StatusWarning,
+				Message: fmt.Sprintf("resolves to the private addresses %s through %s, "+
+					"this is only expected for AWS services with a VPC endpoint", detail, resolver)})
+		default:
+			message := fmt.Sprintf("resolution through %s returned %s", resolver, result)
+			if detail != "" {
+				message += ": " + detail
+			}
+			c.output.AddFinding(output.Finding{Check: "dns-resolution", Resource: host, Status: output.StatusFailed, Message: message})
+		}
+	}
+
+	if resolved > 0 {
+		var names []string
+		for r := range resolvers {
+			names = append(names, r)
+		}
+		sort.Strings(names)
+		c.output.AddFinding(output.Finding{Check: "dns-resolution", Resource: strings.Join(names, ","), Status: output.StatusPassed,
+			Message: fmt.Sprintf("%d hosts resolved to public addresses through the VPC resolver", resolved)})
+	}
+}
+
+// verifyVpcAttribute reports whether the DNS attribute is enabled on the VPC,
+// it returns the class of the AWS API error if the attribute could not be read
+func (c *Client) verifyVpcAttribute(ctx context.Context, vpcID string, attribute ec2Types.VpcAttributeName) errorClass {
+	check := dnsAttributeChecks[attribute]
+	out, err := c.ec2Client.DescribeVpcAttribute(ctx, &ec2.DescribeVpcAttributeInput{
+		Attribute: attribute,
+		VpcId:     aws.String(vpcID),
+	})
+	if err != nil {
+		return c.addUnknownFinding(check, vpcID, fmt.Sprintf("read %s", attribute), err)
+	}
+
+	var value *ec2Types.AttributeBooleanValue
+	switch attribute {
+	case ec2Types.VpcAttributeNameEnableDnsSupport:
+		value = out.EnableDnsSupport
+	case ec2Types.VpcAttributeNameEnableDnsHostnames:
+		value = out.EnableDnsHostnames
+	}
+	if value == nil || value.Value == nil {
+		c.output.AddFinding(output.Finding{Check: check, Resource: vpcID, Status: output.StatusUnknown,
+			Message: fmt.Sprintf("AWS API returned no value for %s", attribute)})
+		return ""
+	}
+
+	c.logger.Info(ctx, "%s for VPC %s: %t", attribute, vpcID, *value.Value)
+	if !*value.Value {
+		c.output.AddFinding(output.Finding{Check: check, Resource: vpcID, Status: output.StatusFailed,
+			Message: fmt.Sprintf("%s is disabled, both DNS support and DNS hostnames must be enabled in order to be compatible with OSD", attribute)})
+		return ""
+	}
+	c.output.AddFinding(output.Finding{Check: check, Resource: vpcID, Status: output.StatusPassed,
+		Message: fmt.Sprintf("%s is enabled", attribute)})
+
+	return ""
+}
+
+// addUnknownFinding reports a requirement that could not be verified because of an AWS API error,
+// the error is also kept so that the verification is not reported as successful
+func (c *Client) addUnknownFinding(check, resource, action string, err error) errorClass {
+	class, code := classifyError(err)
+	c.output.AddUnknownFinding(check, resource, action, string(class), code)
+	c.output.AddError(fmt.Errorf("unable to %s of %s: %w", action, resource, err))
+
+	return class
+}
+
+// addUncheckedFinding reports a check beyond the VPC attributes, e.g. the resolver rules of the VPC, that could not be run
+// because of an AWS API error, usually a missing permission. Unlike addUnknownFinding, the error is only logged and
+// doesn't fail the verification.
+func (c *Client) addUncheckedFinding(ctx context.Context, check, resource, action string, err error) {
+	class, code := classifyError(err)
+	c.output.AddUnknownFinding(check, resource, action, string(class), code)
+	c.logger.Debug(ctx, "Unable to %s of %s: %s", action, resource, err)
+}
+
+// verifyDhcpOptions verifies the DHCP options set associated with the VPC
+// Basic workflow is:
+// - ask AWS API for the DHCP options set of the VPC
+// - warn when instances are not pointed to the VPC resolver
+// - fail when the domain name is one OpenShift cannot use as a node name suffix
+func (c *Client) verifyDhcpOptions(ctx context.Context, vpcID string) {
+	vpcsOut, err := c.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-dhcp-options", vpcID, "describe the VPC", err)
+		return
+	}
+	if len(vpcsOut.Vpcs) == 0 {
+		c.output.AddError(fmt.Errorf("VPC %s not found", vpcID))
+		return
+	}
+	dhcpID := aws.ToString(vpcsOut.Vpcs[0].DhcpOptionsId)
+	if dhcpID == "" || dhcpID == "default" {
+		c.output.AddFinding(output.Finding{Check: "dns-dhcp-options", Resource: vpcID, Status: output.StatusPassed,
+			Message: "the VPC uses the default DHCP options"})
+		return
+	}
+
+	dhcpOut, err := c.ec2Client.DescribeDhcpOptions(ctx, &ec2.DescribeDhcpOptionsInput{DhcpOptionsIds: []string{dhcpID}})
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-dhcp-options", dhcpID, "describe the DHCP options set", err)
+		return
+	}
+	if len(dhcpOut.DhcpOptions) == 0 {
+		c.output.AddError(fmt.Errorf("DHCP options set %s of VPC %s not found", dhcpID, vpcID))
+		return
+	}
+
+	settings := map[string][]string{}
+	for _, config := range dhcpOut.DhcpOptions[0].DhcpConfigurations {
+		for _, v := range config.Values {
+			settings[aws.ToString(config.Key)] = append(settings[aws.ToString(config.Key)], aws.ToString(v.Value))
+		}
+	}
+	c.logger.Debug(ctx, "DHCP options set %s: %v", dhcpID, settings)
+
+	flagged := false
+	servers := settings["domain-name-servers"]
+	if len(servers) > 0 && !helpers.Contains(servers, amazonProvidedDNS) {
+		c.output.AddFinding(output.Finding{Check: "dns-dhcp-options", Resource: dhcpID, Status: output.StatusWarning,
+			Message: fmt.Sprintf("instances use the custom DNS servers %s instead of %s, they must be reachable from every subnet "+
+				"and resolve public names, the AWS API endpoints and the VPC's private names", strings.Join(servers, ", "), amazonProvidedDNS)})
+		flagged = true
+	}
+
+	// the domain name becomes the suffix of the node names, which must be valid lowercase DNS names
+	for _, domain := range settings["domain-name"] {
+		switch {
+		case len(strings.Fields(domain)) > 1 || len(settings["domain-name"]) > 1:
+			c.output.AddFinding(output.Finding{Check: "dns-dhcp-options", Resource: dhcpID, Status: output.StatusFailed,
+				Message: fmt.Sprintf("domain name %q holds several domains, OpenShift only supports a single domain name", domain)})
+			flagged = true
+		case domain != strings.ToLower(domain):
+			c.output.AddFinding(output.Finding{Check: "dns-dhcp-options", Resource: dhcpID, Status: output.StatusFailed,
+				Message: fmt.Sprintf("domain name %q contains uppercase letters, which are not valid in OpenShift node names", domain)})
+			flagged = true
+		}
+	}
+
+	if !flagged {
+		c.output.AddFinding(output.Finding{Check: "dns-dhcp-options", Resource: dhcpID, Status: output.StatusPassed,
+			Message: "the DHCP options set is compatible with OpenShift"})
+	}
+}
+
+// verifyPrivateHostedZones verifies the Route 53 private hosted zones associated with the VPC
+// Basic workflow is:
+// - ask AWS API for the private hosted zones associated with the VPC
+// - fail when a zone shadows the AWS API endpoints or the node names
+// - warn when a zone shadows egress endpoints, which then only resolve if the zone has records for them
+func (c *Client) verifyPrivateHostedZones(ctx context.Context, vpcID string, eps []endpoints.Endpoint) {
+	var zones []*route53.HostedZoneSummary
+	input := &route53.ListHostedZonesByVPCInput{VPCId: awsv1.String(vpcID), VPCRegion: awsv1.String(c.region)}
+	for {
+		out, err := c.route53Client.ListHostedZonesByVPCWithContext(ctx, input)
+		if err != nil {
+			c.addUncheckedFinding(ctx, "dns-private-hosted-zone", vpcID, "list the private hosted zones", err)
+			return
+		}
+		zones = append(zones, out.HostedZoneSummaries...)
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	hosts := endpoints.Hosts(eps)
+	flagged := false
+	for _, zone := range zones {
+		name := helpers.NormalizeDomain(awsv1.StringValue(zone.Name))
+		zoneID := awsv1.StringValue(zone.HostedZoneId)
+		if critical := helpers.CoveredNames(name, criticalDomains(c.region)); len(critical) > 0 {
+			c.output.AddFinding(output.Finding{Check: "dns-private-hosted-zone", Resource: zoneID, Status: output.StatusFailed,
+				Message: fmt.Sprintf("private hosted zone %s shadows %s, names missing from the zone do not resolve in the VPC", name, strings.Join(critical, ", "))})
+			flagged = true
+			continue
+		}
+		if shadowed := helpers.CoveredNames(name, hosts); len(shadowed) > 0 {
+			c.output.AddFinding(output.Finding{Check: "dns-private-hosted-zone", Resource: zoneID, Status: output.StatusWarning,
+				Message: fmt.Sprintf("private hosted zone %s shadows the egress endpoints %s, they only resolve if the zone has records for them", name, strings.Join(shadowed, ", "))})
+			flagged = true
+		}
+	}
+
+	if !flagged {
+		c.output.AddFinding(output.Finding{Check: "dns-private-hosted-zone", Resource: vpcID, Status: output.StatusPassed,
+			Message: fmt.Sprintf("none of the %d private hosted zones shadows the AWS API or egress endpoints", len(zones))})
+	}
+}
+
+// verifyResolverRules verifies the Route 53 Resolver forwarding rules associated with the VPC
+// Basic workflow is:
+// - ask AWS API for the resolver rules associated with the VPC
+// - warn when queries for the AWS API endpoints, the node names or the egress endpoints are forwarded
+func (c *Client) verifyResolverRules(ctx context.Context, vpcID string, eps []endpoints.Endpoint) {
+	var associations []*route53resolver.ResolverRuleAssociation
+	input := &route53resolver.ListResolverRuleAssociationsInput{
+		Filters: []*route53resolver.Filter{{Name: awsv1.String("VPCId"), Values: []*string{awsv1.String(vpcID)}}},
+	}
+	for {
+		out, err := c.resolverClient.ListResolverRuleAssociationsWithContext(ctx, input)
+		if err != nil {
+			c.addUncheckedFinding(ctx, "dns-resolver-rule", vpcID, "list the resolver rules", err)
+			return
+		}
+		associations = append(associations, out.ResolverRuleAssociations...)
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	hosts := append(criticalDomains(c.region), endpoints.Hosts(eps)...)
+	flagged := false
+	for _, assoc := range associations {
+		ruleOut, err := c.resolverClient.GetResolverRuleWithContext(ctx, &route53resolver.GetResolverRuleInput{ResolverRuleId: assoc.ResolverRuleId})
+		if err != nil {
+			c.addUncheckedFinding(ctx, "dns-resolver-rule", awsv1.StringValue(assoc.ResolverRuleId), "get the resolver rule", err)
+			flagged = true
+			continue
+		}
+		rule := ruleOut.ResolverRule
+		if rule == nil || awsv1.StringValue(rule.RuleType) != route53resolver.RuleTypeOptionForward {
+			continue
+		}
+
+		var targets []string
+		for _, t := range rule.TargetIps {
+			targets = append(targets, fmt.Sprintf("%s:%d", awsv1.StringValue(t.Ip), awsv1.Int64Value(t.Port)))
+		}
+		domain := helpers.NormalizeDomain(awsv1.StringValue(rule.DomainName))
+		ruleID := awsv1.StringValue(rule.Id)
+		if domain == "" {
+			c.output.AddFinding(output.Finding{Check: "dns-resolver-rule", Resource: ruleID, Status: output.StatusWarning,
+				Message: fmt.Sprintf("all DNS queries are forwarded to %s, they must resolve public names, the AWS API endpoints and the node names", strings.Join(targets, ", "))})
+			flagged = true
+			continue
+		}
+		if forwarded := helpers.CoveredNames(domain, hosts); len(forwarded) > 0 {
+			c.output.AddFinding(output.Finding{Check: "dns-resolver-rule", Resource: ruleID, Status: output.StatusWarning,
+				Message: fmt.Sprintf("queries for %s are forwarded to %s, they must resolve %s", domain, strings.Join(targets, ", "), strings.Join(forwarded, ", "))})
+			flagged = true
+		}
+	}
+
+	if !flagged {
+		c.output.AddFinding(output.Finding{Check: "dns-resolver-rule", Resource: vpcID, Status: output.StatusPassed,
+			Message: fmt.Sprintf("none of the %d resolver rules forwards queries the cluster depends on", len(associations))})
+	}
+}
@@ -0,0 +1,326 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53resolver"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func dhcpConfiguration(key string, values ...string) types.DhcpConfiguration {
+	config := types.DhcpConfiguration{Key: aws.String(key)}
+	for _, v := range values {
+		config.Values = append(config.Values, types.AttributeValue{Value: aws.String(v)})
+	}
+	return config
+}
+
+func TestVerifyDns(t *testing.T) {
+	vpcID := "vpc-1"
+	tests := []struct {
+		name          string
+		dhcp          []types.DhcpConfiguration
+		zones         []string
+		forwarded     []string
+		expectResults map[string]output.Status
+	}{
+		{
+			name:  "default configuration",
+			dhcp:  []types.DhcpConfiguration{dhcpConfiguration("domain-name-servers", amazonProvidedDNS), dhcpConfiguration("domain-name", "ec2.internal")},
+			zones: []string{"mycluster.example.com."},
+			expectResults: map[string]output.Status{
+				"dns-support":             output.StatusPassed,
+				"dns-hostnames":           output.StatusPassed,
+				"dns-dhcp-options":        output.StatusPassed,
+				"dns-private-hosted-zone": output.StatusPassed,
+				"dns-resolver-rule":       output.StatusPassed,
+			},
+		},
+		{
+			name:      "custom resolvers and uppercase domain name",
+			dhcp:      []types.DhcpConfiguration{dhcpConfiguration("domain-name-servers", "10.0.0.2"), dhcpConfiguration("domain-name", "Corp.Example.com")},
+			forwarded: []string{"."},
+			expectResults: map[string]output.Status{
+				"dns-support":             output.StatusPassed,
+				"dns-hostnames":           output.StatusPassed,
+				"dns-dhcp-options":        output.StatusFailed,
+				"dns-private-hosted-zone": output.StatusPassed,
+				"dns-resolver-rule":       output.StatusWarning,
+			},
+		},
+		{
+			name:      "zone shadowing the AWS API endpoints",
+			dhcp:      []types.DhcpConfiguration{dhcpConfiguration("domain-name-servers", amazonProvidedDNS)},
+			zones:     []string{"amazonaws.com."},
+			forwarded: []string{"example.com."},
+			expectResults: map[string]output.Status{
+				"dns-support":             output.StatusPassed,
+				"dns-hostnames":           output.StatusPassed,
+				"dns-dhcp-options":        output.StatusPassed,
+				"dns-private-hosted-zone": output.StatusFailed,
+				"dns-resolver-rule":       output.StatusPassed,
+			},
+		},
+		{
+			name:  "zone shadowing an egress endpoint",
+			dhcp:  []types.DhcpConfiguration{dhcpConfiguration("domain-name-servers", amazonProvidedDNS)},
+			zones: []string{"quay.io."},
+			expectResults: map[string]output.Status{
+				"dns-support":             output.StatusPassed,
+				"dns-hostnames":           output.StatusPassed,
+				"dns-dhcp-options":        output.StatusPassed,
+				"dns-private-hosted-zone": output.StatusWarning,
+				"dns-resolver-rule":       output.StatusPassed,
+			},
+		},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+		FakeEC2Cli.EXPECT().DescribeVpcAttribute(gomock.Any(), gomock.Any()).Times(2).Return(&ec2.DescribeVpcAttributeOutput{
+			EnableDnsSupport:   &types.AttributeBooleanValue{Value: aws.Bool(true)},
+			EnableDnsHostnames: &types.AttributeBooleanValue{Value: aws.Bool(true)},
+		}, nil)
+		FakeEC2Cli.EXPECT().DescribeVpcs(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeVpcsOutput{
+			Vpcs: []types.Vpc{{VpcId: aws.String(vpcID), DhcpOptionsId: aws.String("dopt-1")}},
+		}, nil)
+		FakeEC2Cli.EXPECT().DescribeDhcpOptions(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeDhcpOptionsOutput{
+			DhcpOptions: []types.DhcpOptions{{DhcpOptionsId: aws.String("dopt-1"), DhcpConfigurations: test.dhcp}},
+		}, nil)
+
+		FakeRoute53Cli := mocks.NewMockRoute53Client(ctrl)
+		zonesOut := &route53.ListHostedZonesByVPCOutput{}
+		for _, zone := range test.zones {
+			zonesOut.HostedZoneSummaries = append(zonesOut.HostedZoneSummaries, &route53.HostedZoneSummary{
+				HostedZoneId: awsv1.String("Z" + zone),
+				Name:         awsv1.String(zone),
+			})
+		}
+		FakeRoute53Cli.EXPECT().ListHostedZonesByVPCWithContext(gomock.Any(), gomock.Any()).Times(1).Return(zonesOut, nil)
+
+		FakeResolverCli := mocks.NewMockResolverClient(ctrl)
+		associationsOut := &route53resolver.ListResolverRuleAssociationsOutput{}
+		for _, domain := range test.forwarded {
+			associationsOut.ResolverRuleAssociations = append(associationsOut.ResolverRuleAssociations, &route53resolver.ResolverRuleAssociation{
+				ResolverRuleId: awsv1.String("rslvr-rr-" + domain),
+			})
+			FakeResolverCli.EXPECT().GetResolverRuleWithContext(gomock.Any(), gomock.Any()).Times(1).Return(&route53resolver.GetResolverRuleOutput{
+				ResolverRule: &route53resolver.ResolverRule{
+					Id:         awsv1.String("rslvr-rr-" + domain),
+					DomainName: awsv1.String(domain),
+					RuleType:   awsv1.String(route53resolver.RuleTypeOptionForward),
+					TargetIps:  []*route53resolver.TargetAddress{{Ip: awsv1.String("192.168.0.2"), Port: awsv1.Int64(53)}},
+				},
+			}, nil)
+		}
+		FakeResolverCli.EXPECT().ListResolverRuleAssociationsWithContext(gomock.Any(), gomock.Any()).Times(1).Return(associationsOut, nil)
+
+		cli := Client{
+			ec2Client:      FakeEC2Cli,
+			route53Client:  FakeRoute53Cli,
+			resolverClient: FakeResolverCli,
+			region:         "us-east-1",
+			logger:         &logging.GlogLogger{},
+		}
+		out := cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: vpcID})
+
+		results := map[string]output.Status{}
+		for _, f := range out.Findings() {
+			results[f.Check] = f.Status
+		}
+		assert.Equal(t, test.expectResults, results, test.name)
+		ctrl.Finish()
+	}
+}
+
+func TestVerifyDnsAttributeErrors(t *testing.T) {
+	vpcID := "vpc-1"
+	tests := []struct {
+		name          string
+		supportErr    error
+		hostnames     *types.AttributeBooleanValue
+		expectResults map[string]output.Status
+		expectMessage string
+	}{
+		{
+			name:       "wrong VPC ID",
+			supportErr: &smithy.GenericAPIError{Code: "InvalidVpcID.NotFound", Message: "The vpc ID 'vpc-1' does not exist"},
+			expectResults: map[string]output.Status{
+				"dns-support": output.StatusUnknown,
+			},
+			expectMessage: "unable to read enableDnsSupport: not found (InvalidVpcID.NotFound)",
+		},
+		{
+			name:       "missing permission and disabled hostnames",
+			supportErr: &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "You are not authorized to perform this operation."},
+			hostnames:  &types.AttributeBooleanValue{Value: aws.Bool(false)},
+			expectResults: map[string]output.Status{
+				"dns-support":   output.StatusUnknown,
+				"dns-hostnames": output.StatusFailed,
+			},
+			expectMessage: "unable to read enableDnsSupport: access denied (UnauthorizedOperation)",
+		},
+		{
+			name:       "throttled and no value returned",
+			supportErr: &smithy.GenericAPIError{Code: "RequestLimitExceeded", Message: "Request limit exceeded."},
+			expectResults: map[string]output.Status{
+				"dns-support":   output.StatusUnknown,
+				"dns-hostnames": output.StatusUnknown,
+			},
+			expectMessage: "unable to read enableDnsSupport: throttled (RequestLimitExceeded)",
+		},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+		FakeEC2Cli.EXPECT().DescribeVpcAttribute(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, input *ec2.DescribeVpcAttributeInput, _ ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error) {
+				if input.Attribute == types.VpcAttributeNameEnableDnsSupport {
+					return nil, test.supportErr
+				}
+				return &ec2.DescribeVpcAttributeOutput{EnableDnsHostnames: test.hostnames}, nil
+			}).AnyTimes()
+		// the remaining checks are not under test, make them fail fast
+		FakeEC2Cli.EXPECT().DescribeVpcs(gomock.Any(), gomock.Any()).Return(&ec2.DescribeVpcsOutput{
+			Vpcs: []types.Vpc{{VpcId: aws.String(vpcID), DhcpOptionsId: aws.String("default")}},
+		}, nil).AnyTimes()
+		FakeRoute53Cli := mocks.NewMockRoute53Client(ctrl)
+		FakeRoute53Cli.EXPECT().ListHostedZonesByVPCWithContext(gomock.Any(), gomock.Any()).Return(&route53.ListHostedZonesByVPCOutput{}, nil).AnyTimes()
+		FakeResolverCli := mocks.NewMockResolverClient(ctrl)
+		FakeResolverCli.EXPECT().ListResolverRuleAssociationsWithContext(gomock.Any(), gomock.Any()).Return(&route53resolver.ListResolverRuleAssociationsOutput{}, nil).AnyTimes()
+
+		cli := Client{
+			ec2Client:      FakeEC2Cli,
+			route53Client:  FakeRoute53Cli,
+			resolverClient: FakeResolverCli,
+			region:         "us-east-1",
+			logger:         &logging.GlogLogger{},
+		}
+		var out *output.Output
+		assert.NotPanics(t, func() { out = cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: vpcID}) }, test.name)
+
+		results := map[string]output.Status{}
+		var messages []string
+		for _, f := range out.Findings() {
+			if f.Check == "dns-support" || f.Check == "dns-hostnames" {
+				results[f.Check] = f.Status
+				messages = append(messages, f.Message)
+			}
+		}
+		assert.Equal(t, test.expectResults, results, test.name)
+		assert.Contains(t, messages, test.expectMessage, test.name)
+		assert.False(t, out.IsSuccessful(), test.name)
+		ctrl.Finish()
+	}
+}
+
+func TestVerifyDnsOptionalCheckErrors(t *testing.T) {
+	vpcID := "vpc-1"
+	denied := &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "You are not authorized to perform this operation."}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+	FakeEC2Cli.EXPECT().DescribeVpcAttribute(gomock.Any(), gomock.Any()).Return(&ec2.DescribeVpcAttributeOutput{
+		EnableDnsSupport:   &types.AttributeBooleanValue{Value: aws.Bool(true)},
+		EnableDnsHostnames: &types.AttributeBooleanValue{Value: aws.Bool(true)},
+	}, nil).Times(2)
+	FakeEC2Cli.EXPECT().DescribeVpcs(gomock.Any(), gomock.Any()).Return(&ec2.DescribeVpcsOutput{
+		Vpcs: []types.Vpc{{VpcId: aws.String(vpcID), DhcpOptionsId: aws.String("dopt-1")}},
+	}, nil)
+	FakeEC2Cli.EXPECT().DescribeDhcpOptions(gomock.Any(), gomock.Any()).Return(nil, denied)
+	FakeRoute53Cli := mocks.NewMockRoute53Client(ctrl)
+	FakeRoute53Cli.EXPECT().ListHostedZonesByVPCWithContext(gomock.Any(), gomock.Any()).Return(nil,
+		awserr.New("AccessDenied", "User is not authorized to perform: route53:ListHostedZonesByVPC", nil))
+	FakeResolverCli := mocks.NewMockResolverClient(ctrl)
+	FakeResolverCli.EXPECT().ListResolverRuleAssociationsWithContext(gomock.Any(), gomock.Any()).Return(nil,
+		awserr.New("AccessDeniedException", "User is not authorized to perform: route53resolver:ListResolverRuleAssociations", nil))
+
+	cli := Client{
+		ec2Client:      FakeEC2Cli,
+		route53Client:  FakeRoute53Cli,
+		resolverClient: FakeResolverCli,
+		region:         "us-east-1",
+		logger:         &logging.GlogLogger{},
+	}
+	out := cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: vpcID})
+
+	results := map[string]output.Status{}
+	for _, f := range out.Findings() {
+		results[f.Check] = f.Status
+	}
+	assert.Equal(t, map[string]output.Status{
+		"dns-support":             output.StatusPassed,
+		"dns-hostnames":           output.StatusPassed,
+		"dns-dhcp-options":        output.StatusUnknown,
+		"dns-private-hosted-zone": output.StatusUnknown,
+		"dns-resolver-rule":       output.StatusUnknown,
+	}, results)
+	// only the VPC attributes are required, the other checks degrade to unknown findings
+	assert.True(t, out.IsSuccessful())
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err         error
+		expectClass errorClass
+	}{
+		{err: &smithy.GenericAPIError{Code: "InvalidVpcID.Malformed"}, expectClass: errorClassNotFound},
+		{err: fmt.Errorf("operation error: %w", &smithy.GenericAPIError{Code: "UnauthorizedOperation"}), expectClass: errorClassAccessDenied},
+		{err: awserr.New("AccessDenied", "User is not authorized to perform route53:ListHostedZonesByVPC", nil), expectClass: errorClassAccessDenied},
+		{err: awserr.New("ThrottlingException", "Rate exceeded", nil), expectClass: errorClassThrottled},
+		{err: awserr.New("ResourceNotFoundException", "Resolver rule not found", nil), expectClass: errorClassNotFound},
+		{err: fmt.Errorf("dial tcp: i/o timeout"), expectClass: errorClassUnknown},
+	}
+	for _, test := range tests {
+		class, _ := classifyError(test.err)
+		assert.Equal(t, test.expectClass, class, test.err.Error())
+	}
+}
+
+func TestAddResolutionResults(t *testing.T) {
+	probeOutput := `DNS resolver: 10.0.0.2:53
+DNS result quay.io via 10.0.0.2:53: OK 52.0.0.1,52.0.0.2
+DNS result api.openshift.com via 10.0.0.2:53: NXDOMAIN
+DNS result registry.redhat.io via 10.0.0.2:53: PRIVATE 0.0.0.0
+DNS result sso.redhat.com via 10.0.0.2:53: TIMEOUT no answer within 2s after 3 retries
+Validating quay.io:443
+`
+	cli := Client{logger: &logging.GlogLogger{}}
+	cli.addResolutionResults("subnet-1", probeOutput)
+
+	assert.Equal(t, []output.Finding{
+		{Check: "dns-resolution", Resource: "api.openshift.com", Status: output.StatusFailed,
+			Message: "resolution through 10.0.0.2:53 returned NXDOMAIN"},
+		{Check: "dns-resolution", Resource: "registry.redhat.io", Status: output.StatusWarning,
+			Message: "resolves to the private addresses 0.0.0.0 through 10.0.0.2:53, this is only expected for AWS services with a VPC endpoint"},
+		{Check: "dns-resolution", Resource: "sso.redhat.com", Status: output.StatusFailed,
+			Message: "resolution through 10.0.0.2:53 returned TIMEOUT: no answer within 2s after 3 retries"},
+		{Check: "dns-resolution", Resource: "10.0.0.2:53", Status: output.StatusPassed,
+			Message: "1 hosts resolved to public addresses through the VPC resolver"},
+	}, cli.output.Findings())
+	assert.False(t, cli.output.IsSuccessful())
+}
+
+func TestAddResolutionResultsMissing(t *testing.T) {
+	cli := Client{logger: &logging.GlogLogger{}}
+	cli.addResolutionResults("subnet-1", "USERDATA BEGIN\nFailed to successfully run the docker container\nUSERDATA END\n")
+
+	assert.Equal(t, []output.Finding{
+		{Check: "dns-resolution", Resource: "subnet-1", Status: output.StatusUnknown,
+			Message: "the network validator printed no DNS results, the resolution through the VPC resolver was not verified"},
+	}, cli.output.Findings())
+}
@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/smithy-go"
+)
+
+// errorClass tells apart the AWS API errors a user can act on: a wrong ID, a missing permission or too many calls
+type errorClass string
+
+const (
+	errorClassNotFound     errorClass = "not found"
+	errorClassAccessDenied errorClass = "access denied"
+	errorClassThrottled    errorClass = "throttled"
+	errorClassUnknown      errorClass = "unclassified error"
+)
+
+var (
+	accessDeniedCodes = map[string]bool{
+		"UnauthorizedOperation": true,
+		"AccessDenied":          true,
+		"AccessDeniedException": true,
+		"AuthFailure":           true,
+	}
+	throttledCodes = map[string]bool{
+		"RequestLimitExceeded":      true,
+		"Throttling":                true,
+		"ThrottlingException":       true,
+		"TooManyRequestsException":  true,
+		"PriorRequestNotComplete":   true,
+		"RequestThrottledException": true,
+	}
+)
+
+// classifyError returns the class and the code of an error returned by either AWS SDK
+func classifyError(err error) (errorClass, string) {
+	var code string
+	var apiErr smithy.APIError
+	var v1Err awserr.Error
+	switch {
+	case errors.As(err, &apiErr):
+		code = apiErr.ErrorCode()
+	case errors.As(err, &v1Err):
+		code = v1Err.Code()
+	default:
+		return errorClassUnknown, ""
+	}
+
+	switch {
+	// e.g. InvalidVpcID.NotFound, InvalidVpcID.Malformed, NoSuchHostedZone, ResourceNotFoundException
+	case strings.HasSuffix(code, ".NotFound"), strings.HasSuffix(code, ".Malformed"),
+		strings.HasPrefix(code, "NoSuch"), strings.HasSuffix(code, "NotFoundException"):
+		return errorClassNotFound, code
+	case accessDeniedCodes[code]:
+		return errorClassAccessDenied, code
+	case throttledCodes[code]:
+		return errorClassThrottled, code
+	}
+
+	return errorClassUnknown, code
+}
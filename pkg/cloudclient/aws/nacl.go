@@ -0,0 +1,201 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+var (
+	// lookupIP resolves endpoint hosts, it can be replaced in tests
+	lookupIP = net.LookupIP
+
+	// Return traffic reaches the nodes on the ephemeral source port of the connection. Traffic
+	// going through NAT gateways and load balancers uses 1024-65535, the range AWS recommends
+	// to open in network ACLs, rather than the narrower ephemeral port range of RHCOS
+	ephemeralPortFrom = 1024
+	ephemeralPortTo   = 65535
+)
+
+// defaultACLRuleNumber is the number AWS reports for the catch-all (*) rule
+const defaultACLRuleNumber int32 = 32767
+
+// aclDecision is the outcome of evaluating a flow against a network ACL,
+// a ruleNumber of 0 means no rule matched at all
+type aclDecision struct {
+	allowed    bool
+	ruleNumber int32
+}
+
+func (d aclDecision) rule() string {
+	if d.ruleNumber == 0 || d.ruleNumber == defaultACLRuleNumber {
+		return "the default (*) rule"
+	}
+	return fmt.Sprintf("rule %d", d.ruleNumber)
+}
+
+// verifyNetworkACLs evaluates the network ACLs of the given subnets against the egress endpoints
+// Basic workflow is:
+// - ask AWS API for the network ACLs associated with the subnets
+// - resolve the IPv4 addresses of every endpoint host
+// - evaluate the outbound rules in order for each host and port
+// - evaluate the inbound rules in order for the return traffic to the ephemeral port range
+// - report which rule would deny each flow
+func (c *Client) verifyNetworkACLs(ctx context.Context, opts options.NetworkACLOptions) *output.Output {
+	eps := opts.Endpoints
+	if len(eps) == 0 {
+		var err error
+		eps, err = endpoints.Default(PlatformName, map[string]string{"AWS_REGION": c.region})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+	}
+
+	c.logger.Info(ctx, "Evaluating network ACLs of subnets %v against %d endpoints", opts.SubnetIDs, len(eps))
+	aclsOut, err := c.ec2Client.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{
+		Filters: []ec2Types.Filter{{
+			Name:   aws.String("association.subnet-id"),
+			Values: opts.SubnetIDs,
+		}},
+	})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	aclBySubnet := map[string]ec2Types.NetworkAcl{}
+	for _, acl := range aclsOut.NetworkAcls {
+		for _, assoc := range acl.Associations {
+			aclBySubnet[aws.ToString(assoc.SubnetId)] = acl
+		}
+	}
+
+	addresses := endpoints.ResolveIPv4(ctx, c.logger, &c.output, "nacl-egress", eps, lookupIP)
+	for _, subnetID := range opts.SubnetIDs {
+		acl, ok := aclBySubnet[subnetID]
+		if !ok {
+			c.output.AddFinding(output.Finding{Check: "nacl-egress", Resource: subnetID, Status: output.StatusUnknown,
+				Message: "no network ACL is associated with the subnet"})
+			continue
+		}
+		c.evaluateNetworkACL(subnetID, acl, eps, addresses)
+	}
+
+	return &c.output
+}
+
+// evaluateNetworkACL adds a failed finding for every denied flow, or a single passed finding if all flows are allowed
+func (c *Client) evaluateNetworkACL(subnetID string, acl ec2Types.NetworkAcl, eps []endpoints.Endpoint, addresses map[string][]net.IP) {
+	aclID := aws.ToString(acl.NetworkAclId)
+	entries := sortedACLEntries(acl.Entries)
+	denied := 0
+	for _, e := range eps {
+		for _, port := range e.Ports {
+			// report the first denied address only, the others are usually denied by the same rule
+			for _, ip := range addresses[e.Host] {
+				if d := evaluateACL(entries, true, ip, port); !d.allowed {
+					c.output.AddFinding(output.Finding{Check: "nacl-egress", Resource: subnetID, Status: output.StatusFailed,
+						Message: fmt.Sprintf("outbound traffic to %s (%s) on port %d is denied by %s of %s", e.Host, ip, port, d.rule(), aclID)})
+					denied++
+					break
+				}
+				if d, ephemeralPort, ok := firstDeniedInRange(entries, false, ip, ephemeralPortFrom, ephemeralPortTo); ok {
+					c.output.AddFinding(output.Finding{Check: "nacl-egress", Resource: subnetID, Status: output.StatusFailed,
+						Message: fmt.Sprintf("return traffic from %s (%s) port %d to ephemeral port %d is denied by %s of %s",
+							e.Host, ip, port, ephemeralPort, d.rule(), aclID)})
+					denied++
+					break
+				}
+			}
+		}
+	}
+
+	if denied == 0 {
+		c.output.AddFinding(output.Finding{Check: "nacl-egress", Resource: subnetID, Status: output.StatusPassed,
+			Message: fmt.Sprintf("%s allows egress to all %d endpoints and the return traffic", aclID, len(eps))})
+	}
+}
+
+// sortedACLEntries returns the entries ordered by rule number, the order in which AWS evaluates them
+func sortedACLEntries(entries []ec2Types.NetworkAclEntry) []ec2Types.NetworkAclEntry {
+	sorted := append([]ec2Types.NetworkAclEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return aws.ToInt32(sorted[i].RuleNumber) < aws.ToInt32(sorted[j].RuleNumber)
+	})
+
+	return sorted
+}
+
+// aclEntryMatches returns true if the entry applies to TCP traffic of the given direction, address and port
+func aclEntryMatches(entry ec2Types.NetworkAclEntry, egress bool, ip net.IP, port int) bool {
+	if aws.ToBool(entry.Egress) != egress {
+		return false
+	}
+	protocol := aws.ToString(entry.Protocol)
+	if protocol != "-1" && protocol != "6" {
+		return false
+	}
+
+	cidr := aws.ToString(entry.CidrBlock)
+	if cidr == "" {
+		cidr = aws.ToString(entry.Ipv6CidrBlock)
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil || !network.Contains(ip) {
+		return false
+	}
+
+	if protocol == "6" && entry.PortRange != nil {
+		from, to := int(aws.ToInt32(entry.PortRange.From)), int(aws.ToInt32(entry.PortRange.To))
+		if port < from || port > to {
+			return false
+		}
+	}
+
+	return true
+}
+
+// evaluateACL returns the decision of the first rule (by number) matching the flow, entries must be sorted
+func evaluateACL(entries []ec2Types.NetworkAclEntry, egress bool, ip net.IP, port int) aclDecision {
+	for _, entry := range entries {
+		if aclEntryMatches(entry, egress, ip, port) {
+			return aclDecision{
+				allowed:    entry.RuleAction == ec2Types.RuleActionAllow,
+				ruleNumber: aws.ToInt32(entry.RuleNumber),
+			}
+		}
+	}
+
+	return aclDecision{allowed: false}
+}
+
+// firstDeniedInRange returns the decision for the first port in [from, to] that is denied, if any.
+// The decision can only change at the boundaries of the port ranges of the rules, so only those are evaluated.
+func firstDeniedInRange(entries []ec2Types.NetworkAclEntry, egress bool, ip net.IP, from, to int) (aclDecision, int, bool) {
+	candidates := []int{from}
+	for _, entry := range entries {
+		if entry.PortRange == nil {
+			continue
+		}
+		for _, p := range []int{int(aws.ToInt32(entry.PortRange.From)), int(aws.ToInt32(entry.PortRange.To)) + 1} {
+			if p > from && p <= to {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	sort.Ints(candidates)
+
+	for _, port := range candidates {
+		if d := evaluateACL(entries, egress, ip, port); !d.allowed {
+			return d, port, true
+		}
+	}
+
+	return aclDecision{}, 0, false
+}
@@ -0,0 +1,125 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/golang/mock/gomock"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func aclEntry(number int32, egress bool, protocol, cidr string, from, to int32, action types.RuleAction) types.NetworkAclEntry {
+	entry := types.NetworkAclEntry{
+		RuleNumber: aws.Int32(number),
+		Egress:     aws.Bool(egress),
+		Protocol:   aws.String(protocol),
+		CidrBlock:  aws.String(cidr),
+		RuleAction: action,
+	}
+	if protocol != "-1" {
+		entry.PortRange = &types.PortRange{From: aws.Int32(from), To: aws.Int32(to)}
+	}
+	return entry
+}
+
+func TestVerifyNetworkACLs(t *testing.T) {
+	defer func(orig func(string) ([]net.IP, error)) { lookupIP = orig }(lookupIP)
+	lookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "quay.io":
+			return []net.IP{net.ParseIP("203.0.113.10")}, nil
+		case "inputs1.osdsecuritylogs.splunkcloud.com":
+			return []net.IP{net.ParseIP("198.51.100.20")}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+	eps := []endpoints.Endpoint{
+		{Host: "quay.io", Ports: []int{443}},
+		{Host: "inputs1.osdsecuritylogs.splunkcloud.com", Ports: []int{9997}},
+	}
+	defaultDeny := []types.NetworkAclEntry{
+		aclEntry(defaultACLRuleNumber, true, "-1", "0.0.0.0/0", 0, 0, types.RuleActionDeny),
+		aclEntry(defaultACLRuleNumber, false, "-1", "0.0.0.0/0", 0, 0, types.RuleActionDeny),
+	}
+
+	tests := []struct {
+		name           string
+		entries        []types.NetworkAclEntry
+		expectFailures []string
+	}{
+		{
+			name: "allow all",
+			entries: append([]types.NetworkAclEntry{
+				aclEntry(100, true, "-1", "0.0.0.0/0", 0, 0, types.RuleActionAllow),
+				aclEntry(100, false, "-1", "0.0.0.0/0", 0, 0, types.RuleActionAllow),
+			}, defaultDeny...),
+		},
+		{
+			name: "splunk port denied before the allow rule",
+			entries: append([]types.NetworkAclEntry{
+				aclEntry(200, true, "-1", "0.0.0.0/0", 0, 0, types.RuleActionAllow),
+				aclEntry(90, true, "6", "198.51.100.0/24", 9000, 9999, types.RuleActionDeny),
+				aclEntry(100, false, "-1", "0.0.0.0/0", 0, 0, types.RuleActionAllow),
+			}, defaultDeny...),
+			expectFailures: []string{"outbound traffic to inputs1.osdsecuritylogs.splunkcloud.com (198.51.100.20) on port 9997 is denied by rule 90 of acl-1"},
+		},
+		{
+			name: "ephemeral return ports not fully allowed",
+			entries: append([]types.NetworkAclEntry{
+				aclEntry(100, true, "6", "0.0.0.0/0", 0, 65535, types.RuleActionAllow),
+				aclEntry(100, false, "6", "0.0.0.0/0", 1024, 40000, types.RuleActionAllow),
+			}, defaultDeny...),
+			expectFailures: []string{
+				"return traffic from quay.io (203.0.113.10) port 443 to ephemeral port 40001 is denied by the default (*) rule of acl-1",
+				"return traffic from inputs1.osdsecuritylogs.splunkcloud.com (198.51.100.20) port 9997 to ephemeral port 40001 is denied by the default (*) rule of acl-1",
+			},
+		},
+		{
+			name: "only the RHCOS ephemeral range allowed",
+			entries: append([]types.NetworkAclEntry{
+				aclEntry(100, true, "6", "0.0.0.0/0", 0, 65535, types.RuleActionAllow),
+				aclEntry(100, false, "6", "0.0.0.0/0", 32768, 60999, types.RuleActionAllow),
+			}, defaultDeny...),
+			expectFailures: []string{
+				"return traffic from quay.io (203.0.113.10) port 443 to ephemeral port 1024 is denied by the default (*) rule of acl-1",
+				"return traffic from inputs1.osdsecuritylogs.splunkcloud.com (198.51.100.20) port 9997 to ephemeral port 1024 is denied by the default (*) rule of acl-1",
+			},
+		},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+		FakeEC2Cli.EXPECT().DescribeNetworkAcls(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeNetworkAclsOutput{
+			NetworkAcls: []types.NetworkAcl{{
+				NetworkAclId: aws.String("acl-1"),
+				Associations: []types.NetworkAclAssociation{{SubnetId: aws.String("subnet-1")}},
+				Entries:      test.entries,
+			}},
+		}, nil)
+		cli := Client{
+			ec2Client: FakeEC2Cli,
+			logger:    &logging.GlogLogger{},
+		}
+		out := cli.VerifyNetworkACLs(context.TODO(), options.NetworkACLOptions{SubnetIDs: []string{"subnet-1"}, Endpoints: eps})
+
+		var failures []string
+		for _, f := range out.Findings() {
+			if f.Status == output.StatusFailed {
+				failures = append(failures, f.Message)
+			}
+		}
+		assert.ElementsMatch(t, test.expectFailures, failures, test.name)
+		assert.Equal(t, len(test.expectFailures) == 0, out.IsSuccessful(), test.name)
+		ctrl.Finish()
+	}
+}
@@ -6,29 +6,35 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	awscredsv1 "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53resolver"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
 	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 	"github.com/openshift/osd-network-verifier/pkg/output"
 
 	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
 )
 
 type createEC2InstanceInput struct {
-	amiID         string
-	vpcSubnetID   string
-	userdata      string
-	ebsKmsKeyID   string
-	instanceCount int
+	amiID            string
+	vpcSubnetID      string
+	userdata         string
+	ebsKmsKeyID      string
+	securityGroupIDs []string
+	instanceCount    int
 }
 
 var (
@@ -57,41 +63,33 @@ var (
 		"af-south-1":     "ami-060867d58b989c6be",
 		"me-south-1":     "ami-0483952b6a5997b06",
 	}
-	// TODO find a location for future docker images
-	networkValidatorImage string = "quay.io/app-sre/osd-network-verifier:v0.1.197-16fe250"
-	userdataEndVerifier   string = "USERDATA END"
+	userdataEndVerifier string = "USERDATA END"
 )
 
-func newClient(ctx context.Context, logger ocmlog.Logger, accessID, accessSecret, sessiontoken, region,
-	instanceType string, tags map[string]string, profile string) (*Client, error) {
-	var cfg aws.Config
-	var err error
-	if profile != "" {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithSharedConfigProfile(profile),
-			config.WithRegion(region),
-		)
-	} else {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion(region),
-			config.WithCredentialsProvider(credentials.StaticCredentialsProvider{
-				Value: aws.Credentials{
-					AccessKeyID: accessID, SecretAccessKey: accessSecret, SessionToken: sessiontoken,
-				},
-			}),
-		)
+func newClient(ctx context.Context, logger ocmlog.Logger, creds Credentials, region,
+	instanceType string, tags map[string]string) (*Client, error) {
+	cfg, err := creds.LoadConfig(ctx, region)
+	if err != nil {
+		return nil, err
 	}
+
+	sess, err := session.NewSession(&awsv1.Config{
+		Region:      awsv1.String(region),
+		Credentials: awscredsv1.NewCredentials(&v1CredentialsProvider{ctx: ctx, provider: cfg.Credentials}),
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	c := &Client{
-		ec2Client:    ec2.NewFromConfig(cfg),
-		region:       region,
-		instanceType: instanceType,
-		tags:         tags,
-		logger:       logger,
-		output:       output.Output{},
+		ec2Client:      ec2.NewFromConfig(cfg),
+		route53Client:  route53.New(sess),
+		resolverClient: route53resolver.New(sess),
+		region:         region,
+		instanceType:   instanceType,
+		tags:           tags,
+		logger:         logger,
+		output:         output.Output{},
 	}
 
 	// Validates the provided instance type will work with the verifier
@@ -103,7 +101,7 @@ func newClient(ctx context.Context, logger ocmlog.Logger, accessID, accessSecret
 	return c, nil
 }
 
-func buildTags(tags map[string]string) []ec2Types.TagSpecification {
+func buildTags(tags map[string]string, resourceType ec2Types.ResourceType) []ec2Types.TagSpecification {
 	tagList := []ec2Types.Tag{}
 	for k, v := range tags {
 		t := ec2Types.Tag{
@@ -114,7 +112,7 @@ func buildTags(tags map[string]string) []ec2Types.TagSpecification {
 	}
 
 	tagSpec := ec2Types.TagSpecification{
-		ResourceType: ec2Types.ResourceTypeInstance,
+		ResourceType: resourceType,
 		Tags:         tagList,
 	}
 
@@ -181,6 +179,7 @@ func (c *Client) createEC2Instance(ctx context.Context, input createEC2InstanceI
 				AssociatePublicIpAddress: aws.Bool(true),
 				DeviceIndex:              aws.Int32(0),
 				SubnetId:                 aws.String(input.vpcSubnetID),
+				Groups:                   input.securityGroupIDs,
 			},
 		},
 		// We specify block devices mainly to enable EBS encryption
@@ -191,7 +190,7 @@ func (c *Client) createEC2Instance(ctx context.Context, input createEC2InstanceI
 			},
 		},
 		UserData:          aws.String(input.userdata),
-		TagSpecifications: buildTags(c.tags),
+		TagSpecifications: buildTags(c.tags, ec2Types.ResourceTypeInstance),
 	}
 	// Finally, we make our request
 	instanceResp, err := c.ec2Client.RunInstances(ctx, &instanceReq)
@@ -278,10 +277,10 @@ func generateUserData(variables map[string]string) (string, error) {
 	return base64.StdEncoding.EncodeToString([]byte(data)), nil
 }
 
-func (c *Client) findUnreachableEndpoints(ctx context.Context, instanceID string) error {
+// getProbeOutput waits for the userdata script of the instance to complete and returns its console output
+func (c *Client) getProbeOutput(ctx context.Context, instanceID string) (string, error) {
 	// Compile the regular expressions once
 	reVerify := regexp.MustCompile(userdataEndVerifier)
-	reUnreachableErrors := regexp.MustCompile(`Unable to reach (\S+)`)
 
 	latest := true
 	input := ec2.GetConsoleOutputInput{
@@ -289,7 +288,7 @@ func (c *Client) findUnreachableEndpoints(ctx context.Context, instanceID string
 		Latest:     &latest,
 	}
 
-	// getConsoleOutput then parse, use c.output to store result of the execution
+	var probeOutput string
 	err := helpers.PollImmediate(30*time.Second, 4*time.Minute, func() (bool, error) {
 		output, err := c.ec2Client.GetConsoleOutput(ctx, &input)
 		if err != nil {
@@ -318,25 +317,31 @@ func (c *Client) findUnreachableEndpoints(ctx context.Context, instanceID string
 				return false, nil
 			}
 
-			// check output failures, report as exception if they occurred
-			var rgx = regexp.MustCompile(`(?m)^(.*Cannot.*)|(.*Could not.*)|(.*Failed.*)|(.*command not found.*)`)
-			notFoundMatch := rgx.FindAllStringSubmatch(string(scriptOutput), -1)
-			if len(notFoundMatch) > 0 {
-				c.output.AddException(handledErrors.NewGenericError(
-					"internet connectivity problem: please ensure there's internet access in given vpc subnets"))
-			}
-
 			// If debug logging is enabled, output the full console log that appears to include the full userdata run
 			c.logger.Debug(ctx, "Full EC2 console output:\n---\n%s\n---", scriptOutput)
 
-			c.output.SetEgressFailures(reUnreachableErrors.FindAllString(string(scriptOutput), -1))
+			probeOutput = string(scriptOutput)
 			return true, nil
 		}
 		c.logger.Debug(ctx, "Waiting for UserData script to complete...")
 		return false, nil
 	})
 
-	return err
+	return probeOutput, err
+}
+
+// addEgressResults reports the endpoints the probe could not reach
+func (c *Client) addEgressResults(probeOutput string) {
+	// check output failures, report as exception if they occurred
+	var rgx = regexp.MustCompile(`(?m)^(.*Cannot.*)|(.*Could not.*)|(.*Failed.*)|(.*command not found.*)`)
+	notFoundMatch := rgx.FindAllStringSubmatch(probeOutput, -1)
+	if len(notFoundMatch) > 0 {
+		c.output.AddException(handledErrors.NewGenericError(
+			"internet connectivity problem: please ensure there's internet access in given vpc subnets"))
+	}
+
+	reUnreachableErrors := regexp.MustCompile(`Unable to reach (\S+)`)
+	c.output.SetEgressFailures(reUnreachableErrors.FindAllString(probeOutput, -1))
 }
 
 // terminateEC2Instance terminates target ec2 instance
@@ -369,8 +374,32 @@ func (c *Client) setCloudImage(cloudImageID string) (string, error) {
 // - create instance and wait till it gets ready, wait for userdata script execution
 // - find unreachable endpoints & parse output, then terminate instance
 // - return `c.output` which stores the execution results
-func (c *Client) validateEgress(ctx context.Context, vpcSubnetID, cloudImageID string, kmsKeyID string, timeout time.Duration) *output.Output {
-	c.logger.Debug(ctx, "Using configured timeout of %s for each egress request", timeout.String())
+func (c *Client) validateEgress(ctx context.Context, opts options.EgressOptions) *output.Output {
+	switch opts.Mode {
+	case options.EgressModeStatic:
+		return c.validateEgressStatic(ctx, opts)
+	case options.EgressModeReachability:
+		return c.validateEgressReachability(ctx, opts)
+	}
+
+	probeOutput, err := c.runProbe(ctx, opts)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	c.addEgressResults(probeOutput)
+	c.addResolutionResults(opts.SubnetID, probeOutput)
+
+	return &c.output
+}
+
+// runProbe launches the probe instance in the subnet and returns the console output of the network validator
+// Basic workflow is:
+// - generate the userdata running the network validator image
+// - create the instance and wait for it to run
+// - gather the console output once the userdata script has completed
+// - terminate the instance
+func (c *Client) runProbe(ctx context.Context, opts options.EgressOptions) (string, error) {
+	c.logger.Debug(ctx, "Using configured timeout of %s for each egress request", opts.Timeout.String())
 	// Generate the userData file
 	userDataVariables := map[string]string{
 		"AWS_REGION":               c.region,
@@ -378,77 +407,185 @@ func (c *Client) validateEgress(ctx context.Context, vpcSubnetID, cloudImageID s
 		"USERDATA_END":             userdataEndVerifier,
 		"VALIDATOR_START_VERIFIER": "VALIDATOR START",
 		"VALIDATOR_END_VERIFIER":   "VALIDATOR END",
-		"VALIDATOR_IMAGE":          networkValidatorImage,
-		"TIMEOUT":                  timeout.String(),
+		"VALIDATOR_IMAGE":          helpers.NetworkValidatorImage,
+		"TIMEOUT":                  opts.Timeout.String(),
 	}
 	userData, err := generateUserData(userDataVariables)
 	if err != nil {
-		return c.output.AddError(err)
+		return "", err
 	}
 	c.logger.Debug(ctx, "Base64-encoded generated userdata script:\n---\n%s\n---", userData)
 
-	cloudImageID, err = c.setCloudImage(cloudImageID)
+	cloudImageID, err := c.setCloudImage(opts.CloudImageID)
 	if err != nil {
-		return c.output.AddError(err) // fatal
+		return "", err
 	}
 
 	instance, err := c.createEC2Instance(ctx, createEC2InstanceInput{
-		amiID:         cloudImageID,
-		vpcSubnetID:   vpcSubnetID,
-		userdata:      userData,
-		ebsKmsKeyID:   kmsKeyID,
-		instanceCount: instanceCount,
+		amiID:            cloudImageID,
+		vpcSubnetID:      opts.SubnetID,
+		userdata:         userData,
+		ebsKmsKeyID:      opts.KmsKeyID,
+		securityGroupIDs: opts.SecurityGroupIDs,
+		instanceCount:    instanceCount,
 	})
 	if err != nil {
-		return c.output.AddError(err) // fatal
+		return "", err
 	}
 
 	instanceID := *instance.Instances[0].InstanceId
+	// try to terminate the created instance whatever happens
+	defer c.terminateEC2Instance(ctx, instanceID)
 	c.logger.Debug(ctx, "Waiting for EC2 instance %s to be running", instanceID)
-	if instanceReadyErr := c.waitForEC2InstanceCompletion(ctx, instanceID); instanceReadyErr != nil {
-		c.terminateEC2Instance(ctx, instanceID)    // try to terminate the created instance
-		return c.output.AddError(instanceReadyErr) // fatal
+	if err := c.waitForEC2InstanceCompletion(ctx, instanceID); err != nil {
+		return "", err
 	}
 
 	c.logger.Info(ctx, "Gathering and parsing console log output...")
-	err = c.findUnreachableEndpoints(ctx, instanceID)
+	return c.getProbeOutput(ctx, instanceID)
+}
+
+// validateEgressStatic evaluates the egress path of the subnet without launching an instance
+// Basic workflow is:
+// - ask AWS API for the VPC of the subnet
+// - verify the default routes of the subnet
+// - evaluate the network ACL of the subnet against the endpoints
+// - evaluate the security groups (or the VPC's default security group) against the endpoints
+// - verify the VPC endpoints serving the endpoints
+func (c *Client) validateEgressStatic(ctx context.Context, opts options.EgressOptions) *output.Output {
+	subnetsOut, err := c.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{opts.SubnetID}})
 	if err != nil {
-		c.output.AddError(err)
+		return c.output.AddError(err) // fatal
 	}
-	c.terminateEC2Instance(ctx, instanceID)
+	if len(subnetsOut.Subnets) == 0 {
+		return c.output.AddError(fmt.Errorf("subnet %s not found", opts.SubnetID)) // fatal
+	}
+	vpcID := aws.ToString(subnetsOut.Subnets[0].VpcId)
 
-	return &c.output
+	c.verifyRoutes(ctx, []string{opts.SubnetID})
+	c.verifyNetworkACLs(ctx, options.NetworkACLOptions{SubnetIDs: []string{opts.SubnetID}, Endpoints: opts.Endpoints})
+	c.verifySecurityGroups(ctx, options.SecurityGroupOptions{
+		SecurityGroupIDs: opts.SecurityGroupIDs,
+		VpcID:            vpcID,
+		Endpoints:        opts.Endpoints,
+	})
+	return c.verifyVpcEndpoints(ctx, options.VpcEndpointOptions{
+		VpcID:     vpcID,
+		SubnetIDs: []string{opts.SubnetID},
+		Endpoints: opts.Endpoints,
+	})
 }
 
-// verifyDns performs verification process for VPC's DNS
+// verifyRoutes performs route table path analysis for the given subnets
 // Basic workflow is:
-// - ask AWS API for VPC attributes
-// - ensure they're set correctly
-func (c *Client) verifyDns(ctx context.Context, vpcID string) *output.Output {
-	c.logger.Info(ctx, "Verifying DNS config for VPC %s", vpcID)
-	// Request boolean values from AWS API
-	dnsSprtResult, dnsSprtErr := c.ec2Client.DescribeVpcAttribute(ctx, &ec2.DescribeVpcAttributeInput{
-		Attribute: "enableDnsSupport",
-		VpcId:     aws.String(vpcID),
-	})
-	dnsHostResult, dnsHostErr := c.ec2Client.DescribeVpcAttribute(ctx, &ec2.DescribeVpcAttributeInput{
-		Attribute: "enableDnsHostnames",
-		VpcId:     aws.String(vpcID),
+// - ask AWS API for the subnets and the route tables of their VPCs
+// - pick each subnet's route table, falling back to the VPC's main route table
+// - resolve the targets of the 0.0.0.0/0 and ::/0 routes and report them per subnet
+func (c *Client) verifyRoutes(ctx context.Context, subnetIDs []string) *output.Output {
+	c.logger.Info(ctx, "Verifying default routes for subnets %v", subnetIDs)
+	subnetsOut, err := c.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: subnetIDs,
 	})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+
+	routeTablesByVpc := map[string]map[string]ec2Types.RouteTable{}
+	for _, subnet := range subnetsOut.Subnets {
+		subnetID, vpcID := aws.ToString(subnet.SubnetId), aws.ToString(subnet.VpcId)
+		if _, ok := routeTablesByVpc[vpcID]; !ok {
+			tables, err := c.subnetRouteTables(ctx, vpcID)
+			if err != nil {
+				c.output.AddError(err)
+				continue
+			}
+			routeTablesByVpc[vpcID] = tables
+		}
+		tables, ok := routeTablesByVpc[vpcID]
+		if !ok {
+			continue
+		}
+
+		rt, ok := routeTableForSubnet(tables, subnetID)
+		if !ok {
+			c.output.AddFinding(output.Finding{Check: "subnet-egress-route", Resource: subnetID, Status: output.StatusFailed,
+				Message: fmt.Sprintf("no route table is associated with the subnet and VPC %s has no main route table", vpcID)})
+			continue
+		}
+		for _, f := range analyzeDefaultRoutes(subnetID, rt) {
+			c.logger.Debug(ctx, "%s", f)
+			c.output.AddFinding(f)
+		}
+	}
+
+	return &c.output
+}
 
-	if dnsSprtErr != nil {
-		c.output.AddError(dnsSprtErr)
+// analyzeDefaultRoutes returns a finding for the IPv4 default route of the route table,
+// and one for the IPv6 default route if there is any
+func analyzeDefaultRoutes(subnetID string, rt ec2Types.RouteTable) []output.Finding {
+	var ipv4, ipv6 *ec2Types.Route
+	for i, route := range rt.Routes {
+		switch {
+		case aws.ToString(route.DestinationCidrBlock) == "0.0.0.0/0":
+			ipv4 = &rt.Routes[i]
+		case aws.ToString(route.DestinationIpv6CidrBlock) == "::/0":
+			ipv6 = &rt.Routes[i]
+		}
 	}
-	if dnsHostErr != nil {
-		c.output.AddError(dnsHostErr)
+
+	rtID := aws.ToString(rt.RouteTableId)
+	findings := []output.Finding{}
+	if ipv4 == nil {
+		findings = append(findings, output.Finding{Check: "subnet-egress-route", Resource: subnetID, Status: output.StatusFailed,
+			Message: fmt.Sprintf("route table %s has no 0.0.0.0/0 route, egress traffic can't leave the VPC", rtID)})
+	} else {
+		findings = append(findings, defaultRouteFinding(subnetID, rtID, "0.0.0.0/0", *ipv4))
 	}
-	// Verify results
-	c.logger.Info(ctx, "DNS Support for VPC %s: %t", vpcID, *dnsSprtResult.EnableDnsSupport.Value)
-	c.logger.Info(ctx, "DNS Hostnames for VPC %s: %t", vpcID, *dnsHostResult.EnableDnsHostnames.Value)
-	if !(*dnsSprtResult.EnableDnsSupport.Value && *dnsHostResult.EnableDnsHostnames.Value) {
-		c.logger.Error(ctx, "Both DNS support and DNS hostnames must be enabled on VPC %s in order to be compatible with OSD.", vpcID)
-		c.output.AddException(handledErrors.NewGenericError("VPC DNS verification failed"))
+	if ipv6 != nil {
+		findings = append(findings, defaultRouteFinding(subnetID, rtID, "::/0", *ipv6))
 	}
 
-	return &c.output
+	return findings
+}
+
+// defaultRouteFinding classifies the target of a default route
+func defaultRouteFinding(subnetID, rtID, destination string, route ec2Types.Route) output.Finding {
+	f := output.Finding{Check: "subnet-egress-route", Resource: subnetID, Status: output.StatusPassed}
+	gatewayID := aws.ToString(route.GatewayId)
+
+	var target string
+	switch {
+	case strings.HasPrefix(gatewayID, "igw-"):
+		target = fmt.Sprintf("internet gateway %s", gatewayID)
+	case route.NatGatewayId != nil:
+		target = fmt.Sprintf("NAT gateway %s", *route.NatGatewayId)
+	case route.TransitGatewayId != nil:
+		target = fmt.Sprintf("transit gateway %s", *route.TransitGatewayId)
+	case strings.HasPrefix(gatewayID, "vpce-"):
+		target = fmt.Sprintf("firewall endpoint %s", gatewayID)
+	case route.EgressOnlyInternetGatewayId != nil:
+		target = fmt.Sprintf("egress-only internet gateway %s", *route.EgressOnlyInternetGatewayId)
+	case route.InstanceId != nil || route.NetworkInterfaceId != nil:
+		target = fmt.Sprintf("instance %s (network interface %s)", aws.ToString(route.InstanceId), aws.ToString(route.NetworkInterfaceId))
+		f.Status = output.StatusWarning
+	case route.VpcPeeringConnectionId != nil:
+		// peering connections are not transitive, the peer VPC's gateways can't be used for egress
+		target = fmt.Sprintf("VPC peering connection %s", *route.VpcPeeringConnectionId)
+		f.Status = output.StatusFailed
+	case gatewayID != "":
+		target = fmt.Sprintf("gateway %s", gatewayID)
+		f.Status = output.StatusWarning
+	default:
+		target = "an unknown target"
+		f.Status = output.StatusWarning
+	}
+
+	f.Message = fmt.Sprintf("%s egress goes through %s (route table %s)", destination, target, rtID)
+	if route.State == ec2Types.RouteStateBlackhole {
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("%s route points to %s which no longer exists, egress traffic is blackholed (route table %s)", destination, target, rtID)
+	}
+
+	return f
 }
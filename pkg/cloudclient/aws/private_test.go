@@ -10,8 +10,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/validatortest"
 	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
 	"github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/golang/mock/gomock"
@@ -89,11 +93,53 @@ func TestValidateEgress(t *testing.T) {
 		logger:    &logging.GlogLogger{},
 	}
 
-	if !cli.validateEgress(context.TODO(), vpcSubnetID, cloudImageID, "", time.Duration(1*time.Second)).IsSuccessful() {
+	opts := options.EgressOptions{SubnetID: vpcSubnetID, CloudImageID: cloudImageID, Timeout: time.Duration(1 * time.Second)}
+	if !cli.validateEgress(context.TODO(), opts).IsSuccessful() {
 		t.Errorf("validateEgress(): should pass")
 	}
 }
 
+func TestValidateEgressValidatorOutput(t *testing.T) {
+	testID := "aws-docs-example-instanceID"
+	result := validatortest.Run(t, "aws")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+	FakeEC2Cli.EXPECT().RunInstances(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+		func(_ context.Context, input *ec2.RunInstancesInput, _ ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+			userData, err := base64.StdEncoding.DecodeString(aws.ToString(input.UserData))
+			assert.NoError(t, err)
+			assert.Contains(t, string(userData), "sudo docker run")
+			assert.Contains(t, string(userData), helpers.NetworkValidatorImage)
+			return &ec2.RunInstancesOutput{Instances: []types.Instance{{InstanceId: aws.String(testID)}}}, nil
+		})
+	FakeEC2Cli.EXPECT().DescribeInstanceStatus(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeInstanceStatusOutput{
+		InstanceStatuses: []types.InstanceStatus{{InstanceId: aws.String(testID), InstanceState: &types.InstanceState{Code: aws.Int32(16)}}},
+	}, nil)
+	FakeEC2Cli.EXPECT().GetConsoleOutput(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.GetConsoleOutputOutput{
+		Output: aws.String(base64.StdEncoding.EncodeToString([]byte(result.Output))),
+	}, nil)
+	FakeEC2Cli.EXPECT().TerminateInstances(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+
+	cli := Client{
+		ec2Client: FakeEC2Cli,
+		logger:    &logging.GlogLogger{},
+	}
+	opts := options.EgressOptions{SubnetID: "subnet-1", CloudImageID: "dummy-id", Timeout: time.Second}
+	out := cli.validateEgress(context.TODO(), opts)
+
+	failures, exceptions, errs := out.Parse()
+	assert.Len(t, failures, 1)
+	assert.Contains(t, failures[0].Error(), result.Unreachable)
+	assert.Empty(t, exceptions)
+	assert.Empty(t, errs)
+	assert.Equal(t, []output.Finding{
+		{Check: "dns-resolution", Resource: result.Resolver, Status: output.StatusPassed,
+			Message: "1 hosts resolved to public addresses through the VPC resolver"},
+	}, out.Findings())
+}
+
 func TestValidateOutputErrors(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -153,8 +199,8 @@ Unable to reach somesample.endpoint
 			ec2Client: FakeEC2Cli,
 			logger:    &logging.GlogLogger{},
 		}
-		if cli.validateEgress(context.TODO(), vpcSubnetID, cloudImageID, "",
-			time.Duration(1*time.Second)).IsSuccessful() {
+		opts := options.EgressOptions{SubnetID: vpcSubnetID, CloudImageID: cloudImageID, Timeout: time.Duration(1 * time.Second)}
+		if cli.validateEgress(context.TODO(), opts).IsSuccessful() {
 			t.Errorf("failed %s: validateEgress(): should fail", test.name)
 		}
 
@@ -175,3 +221,81 @@ Unable to reach somesample.endpoint
 
 	}
 }
+
+func TestVerifyRoutes(t *testing.T) {
+	vpcID := "vpc-1"
+	tests := []struct {
+		name           string
+		routes         []types.Route
+		expectStatuses []output.Status
+		expectMessage  string
+	}{
+		{
+			name:           "internet gateway",
+			routes:         []types.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1")}},
+			expectStatuses: []output.Status{output.StatusPassed},
+			expectMessage:  "internet gateway igw-1",
+		},
+		{
+			name: "nat gateway and egress-only internet gateway",
+			routes: []types.Route{
+				{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1")},
+				{DestinationIpv6CidrBlock: aws.String("::/0"), EgressOnlyInternetGatewayId: aws.String("eigw-1")},
+			},
+			expectStatuses: []output.Status{output.StatusPassed, output.StatusPassed},
+			expectMessage:  "NAT gateway nat-1",
+		},
+		{
+			name:           "firewall endpoint",
+			routes:         []types.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("vpce-1")}},
+			expectStatuses: []output.Status{output.StatusPassed},
+			expectMessage:  "firewall endpoint vpce-1",
+		},
+		{
+			name: "blackholed transit gateway",
+			routes: []types.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), TransitGatewayId: aws.String("tgw-1"),
+				State: types.RouteStateBlackhole}},
+			expectStatuses: []output.Status{output.StatusFailed},
+			expectMessage:  "blackholed",
+		},
+		{
+			name:           "no default route",
+			routes:         []types.Route{{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")}},
+			expectStatuses: []output.Status{output.StatusFailed},
+			expectMessage:  "no 0.0.0.0/0 route",
+		},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+		FakeEC2Cli.EXPECT().DescribeSubnets(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeSubnetsOutput{
+			Subnets: []types.Subnet{{SubnetId: aws.String("subnet-1"), VpcId: aws.String(vpcID)}},
+		}, nil)
+		// the subnet has no explicit association, so the main route table must be used
+		FakeEC2Cli.EXPECT().DescribeRouteTables(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeRouteTablesOutput{
+			RouteTables: []types.RouteTable{{
+				RouteTableId: aws.String("rtb-main"),
+				Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+				Routes:       test.routes,
+			}},
+		}, nil)
+
+		cli := Client{
+			ec2Client: FakeEC2Cli,
+			logger:    &logging.GlogLogger{},
+		}
+		out := cli.VerifyRoutes(context.TODO(), options.RoutesOptions{SubnetIDs: []string{"subnet-1"}})
+
+		findings := out.Findings()
+		var statuses []output.Status
+		for _, f := range findings {
+			statuses = append(statuses, f.Status)
+		}
+		assert.Equal(t, test.expectStatuses, statuses, test.name)
+		if assert.NotEmpty(t, findings, test.name) {
+			assert.Contains(t, findings[0].Message, test.expectMessage, test.name)
+			assert.Contains(t, findings[0].Message, "rtb-main", test.name)
+		}
+		ctrl.Finish()
+	}
+}
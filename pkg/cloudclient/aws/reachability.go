@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+var (
+	// Analyses usually complete within a minute, they can be polled faster in tests
+	reachabilityPollInterval = 10 * time.Second
+	reachabilityPollTimeout  = 5 * time.Minute
+)
+
+// explanationReasons translates the explanation codes of the Reachability Analyzer
+var explanationReasons = map[string]string{
+	"ENI_SG_RULES_MISMATCH":   "no egress rule of the security groups allows the traffic",
+	"SUBNET_ACL_RESTRICTION":  "the network ACL denies the traffic",
+	"NO_ROUTE_TO_DESTINATION": "the route table has no route to the internet",
+	"BAD_STATE_ROUTE":         "the route to the internet is blackholed",
+}
+
+// validateEgressReachability analyzes the paths from the subnet to the internet gateway of its VPC
+// Basic workflow is:
+// - ask AWS API for the VPC of the subnet and the internet gateway attached to it
+// - create a temporary network interface in the subnet, with the probe's security groups
+// - create a network insights path to the internet gateway for every port of the egress endpoints
+// - run the analyses and wait for them to complete
+// - translate the explanations of every blocked path into findings
+// - delete the analyses, paths and network interface
+func (c *Client) validateEgressReachability(ctx context.Context, opts options.EgressOptions) *output.Output {
+	eps := opts.Endpoints
+	if len(eps) == 0 {
+		var err error
+		eps, err = endpoints.Default(PlatformName, map[string]string{"AWS_REGION": c.region})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+	}
+	hostsByPort := map[int][]string{}
+	for _, e := range eps {
+		for _, port := range e.Ports {
+			hostsByPort[port] = append(hostsByPort[port], e.Host)
+		}
+	}
+	var ports []int
+	for port := range hostsByPort {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	subnetsOut, err := c.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{opts.SubnetID}})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	if len(subnetsOut.Subnets) == 0 {
+		return c.output.AddError(fmt.Errorf("subnet %s not found", opts.SubnetID)) // fatal
+	}
+	vpcID := aws.ToString(subnetsOut.Subnets[0].VpcId)
+
+	igwOut, err := c.ec2Client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("attachment.vpc-id"), Values: []string{vpcID}}},
+	})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	if len(igwOut.InternetGateways) == 0 {
+		c.output.AddFinding(output.Finding{Check: "reachability-egress", Resource: vpcID, Status: output.StatusUnknown,
+			Message: "no internet gateway is attached to the VPC, egress through a transit gateway or proxy cannot be analyzed"})
+		return &c.output
+	}
+	igwID := aws.ToString(igwOut.InternetGateways[0].InternetGatewayId)
+
+	eniOut, err := c.ec2Client.CreateNetworkInterface(ctx, &ec2.CreateNetworkInterfaceInput{
+		SubnetId:          aws.String(opts.SubnetID),
+		Groups:            opts.SecurityGroupIDs,
+		Description:       aws.String("osd-network-verifier reachability analysis"),
+		TagSpecifications: buildTags(c.tags, ec2Types.ResourceTypeNetworkInterface),
+	})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	eniID := aws.ToString(eniOut.NetworkInterface.NetworkInterfaceId)
+
+	var pathIDs, analysisIDs []string
+	defer func() { c.cleanupReachability(ctx, eniID, pathIDs, analysisIDs) }()
+
+	portByAnalysis := map[string]int{}
+	for _, port := range ports {
+		pathOut, err := c.ec2Client.CreateNetworkInsightsPath(ctx, &ec2.CreateNetworkInsightsPathInput{
+			Source:            aws.String(eniID),
+			Destination:       aws.String(igwID),
+			Protocol:          ec2Types.ProtocolTcp,
+			DestinationPort:   aws.Int32(int32(port)),
+			TagSpecifications: buildTags(c.tags, ec2Types.ResourceTypeNetworkInsightsPath),
+		})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+		pathID := aws.ToString(pathOut.NetworkInsightsPath.NetworkInsightsPathId)
+		pathIDs = append(pathIDs, pathID)
+
+		analysisOut, err := c.ec2Client.StartNetworkInsightsAnalysis(ctx, &ec2.StartNetworkInsightsAnalysisInput{
+			NetworkInsightsPathId: aws.String(pathID),
+			TagSpecifications:     buildTags(c.tags, ec2Types.ResourceTypeNetworkInsightsAnalysis),
+		})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+		analysisID := aws.ToString(analysisOut.NetworkInsightsAnalysis.NetworkInsightsAnalysisId)
+		analysisIDs = append(analysisIDs, analysisID)
+		portByAnalysis[analysisID] = port
+	}
+
+	c.logger.Info(ctx, "Waiting for %d reachability analyses from %s to %s", len(analysisIDs), eniID, igwID)
+	var analyses []ec2Types.NetworkInsightsAnalysis
+	err = helpers.PollImmediate(reachabilityPollInterval, reachabilityPollTimeout, func() (bool, error) {
+		out, err := c.ec2Client.DescribeNetworkInsightsAnalyses(ctx, &ec2.DescribeNetworkInsightsAnalysesInput{
+			NetworkInsightsAnalysisIds: analysisIDs,
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, a := range out.NetworkInsightsAnalyses {
+			if a.Status == ec2Types.AnalysisStatusRunning {
+				return false, nil
+			}
+		}
+		analyses = out.NetworkInsightsAnalyses
+		return true, nil
+	})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+
+	for _, a := range analyses {
+		port := portByAnalysis[aws.ToString(a.NetworkInsightsAnalysisId)]
+		hosts := strings.Join(hostsByPort[port], ", ")
+		switch {
+		case a.Status == ec2Types.AnalysisStatusFailed:
+			c.output.AddError(fmt.Errorf("reachability analysis for port %d failed: %s", port, aws.ToString(a.StatusMessage)))
+		case aws.ToBool(a.NetworkPathFound):
+			c.output.AddFinding(output.Finding{Check: "reachability-egress", Resource: opts.SubnetID, Status: output.StatusPassed,
+				Message: fmt.Sprintf("a path to %s exists on port %d", igwID, port)})
+		case len(a.Explanations) == 0:
+			c.output.AddFinding(output.Finding{Check: "reachability-egress", Resource: opts.SubnetID, Status: output.StatusFailed,
+				Message: fmt.Sprintf("no path to %s exists on port %d, used by %s", igwID, port, hosts)})
+		default:
+			for _, e := range a.Explanations {
+				resource, reason := explainBlockedPath(e)
+				if resource == "" {
+					resource = opts.SubnetID
+				}
+				c.output.AddFinding(output.Finding{Check: "reachability-egress", Resource: resource, Status: output.StatusFailed,
+					Message: fmt.Sprintf("traffic on port %d, used by %s, is blocked: %s", port, hosts, reason)})
+			}
+		}
+	}
+
+	return &c.output
+}
+
+// explainBlockedPath returns the ID of the component blocking the path and the reason it does
+func explainBlockedPath(e ec2Types.Explanation) (string, string) {
+	code := aws.ToString(e.ExplanationCode)
+	reason, ok := explanationReasons[code]
+	if !ok {
+		reason = fmt.Sprintf("the Reachability Analyzer reported %s", code)
+	}
+	if e.AclRule != nil {
+		reason += fmt.Sprintf(" (rule %d)", aws.ToInt32(e.AclRule.RuleNumber))
+	}
+	if e.MissingComponent != nil {
+		reason += fmt.Sprintf(" (%s)", aws.ToString(e.MissingComponent))
+	}
+
+	// the most specific component is reported, e.g. the ACL rather than its subnet
+	for _, component := range []*ec2Types.AnalysisComponent{
+		e.Acl, e.SecurityGroup, e.RouteTable, e.SubnetRouteTable, e.NatGateway, e.InternetGateway,
+		e.VpcEndpoint, e.NetworkInterface, e.Subnet, e.Vpc, e.Component,
+	} {
+		if component != nil && component.Id != nil {
+			return aws.ToString(component.Id), reason
+		}
+	}
+
+	return "", reason
+}
+
+// cleanupReachability deletes the temporary resources of the analysis, in dependency order.
+// Failures are only logged, leftover resources are tagged and cost nothing.
+func (c *Client) cleanupReachability(ctx context.Context, eniID string, pathIDs, analysisIDs []string) {
+	for _, id := range analysisIDs {
+		if _, err := c.ec2Client.DeleteNetworkInsightsAnalysis(ctx, &ec2.DeleteNetworkInsightsAnalysisInput{NetworkInsightsAnalysisId: aws.String(id)}); err != nil {
+			c.logger.Error(ctx, "Unable to delete network insights analysis %s: %s", id, err)
+		}
+	}
+	for _, id := range pathIDs {
+		if _, err := c.ec2Client.DeleteNetworkInsightsPath(ctx, &ec2.DeleteNetworkInsightsPathInput{NetworkInsightsPathId: aws.String(id)}); err != nil {
+			c.logger.Error(ctx, "Unable to delete network insights path %s: %s", id, err)
+		}
+	}
+	if _, err := c.ec2Client.DeleteNetworkInterface(ctx, &ec2.DeleteNetworkInterfaceInput{NetworkInterfaceId: aws.String(eniID)}); err != nil {
+		c.logger.Error(ctx, "Unable to delete network interface %s: %s", eniID, err)
+	}
+}
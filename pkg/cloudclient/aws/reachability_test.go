@@ -0,0 +1,108 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/golang/mock/gomock"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEgressReachability(t *testing.T) {
+	defer func(orig time.Duration) { reachabilityPollInterval = orig }(reachabilityPollInterval)
+	reachabilityPollInterval = time.Millisecond
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+	FakeEC2Cli.EXPECT().DescribeSubnets(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeSubnetsOutput{
+		Subnets: []types.Subnet{{SubnetId: aws.String("subnet-1"), VpcId: aws.String("vpc-1")}},
+	}, nil)
+	FakeEC2Cli.EXPECT().DescribeInternetGateways(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeInternetGatewaysOutput{
+		InternetGateways: []types.InternetGateway{{InternetGatewayId: aws.String("igw-1")}},
+	}, nil)
+	FakeEC2Cli.EXPECT().CreateNetworkInterface(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.CreateNetworkInterfaceOutput{
+		NetworkInterface: &types.NetworkInterface{NetworkInterfaceId: aws.String("eni-1")},
+	}, nil)
+	for _, port := range []int32{443, 9997} {
+		port := port
+		FakeEC2Cli.EXPECT().CreateNetworkInsightsPath(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+			func(_ context.Context, input *ec2.CreateNetworkInsightsPathInput, _ ...func(*ec2.Options)) (*ec2.CreateNetworkInsightsPathOutput, error) {
+				assert.Equal(t, port, aws.ToInt32(input.DestinationPort))
+				return &ec2.CreateNetworkInsightsPathOutput{
+					NetworkInsightsPath: &types.NetworkInsightsPath{NetworkInsightsPathId: aws.String(fmt.Sprintf("nip-%d", port))},
+				}, nil
+			})
+	}
+	FakeEC2Cli.EXPECT().StartNetworkInsightsAnalysis(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.StartNetworkInsightsAnalysisOutput{
+		NetworkInsightsAnalysis: &types.NetworkInsightsAnalysis{NetworkInsightsAnalysisId: aws.String("nia-443")},
+	}, nil)
+	FakeEC2Cli.EXPECT().StartNetworkInsightsAnalysis(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.StartNetworkInsightsAnalysisOutput{
+		NetworkInsightsAnalysis: &types.NetworkInsightsAnalysis{NetworkInsightsAnalysisId: aws.String("nia-9997")},
+	}, nil)
+	gomock.InOrder(
+		FakeEC2Cli.EXPECT().DescribeNetworkInsightsAnalyses(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeNetworkInsightsAnalysesOutput{
+			NetworkInsightsAnalyses: []types.NetworkInsightsAnalysis{
+				{NetworkInsightsAnalysisId: aws.String("nia-443"), Status: types.AnalysisStatusRunning},
+				{NetworkInsightsAnalysisId: aws.String("nia-9997"), Status: types.AnalysisStatusRunning},
+			},
+		}, nil),
+		FakeEC2Cli.EXPECT().DescribeNetworkInsightsAnalyses(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeNetworkInsightsAnalysesOutput{
+			NetworkInsightsAnalyses: []types.NetworkInsightsAnalysis{
+				{NetworkInsightsAnalysisId: aws.String("nia-443"), Status: types.AnalysisStatusSucceeded, NetworkPathFound: aws.Bool(true)},
+				{
+					NetworkInsightsAnalysisId: aws.String("nia-9997"),
+					Status:                    types.AnalysisStatusSucceeded,
+					NetworkPathFound:          aws.Bool(false),
+					Explanations: []types.Explanation{{
+						ExplanationCode: aws.String("SUBNET_ACL_RESTRICTION"),
+						Acl:             &types.AnalysisComponent{Id: aws.String("acl-1")},
+						AclRule:         &types.AnalysisAclRule{RuleNumber: aws.Int32(90)},
+						Subnet:          &types.AnalysisComponent{Id: aws.String("subnet-1")},
+					}},
+				},
+			},
+		}, nil),
+	)
+	// the temporary resources must be cleaned up
+	FakeEC2Cli.EXPECT().DeleteNetworkInsightsAnalysis(gomock.Any(), gomock.Any()).Times(2).Return(&ec2.DeleteNetworkInsightsAnalysisOutput{}, nil)
+	FakeEC2Cli.EXPECT().DeleteNetworkInsightsPath(gomock.Any(), gomock.Any()).Times(2).Return(&ec2.DeleteNetworkInsightsPathOutput{}, nil)
+	FakeEC2Cli.EXPECT().DeleteNetworkInterface(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DeleteNetworkInterfaceOutput{}, nil)
+
+	cli := Client{
+		ec2Client: FakeEC2Cli,
+		logger:    &logging.GlogLogger{},
+	}
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{
+		Mode:     options.EgressModeReachability,
+		SubnetID: "subnet-1",
+		Endpoints: []endpoints.Endpoint{
+			{Host: "quay.io", Ports: []int{443}},
+			{Host: "inputs1.osdsecuritylogs.splunkcloud.com", Ports: []int{9997}},
+		},
+	})
+
+	var failed []output.Finding
+	for _, f := range out.Findings() {
+		if f.Status == output.StatusFailed {
+			failed = append(failed, f)
+		}
+	}
+	assert.Equal(t, []output.Finding{{
+		Check:    "reachability-egress",
+		Resource: "acl-1",
+		Status:   output.StatusFailed,
+		Message:  "traffic on port 9997, used by inputs1.osdsecuritylogs.splunkcloud.com, is blocked: the network ACL denies the traffic (rule 90)",
+	}}, failed)
+	assert.False(t, out.IsSuccessful())
+}
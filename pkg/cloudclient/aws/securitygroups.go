@@ -0,0 +1,186 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// verifySecurityGroups evaluates the egress rules of the security groups against the egress endpoints
+// Basic workflow is:
+// - ask AWS API for the security groups, or the VPC's default security group if none is given
+// - resolve the CIDRs of the prefix lists referenced by egress rules
+// - resolve the IPv4 addresses of every endpoint host
+// - report every host and port no egress rule of any of the groups allows
+func (c *Client) verifySecurityGroups(ctx context.Context, opts options.SecurityGroupOptions) *output.Output {
+	eps := opts.Endpoints
+	if len(eps) == 0 {
+		var err error
+		eps, err = endpoints.Default(PlatformName, map[string]string{"AWS_REGION": c.region})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+	}
+
+	input := &ec2.DescribeSecurityGroupsInput{GroupIds: opts.SecurityGroupIDs}
+	if len(opts.SecurityGroupIDs) == 0 {
+		c.logger.Debug(ctx, "No security group given, using the default security group of VPC %s", opts.VpcID)
+		input = &ec2.DescribeSecurityGroupsInput{
+			Filters: []ec2Types.Filter{
+				{Name: aws.String("vpc-id"), Values: []string{opts.VpcID}},
+				{Name: aws.String("group-name"), Values: []string{"default"}},
+			},
+		}
+	}
+	sgOut, err := c.ec2Client.DescribeSecurityGroups(ctx, input)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	if len(sgOut.SecurityGroups) == 0 {
+		if len(opts.SecurityGroupIDs) == 0 {
+			return c.output.AddError(fmt.Errorf("no default security group found in VPC %s", opts.VpcID)) // fatal
+		}
+		return c.output.AddError(fmt.Errorf("no security group found for %s", strings.Join(opts.SecurityGroupIDs, ","))) // fatal
+	}
+
+	var groupIDs []string
+	var rules []ec2Types.IpPermission
+	for _, sg := range sgOut.SecurityGroups {
+		groupIDs = append(groupIDs, aws.ToString(sg.GroupId))
+		rules = append(rules, sg.IpPermissionsEgress...)
+	}
+	resource := strings.Join(groupIDs, ",")
+	c.logger.Info(ctx, "Evaluating egress rules of security groups %s against %d endpoints", resource, len(eps))
+
+	prefixLists, err := c.prefixListCIDRs(ctx, rules)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+
+	addresses := endpoints.ResolveIPv4(ctx, c.logger, &c.output, "security-group-egress", eps, lookupIP)
+	blocked := 0
+	for _, e := range eps {
+		for _, port := range e.Ports {
+			for _, ip := range addresses[e.Host] {
+				if !securityGroupRulesAllow(rules, prefixLists, ip, port) {
+					message := fmt.Sprintf("no egress rule allows traffic to %s (%s) on port %d", e.Host, ip, port)
+					if refs := referencedGroupsForPort(rules, port); len(refs) > 0 {
+						message += fmt.Sprintf(", the rules referencing %s only allow traffic to members of those groups", strings.Join(refs, ","))
+					}
+					c.output.AddFinding(output.Finding{Check: "security-group-egress", Resource: resource, Status: output.StatusFailed, Message: message})
+					blocked++
+					break
+				}
+			}
+		}
+	}
+	if blocked == 0 {
+		c.output.AddFinding(output.Finding{Check: "security-group-egress", Resource: resource, Status: output.StatusPassed,
+			Message: fmt.Sprintf("egress rules allow traffic to all %d endpoints", len(eps))})
+	}
+
+	return &c.output
+}
+
+// prefixListCIDRs returns the CIDRs of every prefix list referenced by the rules
+func (c *Client) prefixListCIDRs(ctx context.Context, rules []ec2Types.IpPermission) (map[string][]*net.IPNet, error) {
+	prefixLists := map[string][]*net.IPNet{}
+	for _, rule := range rules {
+		for _, pl := range rule.PrefixListIds {
+			id := aws.ToString(pl.PrefixListId)
+			if _, done := prefixLists[id]; done {
+				continue
+			}
+			var cidrs []*net.IPNet
+			input := &ec2.GetManagedPrefixListEntriesInput{PrefixListId: aws.String(id)}
+			for {
+				out, err := c.ec2Client.GetManagedPrefixListEntries(ctx, input)
+				if err != nil {
+					return nil, fmt.Errorf("unable to get entries of prefix list %s: %w", id, err)
+				}
+				for _, entry := range out.Entries {
+					if _, cidr, err := net.ParseCIDR(aws.ToString(entry.Cidr)); err == nil {
+						cidrs = append(cidrs, cidr)
+					}
+				}
+				if out.NextToken == nil {
+					break
+				}
+				input.NextToken = out.NextToken
+			}
+			prefixLists[id] = cidrs
+		}
+	}
+
+	return prefixLists, nil
+}
+
+// ruleMatchesPort returns true if the rule applies to TCP traffic on the port
+func ruleMatchesPort(rule ec2Types.IpPermission, port int) bool {
+	protocol := aws.ToString(rule.IpProtocol)
+	if protocol == "-1" {
+		return true
+	}
+	if protocol != "tcp" && protocol != "6" {
+		return false
+	}
+
+	return port >= int(aws.ToInt32(rule.FromPort)) && port <= int(aws.ToInt32(rule.ToPort))
+}
+
+// referencedGroupsForPort returns the security groups referenced by the rules matching the port
+func referencedGroupsForPort(rules []ec2Types.IpPermission, port int) []string {
+	var refs []string
+	for _, rule := range rules {
+		if !ruleMatchesPort(rule, port) {
+			continue
+		}
+		for _, pair := range rule.UserIdGroupPairs {
+			refs = append(refs, aws.ToString(pair.GroupId))
+		}
+	}
+
+	return refs
+}
+
+// securityGroupRulesAllow returns true if any of the egress rules allows TCP traffic to the address and port.
+// Rules referencing other security groups only allow traffic to the members of those groups,
+// so they never allow traffic to the endpoints.
+func securityGroupRulesAllow(rules []ec2Types.IpPermission, prefixLists map[string][]*net.IPNet, ip net.IP, port int) bool {
+	for _, rule := range rules {
+		if !ruleMatchesPort(rule, port) {
+			continue
+		}
+
+		var cidrs []*net.IPNet
+		for _, r := range rule.IpRanges {
+			if _, cidr, err := net.ParseCIDR(aws.ToString(r.CidrIp)); err == nil {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+		for _, r := range rule.Ipv6Ranges {
+			if _, cidr, err := net.ParseCIDR(aws.ToString(r.CidrIpv6)); err == nil {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+		for _, pl := range rule.PrefixListIds {
+			cidrs = append(cidrs, prefixLists[aws.ToString(pl.PrefixListId)]...)
+		}
+
+		for _, cidr := range cidrs {
+			if cidr.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
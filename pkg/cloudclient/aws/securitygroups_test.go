@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/golang/mock/gomock"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func sgRule(protocol string, from, to int32) types.IpPermission {
+	rule := types.IpPermission{IpProtocol: aws.String(protocol)}
+	if protocol != "-1" {
+		rule.FromPort = aws.Int32(from)
+		rule.ToPort = aws.Int32(to)
+	}
+	return rule
+}
+
+func TestVerifySecurityGroups(t *testing.T) {
+	defer func(orig func(string) ([]net.IP, error)) { lookupIP = orig }(lookupIP)
+	lookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "quay.io":
+			return []net.IP{net.ParseIP("203.0.113.10")}, nil
+		case "inputs1.osdsecuritylogs.splunkcloud.com":
+			return []net.IP{net.ParseIP("198.51.100.20")}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+	eps := []endpoints.Endpoint{
+		{Host: "quay.io", Ports: []int{443}},
+		{Host: "inputs1.osdsecuritylogs.splunkcloud.com", Ports: []int{9997}},
+	}
+
+	allowAll := sgRule("-1", 0, 0)
+	allowAll.IpRanges = []types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}
+	https := sgRule("tcp", 443, 443)
+	https.IpRanges = []types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}
+	splunkPrefixList := sgRule("tcp", 9997, 9997)
+	splunkPrefixList.PrefixListIds = []types.PrefixListId{{PrefixListId: aws.String("pl-1")}}
+	splunkGroupRef := sgRule("tcp", 9997, 9997)
+	splunkGroupRef.UserIdGroupPairs = []types.UserIdGroupPair{{GroupId: aws.String("sg-proxy")}}
+
+	tests := []struct {
+		name           string
+		groupIDs       []string
+		rules          []types.IpPermission
+		prefixLists    bool
+		expectFailures []string
+	}{
+		{
+			name:  "default security group allows all",
+			rules: []types.IpPermission{allowAll},
+		},
+		{
+			name:     "splunk port blocked",
+			groupIDs: []string{"sg-1"},
+			rules:    []types.IpPermission{https},
+			expectFailures: []string{
+				"no egress rule allows traffic to inputs1.osdsecuritylogs.splunkcloud.com (198.51.100.20) on port 9997",
+			},
+		},
+		{
+			name:        "splunk allowed through a prefix list",
+			groupIDs:    []string{"sg-1"},
+			rules:       []types.IpPermission{https, splunkPrefixList},
+			prefixLists: true,
+		},
+		{
+			name:     "splunk only allowed to a referenced security group",
+			groupIDs: []string{"sg-1"},
+			rules:    []types.IpPermission{https, splunkGroupRef},
+			expectFailures: []string{
+				"no egress rule allows traffic to inputs1.osdsecuritylogs.splunkcloud.com (198.51.100.20) on port 9997, the rules referencing sg-proxy only allow traffic to members of those groups",
+			},
+		},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+		FakeEC2Cli.EXPECT().DescribeSecurityGroups(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeSecurityGroupsOutput{
+			SecurityGroups: []types.SecurityGroup{{
+				GroupId:             aws.String("sg-1"),
+				IpPermissionsEgress: test.rules,
+			}},
+		}, nil)
+		if test.prefixLists {
+			FakeEC2Cli.EXPECT().GetManagedPrefixListEntries(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.GetManagedPrefixListEntriesOutput{
+				Entries: []types.PrefixListEntry{{Cidr: aws.String("198.51.100.0/24")}},
+			}, nil)
+		}
+		cli := Client{
+			ec2Client: FakeEC2Cli,
+			logger:    &logging.GlogLogger{},
+		}
+		out := cli.VerifySecurityGroups(context.TODO(), options.SecurityGroupOptions{
+			SecurityGroupIDs: test.groupIDs,
+			VpcID:            "vpc-1",
+			Endpoints:        eps,
+		})
+
+		var failures []string
+		for _, f := range out.Findings() {
+			if f.Status == output.StatusFailed {
+				failures = append(failures, f.Message)
+			}
+		}
+		assert.ElementsMatch(t, test.expectFailures, failures, test.name)
+		assert.Equal(t, len(test.expectFailures) == 0, out.IsSuccessful(), test.name)
+		ctrl.Finish()
+	}
+}
@@ -0,0 +1,403 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// requiredServiceActions are the actions the cluster calls through the endpoints of each service,
+// S3 actions are checked against the buckets of the egress endpoints instead
+var requiredServiceActions = map[string][]string{
+	"ec2": {"ec2:DescribeInstances", "ec2:RunInstances", "ec2:CreateTags", "ec2:DescribeSubnets"},
+	"elasticloadbalancing": {
+		"elasticloadbalancing:DescribeLoadBalancers",
+		"elasticloadbalancing:CreateLoadBalancer",
+		"elasticloadbalancing:RegisterTargets",
+	},
+	"sts": {"sts:AssumeRole", "sts:AssumeRoleWithWebIdentity", "sts:GetCallerIdentity"},
+}
+
+// policyDecision is the outcome of evaluating an endpoint policy for an action on a resource
+type policyDecision int
+
+const (
+	policyAllowed policyDecision = iota
+	// policyConditional means the action is only allowed for some principals or under conditions
+	policyConditional
+	// policyConditionalDeny means the action is allowed, but denied for some principals or under conditions
+	policyConditionalDeny
+	policyNotAllowed
+	policyDenied
+)
+
+// stringOrSlice is a policy element holding either a single string or a list of strings
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = []string{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+
+	return nil
+}
+
+type policyStatement struct {
+	Sid         string
+	Effect      string
+	Principal   interface{}
+	Action      stringOrSlice
+	NotAction   stringOrSlice
+	Resource    stringOrSlice
+	NotResource stringOrSlice
+	Condition   map[string]interface{}
+}
+
+// policyStatements is the Statement element of a policy, holding either a single statement or a list
+type policyStatements []policyStatement
+
+func (s *policyStatements) UnmarshalJSON(data []byte) error {
+	var single policyStatement
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = []policyStatement{single}
+		return nil
+	}
+	var list []policyStatement
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+
+	return nil
+}
+
+type policyDocument struct {
+	Statement policyStatements
+}
+
+// verifyVpcEndpoints verifies the VPC endpoints through which the cluster reaches AWS services
+// Basic workflow is:
+// - ask AWS API for the VPC endpoints of the VPC
+// - map every endpoint to the egress endpoints served by its service
+// - report interface endpoints with private DNS disabled, whose hosts bypass the endpoint
+// - report subnets whose route table is not associated with a gateway endpoint
+// - evaluate the endpoint policies against the actions the cluster needs
+func (c *Client) verifyVpcEndpoints(ctx context.Context, opts options.VpcEndpointOptions) *output.Output {
+	eps := opts.Endpoints
+	if len(eps) == 0 {
+		var err error
+		eps, err = endpoints.Default(PlatformName, map[string]string{"AWS_REGION": c.region})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+	}
+
+	var vpcEndpoints []ec2Types.VpcEndpoint
+	input := &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("vpc-id"), Values: []string{opts.VpcID}}},
+	}
+	for {
+		out, err := c.ec2Client.DescribeVpcEndpoints(ctx, input)
+		if err != nil {
+			return c.output.AddError(fmt.Errorf("unable to describe the VPC endpoints of %s: %w", opts.VpcID, err)) // fatal
+		}
+		vpcEndpoints = append(vpcEndpoints, out.VpcEndpoints...)
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	if len(vpcEndpoints) == 0 {
+		c.output.AddFinding(output.Finding{Check: "vpc-endpoint", Resource: opts.VpcID, Status: output.StatusPassed,
+			Message: "the VPC has no VPC endpoint, AWS services are reached through the default routes"})
+		return &c.output
+	}
+
+	var routeTables map[string]ec2Types.RouteTable
+	if len(opts.SubnetIDs) > 0 {
+		var err error
+		if routeTables, err = c.subnetRouteTables(ctx, opts.VpcID); err != nil {
+			return c.output.AddError(err) // fatal
+		}
+	}
+
+	for _, vpce := range vpcEndpoints {
+		c.verifyVpcEndpoint(ctx, vpce, opts.SubnetIDs, routeTables, eps)
+	}
+
+	return &c.output
+}
+
+// verifyVpcEndpoint reports the issues of a single VPC endpoint, or a passed finding if there are none
+func (c *Client) verifyVpcEndpoint(ctx context.Context, vpce ec2Types.VpcEndpoint, subnetIDs []string, routeTables map[string]ec2Types.RouteTable, eps []endpoints.Endpoint) {
+	id := aws.ToString(vpce.VpcEndpointId)
+	serviceName := aws.ToString(vpce.ServiceName)
+	service, ok := endpointService(serviceName, c.region)
+	if !ok {
+		c.logger.Debug(ctx, "Skipping VPC endpoint %s of service %s", id, serviceName)
+		return
+	}
+	hosts := servedHosts(service, c.region, eps)
+	served := strings.Join(hosts, ", ")
+	kind := strings.ToLower(string(vpce.VpcEndpointType))
+
+	issues := 0
+	addIssue := func(status output.Status, message string) {
+		c.output.AddFinding(output.Finding{Check: "vpc-endpoint", Resource: id, Status: status, Message: message})
+		issues++
+	}
+
+	if vpce.State != ec2Types.StateAvailable {
+		addIssue(output.StatusWarning, fmt.Sprintf("%s endpoint for %s is %s", kind, service, vpce.State))
+	}
+
+	switch vpce.VpcEndpointType {
+	case ec2Types.VpcEndpointTypeInterface:
+		if len(hosts) > 0 && !aws.ToBool(vpce.PrivateDnsEnabled) {
+			addIssue(output.StatusWarning, fmt.Sprintf("private DNS is disabled on the interface endpoint for %s, "+
+				"traffic to %s bypasses it", service, served))
+		}
+	case ec2Types.VpcEndpointTypeGateway:
+		if len(hosts) == 0 {
+			break
+		}
+		associated := map[string]bool{}
+		for _, rtID := range vpce.RouteTableIds {
+			associated[rtID] = true
+		}
+		for _, subnetID := range subnetIDs {
+			rt, ok := routeTableForSubnet(routeTables, subnetID)
+			if !ok {
+				continue
+			}
+			if rtID := aws.ToString(rt.RouteTableId); !associated[rtID] {
+				addIssue(output.StatusWarning, fmt.Sprintf("route table %s of subnet %s is not associated with the gateway endpoint for %s, "+
+					"traffic to %s does not use it", rtID, subnetID, service, served))
+			}
+		}
+	}
+
+	for _, finding := range evaluateEndpointPolicy(id, service, aws.ToString(vpce.PolicyDocument), hosts) {
+		addIssue(finding.Status, finding.Message)
+	}
+
+	if issues == 0 {
+		message := fmt.Sprintf("%s endpoint for %s allows the actions the cluster needs", kind, service)
+		if len(hosts) > 0 {
+			message += fmt.Sprintf(", it serves %s", served)
+		}
+		c.output.AddFinding(output.Finding{Check: "vpc-endpoint", Resource: id, Status: output.StatusPassed, Message: message})
+	}
+}
+
+// endpointService returns the short name of an AWS service from the service name of a VPC endpoint,
+// e.g. "s3" for "com.amazonaws.us-east-1.s3"
+func endpointService(serviceName, region string) (string, bool) {
+	prefix := fmt.Sprintf("com.amazonaws.%s.", region)
+	if !strings.HasPrefix(serviceName, prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(serviceName, prefix), true
+}
+
+// servedHosts returns the egress endpoint hosts whose traffic goes through an endpoint of the service in the region
+func servedHosts(service, region string, eps []endpoints.Endpoint) []string {
+	regional := fmt.Sprintf("%s.%s.amazonaws.com", service, region)
+	var hosts []string
+	for _, e := range eps {
+		host := helpers.NormalizeDomain(e.Host)
+		switch {
+		case host == regional, strings.HasSuffix(host, "."+regional):
+		// the legacy global S3 endpoint is served from us-east-1
+		case service == "s3" && region == "us-east-1" && (host == "s3.amazonaws.com" || strings.HasSuffix(host, ".s3.amazonaws.com")):
+		default:
+			continue
+		}
+		if !helpers.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	return hosts
+}
+
+// requiredPolicyAccess returns the actions the cluster needs through an endpoint of the service,
+// keyed by the resource they apply to
+func requiredPolicyAccess(service string, hosts []string) map[string][]string {
+	if service != "s3" {
+		if actions, ok := requiredServiceActions[service]; ok {
+			return map[string][]string{"*": actions}
+		}
+		return nil
+	}
+
+	// virtual-hosted-style bucket hosts, e.g. quay-registry.s3.amazonaws.com
+	access := map[string][]string{}
+	for _, host := range hosts {
+		if i := strings.Index(host, ".s3."); i > 0 {
+			access[fmt.Sprintf("arn:aws:s3:::%s/*", host[:i])] = []string{"s3:GetObject"}
+		}
+	}
+
+	return access
+}
+
+// evaluateEndpointPolicy returns a finding for every action the cluster needs that the endpoint policy doesn't allow.
+// Endpoint policies default to full access when none is set.
+func evaluateEndpointPolicy(id, service, document string, hosts []string) []output.Finding {
+	access := requiredPolicyAccess(service, hosts)
+	if len(access) == 0 || document == "" {
+		return nil
+	}
+	// some API versions return the document URL-encoded
+	if strings.HasPrefix(document, "%7B") {
+		if unescaped, err := url.QueryUnescape(document); err == nil {
+			document = unescaped
+		}
+	}
+	var policy policyDocument
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return []output.Finding{{Check: "vpc-endpoint", Resource: id, Status: output.StatusWarning,
+			Message: fmt.Sprintf("unable to parse the policy of the endpoint for %s: %s", service, err)}}
+	}
+
+	var resources []string
+	for resource := range access {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	var findings []output.Finding
+	for _, resource := range resources {
+		for _, action := range access[resource] {
+			target := action
+			if resource != "*" {
+				target += " on " + resource
+			}
+			switch policy.evaluate(action, resource) {
+			case policyDenied:
+				findings = append(findings, output.Finding{Check: "vpc-endpoint", Resource: id, Status: output.StatusFailed,
+					Message: fmt.Sprintf("the endpoint policy denies %s", target)})
+			case policyNotAllowed:
+				findings = append(findings, output.Finding{Check: "vpc-endpoint", Resource: id, Status: output.StatusFailed,
+					Message: fmt.Sprintf("the endpoint policy does not allow %s", target)})
+			case policyConditional:
+				findings = append(findings, output.Finding{Check: "vpc-endpoint", Resource: id, Status: output.StatusWarning,
+					Message: fmt.Sprintf("the endpoint policy only allows %s for specific principals or under conditions", target)})
+			case policyConditionalDeny:
+				findings = append(findings, output.Finding{Check: "vpc-endpoint", Resource: id, Status: output.StatusWarning,
+					Message: fmt.Sprintf("the endpoint policy allows %s, but it may be denied for specific principals or under conditions", target)})
+			}
+		}
+	}
+
+	return findings
+}
+
+// evaluate applies the policy evaluation logic: an explicit deny wins over any allow,
+// and actions no statement allows are implicitly denied, even if a conditional deny matches them
+func (p policyDocument) evaluate(action, resource string) policyDecision {
+	allowed, conditionalAllow, conditionalDeny := false, false, false
+	for _, s := range p.Statement {
+		if !s.matches(action, resource) {
+			continue
+		}
+		unrestricted := len(s.Condition) == 0 && isAnyPrincipal(s.Principal)
+		switch {
+		case strings.EqualFold(s.Effect, "Deny") && unrestricted:
+			return policyDenied
+		case strings.EqualFold(s.Effect, "Deny"):
+			conditionalDeny = true
+		case strings.EqualFold(s.Effect, "Allow") && unrestricted:
+			allowed = true
+		case strings.EqualFold(s.Effect, "Allow"):
+			conditionalAllow = true
+		}
+	}
+
+	switch {
+	case allowed && conditionalDeny:
+		return policyConditionalDeny
+	case allowed:
+		return policyAllowed
+	case conditionalAllow:
+		return policyConditional
+	}
+
+	return policyNotAllowed
+}
+
+// matches returns true if the statement applies to the action on the resource
+func (s policyStatement) matches(action, resource string) bool {
+	actionMatches := anyWildcardMatch(s.Action, action, true)
+	if len(s.NotAction) > 0 {
+		actionMatches = !anyWildcardMatch(s.NotAction, action, true)
+	}
+	resourceMatches := len(s.Resource) == 0 || anyWildcardMatch(s.Resource, resource, false)
+	if len(s.NotResource) > 0 {
+		resourceMatches = !anyWildcardMatch(s.NotResource, resource, false)
+	}
+
+	return actionMatches && resourceMatches
+}
+
+// isAnyPrincipal returns true if the principal element applies to everyone, endpoint policies
+// usually restrict access with conditions or principals rather than resources
+func isAnyPrincipal(principal interface{}) bool {
+	switch p := principal.(type) {
+	case nil:
+		return true
+	case string:
+		return p == "*"
+	case map[string]interface{}:
+		value, ok := p["AWS"]
+		if !ok || len(p) > 1 {
+			return false
+		}
+		switch v := value.(type) {
+		case string:
+			return v == "*"
+		case []interface{}:
+			return len(v) == 1 && v[0] == "*"
+		}
+	}
+
+	return false
+}
+
+// anyWildcardMatch returns true if the value matches any of the IAM patterns, where * matches any
+// sequence of characters and ? any single character. Actions are matched case-insensitively.
+func anyWildcardMatch(patterns []string, value string, ignoreCase bool) bool {
+	for _, pattern := range patterns {
+		expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern))
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		if regexp.MustCompile("^" + expr + "$").MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/golang/mock/gomock"
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyVpcEndpoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	FakeEC2Cli := mocks.NewMockEC2Client(ctrl)
+	FakeEC2Cli.EXPECT().DescribeVpcEndpoints(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeVpcEndpointsOutput{
+		VpcEndpoints: []types.VpcEndpoint{
+			{
+				VpcEndpointId:   aws.String("vpce-s3"),
+				ServiceName:     aws.String("com.amazonaws.us-east-1.s3"),
+				VpcEndpointType: types.VpcEndpointTypeGateway,
+				State:           types.StateAvailable,
+				RouteTableIds:   []string{"rtb-private"},
+				PolicyDocument: aws.String(`{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:*",
+					"Resource": ["arn:aws:s3:::customer-bucket", "arn:aws:s3:::customer-bucket/*"]}]}`),
+			},
+			{
+				VpcEndpointId:     aws.String("vpce-sts"),
+				ServiceName:       aws.String("com.amazonaws.us-east-1.sts"),
+				VpcEndpointType:   types.VpcEndpointTypeInterface,
+				State:             types.StateAvailable,
+				PrivateDnsEnabled: aws.Bool(true),
+				PolicyDocument: aws.String(`{"Statement": [
+					{"Effect": "Allow", "Principal": "*", "Action": "*", "Resource": "*"},
+					{"Effect": "Deny", "Principal": "*", "Action": "sts:AssumeRoleWith*", "Resource": "*"}]}`),
+			},
+			{
+				VpcEndpointId:     aws.String("vpce-ec2"),
+				ServiceName:       aws.String("com.amazonaws.us-east-1.ec2"),
+				VpcEndpointType:   types.VpcEndpointTypeInterface,
+				State:             types.StateAvailable,
+				PrivateDnsEnabled: aws.Bool(false),
+				PolicyDocument:    aws.String(`{"Statement": {"Effect": "Allow", "Principal": "*", "Action": "*", "Resource": "*"}}`),
+			},
+		},
+	}, nil)
+	FakeEC2Cli.EXPECT().DescribeRouteTables(gomock.Any(), gomock.Any()).Times(1).Return(&ec2.DescribeRouteTablesOutput{
+		RouteTables: []types.RouteTable{
+			{RouteTableId: aws.String("rtb-main"), Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}}},
+			{RouteTableId: aws.String("rtb-private"), Associations: []types.RouteTableAssociation{{SubnetId: aws.String("subnet-private")}}},
+		},
+	}, nil)
+
+	cli := Client{
+		ec2Client: FakeEC2Cli,
+		region:    "us-east-1",
+		logger:    &logging.GlogLogger{},
+	}
+	out := cli.VerifyVpcEndpoints(context.TODO(), options.VpcEndpointOptions{
+		VpcID:     "vpc-1",
+		SubnetIDs: []string{"subnet-private", "subnet-public"},
+		Endpoints: []endpoints.Endpoint{
+			{Host: "quay-registry.s3.amazonaws.com", Ports: []int{443}},
+			{Host: "ec2.us-east-1.amazonaws.com", Ports: []int{443}},
+			{Host: "quay.io", Ports: []int{443}},
+		},
+	})
+
+	assert.Equal(t, []output.Finding{
+		{Check: "vpc-endpoint", Resource: "vpce-s3", Status: output.StatusWarning,
+			Message: "route table rtb-main of subnet subnet-public is not associated with the gateway endpoint for s3, " +
+				"traffic to quay-registry.s3.amazonaws.com does not use it"},
+		{Check: "vpc-endpoint", Resource: "vpce-s3", Status: output.StatusFailed,
+			Message: "the endpoint policy does not allow s3:GetObject on arn:aws:s3:::quay-registry/*"},
+		{Check: "vpc-endpoint", Resource: "vpce-sts", Status: output.StatusFailed,
+			Message: "the endpoint policy denies sts:AssumeRoleWithWebIdentity"},
+		{Check: "vpc-endpoint", Resource: "vpce-ec2", Status: output.StatusWarning,
+			Message: "private DNS is disabled on the interface endpoint for ec2, traffic to ec2.us-east-1.amazonaws.com bypasses it"},
+	}, out.Findings())
+	assert.False(t, out.IsSuccessful())
+}
+
+func TestEvaluateEndpointPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expect   policyDecision
+		message  string
+	}{
+		{name: "full access", document: `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "*", "Resource": "*"}]}`,
+			expect: policyAllowed},
+		{name: "url-encoded", document: `%7B%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Principal%22%3A%22%2A%22%2C%22Action%22%3A%22s3%3AGet%2A%22%2C%22Resource%22%3A%22%2A%22%7D%5D%7D`,
+			expect: policyAllowed},
+		{name: "action case", document: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "S3:getobject", "Resource": "arn:aws:s3:::quay-registry/*"}]}`,
+			expect: policyAllowed},
+		{name: "conditional", document: `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:*", "Resource": "*",
+			"Condition": {"StringEquals": {"aws:PrincipalAccount": "123456789012"}}}]}`,
+			expect: policyConditional},
+		{name: "not action", document: `{"Statement": [{"Effect": "Allow", "Principal": "*", "NotAction": "s3:GetObject", "Resource": "*"}]}`,
+			expect: policyNotAllowed},
+		{name: "explicit deny", document: `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "*", "Resource": "*"},
+			{"Effect": "Deny", "Principal": "*", "Action": "s3:Get*", "NotResource": "arn:aws:s3:::customer-*"}]}`,
+			expect: policyDenied},
+		{name: "conditional deny without allow", document: `{"Statement": [{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "*",
+			"Condition": {"StringNotEquals": {"aws:PrincipalAccount": "123456789012"}}}]}`,
+			expect: policyNotAllowed, message: "the endpoint policy does not allow s3:GetObject"},
+		{name: "allow with conditional deny", document: `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "*", "Resource": "*"},
+			{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "*", "Condition": {"StringNotEquals": {"aws:PrincipalAccount": "123456789012"}}}]}`,
+			expect: policyConditionalDeny, message: "the endpoint policy allows s3:GetObject on arn:aws:s3:::quay-registry/*, but it may be denied"},
+	}
+	for _, test := range tests {
+		findings := evaluateEndpointPolicy("vpce-1", "s3", test.document, []string{"quay-registry.s3.amazonaws.com"})
+		var status output.Status
+		if len(findings) > 0 {
+			status = findings[0].Status
+		}
+		switch test.expect {
+		case policyAllowed:
+			assert.Empty(t, findings, test.name)
+		case policyConditional, policyConditionalDeny:
+			assert.Equal(t, output.StatusWarning, status, test.name)
+		case policyNotAllowed, policyDenied:
+			assert.Equal(t, output.StatusFailed, status, test.name)
+		}
+		if test.message != "" && assert.NotEmpty(t, findings, test.name) {
+			assert.Contains(t, findings[0].Message, test.message, test.name)
+		}
+	}
+}
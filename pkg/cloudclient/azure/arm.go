@@ -0,0 +1,289 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+)
+
+// defaultARMEndpoint is the Azure Resource Manager endpoint of the public cloud
+const defaultARMEndpoint = "https://management.azure.com"
+
+// API versions of the Azure Resource Manager providers
+const (
+	computeAPIVersion    = "2023-09-01"
+	networkAPIVersion    = "2023-09-01"
+	privateDnsAPIVersion = "2020-06-01"
+)
+
+// armClient calls the Azure Resource Manager REST API. Resources are addressed by their ID,
+// e.g. /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Network/virtualNetworks/<name>
+type armClient struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// armError is the error returned by the Azure Resource Manager API
+type armError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *armError) Error() string {
+	return fmt.Sprintf("ARM API returned HTTP %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// armErrorResponse is the body of an error returned by the Azure Resource Manager API
+type armErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// armListResponse is a page of a list, followed by the next one if nextLink is set
+type armListResponse struct {
+	Value    json.RawMessage `json:"value"`
+	NextLink string          `json:"nextLink"`
+}
+
+// armOperation is the status of a long-running operation, polled on its Azure-AsyncOperation URL
+type armOperation struct {
+	Status string `json:"status"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+// get reads the resource into out
+func (a *armClient) get(ctx context.Context, id, apiVersion string, out interface{}) error {
+	_, err := a.do(ctx, http.MethodGet, id, apiVersion, nil, out)
+	return err
+}
+
+// list calls add with the value of every page of the list
+func (a *armClient) list(ctx context.Context, id, apiVersion string, add func(value json.RawMessage) error) error {
+	next := id
+	for next != "" {
+		var page armListResponse
+		// the next link holds the API version and the continuation token
+		version := apiVersion
+		if strings.HasPrefix(next, "http") {
+			version = ""
+		}
+		if _, err := a.do(ctx, http.MethodGet, next, version, nil, &page); err != nil {
+			return err
+		}
+		if len(page.Value) > 0 {
+			if err := add(page.Value); err != nil {
+				return err
+			}
+		}
+		next = page.NextLink
+	}
+
+	return nil
+}
+
+// put creates or updates the resource, waits for the operation to complete and reads the resulting resource into out
+func (a *armClient) put(ctx context.Context, id, apiVersion string, body, out interface{}) error {
+	resp, err := a.do(ctx, http.MethodPut, id, apiVersion, body, out)
+	if err != nil {
+		return err
+	}
+
+	return a.complete(ctx, id, apiVersion, resp, out)
+}
+
+// complete waits for the operation a PUT on the resource started to complete, and reads the resulting resource into out
+func (a *armClient) complete(ctx context.Context, id, apiVersion string, resp *http.Response, out interface{}) error {
+	if _, err := a.wait(ctx, resp); err != nil {
+		return fmt.Errorf("unable to create %s: %w", resourceName(id), err)
+	}
+
+	return a.get(ctx, id, apiVersion, out)
+}
+
+// post runs the action on the resource, e.g. virtualMachines/<name>/runCommand, waits for the operation to complete
+// and reads its result into out
+func (a *armClient) post(ctx context.Context, id, apiVersion string, body, out interface{}) error {
+	resp, err := a.do(ctx, http.MethodPost, id, apiVersion, body, out)
+	if err != nil {
+		return err
+	}
+	properties, err := a.wait(ctx, resp)
+	if err != nil {
+		return fmt.Errorf("unable to %s: %w", resourceName(id), err)
+	}
+	switch {
+	// the result is served on the Location URL once the operation has completed
+	case resp.Header.Get("Location") != "":
+		_, err = a.do(ctx, http.MethodGet, resp.Header.Get("Location"), "", nil, out)
+		return err
+	case len(properties) > 0:
+		var result struct {
+			Output json.RawMessage `json:"output"`
+		}
+		if err := json.Unmarshal(properties, &result); err == nil && len(result.Output) > 0 {
+			return json.Unmarshal(result.Output, out)
+		}
+	}
+
+	return nil
+}
+
+// delete starts deleting the resource, without waiting for the deletion to complete
+func (a *armClient) delete(ctx context.Context, id, apiVersion string) error {
+	_, err := a.do(ctx, http.MethodDelete, id, apiVersion, nil, nil)
+	return err
+}
+
+// wait polls the Azure-AsyncOperation URL of a long-running operation until it completes, and returns its properties.
+// Accepted operations without such URL are polled on their Location URL, the others completed synchronously.
+func (a *armClient) wait(ctx context.Context, resp *http.Response) (json.RawMessage, error) {
+	operationURL := resp.Header.Get("Azure-AsyncOperation")
+	if operationURL == "" {
+		location := resp.Header.Get("Location")
+		if resp.StatusCode != http.StatusAccepted || location == "" {
+			return nil, nil
+		}
+		return nil, helpers.PollImmediate(operationPollInterval, operationPollTimeout, func() (bool, error) {
+			r, err := a.do(ctx, http.MethodGet, location, "", nil, nil)
+			if err != nil {
+				return false, err
+			}
+			return r.StatusCode != http.StatusAccepted, nil
+		})
+	}
+
+	var op armOperation
+	err := helpers.PollImmediate(operationPollInterval, operationPollTimeout, func() (bool, error) {
+		op = armOperation{}
+		if _, err := a.do(ctx, http.MethodGet, operationURL, "", nil, &op); err != nil {
+			return false, err
+		}
+		return op.Status != "InProgress" && op.Status != "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if op.Status != "Succeeded" {
+		if op.Error != nil {
+			return nil, fmt.Errorf("operation %s: %s: %s", strings.ToLower(op.Status), op.Error.Code, op.Error.Message)
+		}
+		return nil, fmt.Errorf("operation %s", strings.ToLower(op.Status))
+	}
+
+	return op.Properties, nil
+}
+
+// do sends the request to the resource ID, or to the absolute URL, and decodes the JSON response into out.
+// Error responses are returned as *armError.
+func (a *armClient) do(ctx context.Context, method, id, apiVersion string, body, out interface{}) (*http.Response, error) {
+	target := id
+	if !strings.HasPrefix(id, "http") {
+		target = a.endpoint + id
+	}
+	if apiVersion != "" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		query := u.Query()
+		query.Set("api-version", apiVersion)
+		u.RawQuery = query.Encode()
+		target = u.String()
+	}
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &armError{StatusCode: resp.StatusCode, Code: http.StatusText(resp.StatusCode)}
+		var errResp armErrorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error.Code != "" {
+			apiErr.Code, apiErr.Message = errResp.Error.Code, errResp.Error.Message
+		}
+		return nil, apiErr
+	}
+	// accepted operations have no body, or the resource in its initial state
+	if out != nil && len(bytes.TrimSpace(data)) > 0 && resp.StatusCode != http.StatusAccepted {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("unable to decode the response to %s %s: %w", method, id, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// resourceName returns the last segments of a resource ID naming the resource, e.g. virtualMachines/<name>
+func resourceName(id string) string {
+	parts := strings.Split(strings.TrimSuffix(id, "/"), "/")
+	if len(parts) < 2 {
+		return id
+	}
+
+	return strings.Join(parts[len(parts)-2:], "/")
+}
+
+// resourceGroupID returns the ID of the resource group of the resource, e.g. /subscriptions/<id>/resourceGroups/<group>
+func resourceGroupID(id string) string {
+	parts := strings.Split(id, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return strings.Join(parts[:i+2], "/")
+		}
+	}
+
+	return ""
+}
+
+// parentID returns the ID of the parent of a child resource, e.g. the virtual network of a subnet
+func parentID(id string) string {
+	parts := strings.Split(strings.TrimSuffix(id, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return strings.Join(parts[:len(parts)-2], "/")
+}
+
+// sameID returns true if both IDs name the same resource, resource IDs are case insensitive
+func sameID(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "/"), strings.TrimSuffix(b, "/"))
+}
@@ -0,0 +1,144 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	rgPath   = "/subscriptions/sub/resourceGroups/rg"
+	vnetID   = rgPath + "/providers/Microsoft.Network/virtualNetworks/vnet"
+	opPrefix = "/subscriptions/sub/providers/Microsoft.Network/locations/eastus"
+)
+
+func TestArmPut(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+	operationPollInterval = time.Millisecond
+
+	const id = rgPath + "/providers/Microsoft.Network/networkInterfaces/nic"
+	api := fakeapi.New(t).
+		On("PUT", id, fakeapi.Response{Status: http.StatusCreated, Header: map[string]string{"Azure-AsyncOperation": opPrefix + "/operations/op1"},
+			Body: map[string]interface{}{"properties": map[string]string{"provisioningState": "Updating"}}}).
+		On("GET", opPrefix+"/operations/op1",
+			fakeapi.Response{Body: map[string]string{"status": "InProgress"}},
+			fakeapi.Response{Body: map[string]string{"status": "Succeeded"}}).
+		On("GET", id, fakeapi.Response{Body: map[string]interface{}{"id": id, "properties": map[string]string{"provisioningState": "Succeeded"}}})
+	cli := newFakeClient(t, api)
+
+	var nic struct {
+		ID         string `json:"id"`
+		Properties struct {
+			ProvisioningState string `json:"provisioningState"`
+		} `json:"properties"`
+	}
+	err := cli.arm.put(context.TODO(), id, networkAPIVersion, map[string]string{"location": "eastus"}, &nic)
+	assert.NoError(t, err)
+	assert.Equal(t, "Succeeded", nic.Properties.ProvisioningState)
+	assert.Len(t, api.Received("GET", opPrefix+"/operations/op1"), 2)
+	if puts := api.Received("PUT", id); assert.Len(t, puts, 1) {
+		assert.Equal(t, []string{networkAPIVersion}, puts[0].Query["api-version"])
+		assert.JSONEq(t, `{"location": "eastus"}`, string(puts[0].Body))
+	}
+}
+
+func TestArmPutFailedOperation(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+	operationPollInterval = time.Millisecond
+
+	const id = rgPath + "/providers/Microsoft.Compute/virtualMachines/vm"
+	api := fakeapi.New(t).
+		On("PUT", id, fakeapi.Response{Status: http.StatusCreated, Header: map[string]string{"Azure-AsyncOperation": opPrefix + "/operations/op2"}}).
+		On("GET", opPrefix+"/operations/op2", fakeapi.Response{Body: map[string]interface{}{
+			"status": "Failed",
+			"error":  map[string]string{"code": "SkuNotAvailable", "message": "Standard_B2s is not available in eastus"},
+		}})
+	cli := newFakeClient(t, api)
+
+	err := cli.arm.put(context.TODO(), id, computeAPIVersion, map[string]string{}, &virtualMachine{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "operation failed: SkuNotAvailable")
+	}
+}
+
+func TestArmPostLocation(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+	operationPollInterval = time.Millisecond
+
+	const id = rgPath + "/providers/Microsoft.Network/networkInterfaces/nic/effectiveRouteTable"
+	api := fakeapi.New(t).
+		On("POST", id, fakeapi.Response{Status: http.StatusAccepted, Header: map[string]string{"Location": opPrefix + "/operationResults/r1"}}).
+		On("GET", opPrefix+"/operationResults/r1",
+			fakeapi.Response{Status: http.StatusAccepted, Header: map[string]string{"Location": opPrefix + "/operationResults/r1"}},
+			fakeapi.Response{Body: map[string]interface{}{"value": []map[string]interface{}{
+				{"source": "Default", "state": "Active", "addressPrefix": []string{"0.0.0.0/0"}, "nextHopType": "Internet"},
+			}}})
+	cli := newFakeClient(t, api)
+
+	var routes struct {
+		Value []effectiveRoute `json:"value"`
+	}
+	assert.NoError(t, cli.arm.post(context.TODO(), id, networkAPIVersion, nil, &routes))
+	assert.Equal(t, []effectiveRoute{{Source: "Default", State: "Active", AddressPrefix: []string{"0.0.0.0/0"}, NextHopType: "Internet"}}, routes.Value)
+}
+
+func TestArmList(t *testing.T) {
+	const id = "/subscriptions/sub/providers/Microsoft.Network/privateDnsZones"
+	api := fakeapi.New(t)
+	cli := newFakeClient(t, api)
+	api.On("GET", id,
+		fakeapi.Response{Body: map[string]interface{}{"value": []map[string]string{{"name": "a.example"}}, "nextLink": cli.arm.endpoint + id + "?page=2"}},
+		fakeapi.Response{Body: map[string]interface{}{"value": []map[string]string{{"name": "b.example"}}}})
+
+	var names []string
+	err := cli.arm.list(context.TODO(), id, privateDnsAPIVersion, func(value json.RawMessage) error {
+		var zones []privateDnsZone
+		if err := json.Unmarshal(value, &zones); err != nil {
+			return err
+		}
+		for _, z := range zones {
+			names = append(names, z.Name)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.example", "b.example"}, names)
+	if requests := api.Received("GET", id); assert.Len(t, requests, 2) {
+		assert.Equal(t, []string{privateDnsAPIVersion}, requests[0].Query["api-version"])
+		// the next link is followed as is
+		assert.Equal(t, []string{"2"}, requests[1].Query["page"])
+		assert.Empty(t, requests[1].Query["api-version"])
+	}
+}
+
+func TestArmError(t *testing.T) {
+	api := fakeapi.New(t).
+		On("GET", vnetID, armFailure(http.StatusForbidden, "AuthorizationFailed", "the client does not have authorization"))
+	cli := newFakeClient(t, api)
+
+	err := cli.arm.get(context.TODO(), vnetID, networkAPIVersion, &virtualNetwork{})
+	var apiErr *armError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+		assert.Equal(t, "AuthorizationFailed", apiErr.Code)
+	}
+	class, code := classifyError(err)
+	assert.Equal(t, errorClassAccessDenied, class)
+	assert.Equal(t, "AuthorizationFailed", code)
+}
+
+func TestResourceIDs(t *testing.T) {
+	const subnetID = vnetID + "/subnets/workers"
+	assert.Equal(t, "subnets/workers", resourceName(subnetID))
+	assert.Equal(t, vnetID, parentID(subnetID))
+	assert.Equal(t, rgPath, resourceGroupID(subnetID))
+	assert.Equal(t, "", resourceGroupID("/subscriptions/sub"))
+	assert.True(t, sameID(vnetID, "/subscriptions/SUB/resourcegroups/RG/providers/Microsoft.Network/virtualNetworks/vnet/"))
+	assert.False(t, sameID(vnetID, vnetID+"2"))
+}
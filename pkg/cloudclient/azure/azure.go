@@ -0,0 +1,63 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"golang.org/x/oauth2"
+)
+
+// ClientIdentifier is what kind of cloud this implement supports
+const ClientIdentifier string = "Azure"
+
+// PlatformName is the name the Azure provider is registered under, and the endpoint profile it verifies
+const PlatformName string = "azure"
+
+// Client represents an Azure Client
+type Client struct {
+	subscriptionID string
+	region         string
+	instanceType   string
+	arm            *armClient
+	tags           map[string]string
+	logger         ocmlog.Logger
+	output         output.Output
+}
+
+func (c *Client) ByoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.byoVPCValidator(ctx, opts)
+}
+
+func (c *Client) ValidateEgress(ctx context.Context, opts options.EgressOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	if opts.Mode != options.EgressModeInstance {
+		return c.output.AddError(fmt.Errorf("egress mode %q is not supported on Azure", opts.Mode))
+	}
+	return c.validateEgress(ctx, opts)
+}
+
+func (c *Client) VerifyDns(ctx context.Context, opts options.DnsOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.verifyDns(ctx, opts)
+}
+
+// NewClient creates a new CloudClient for use with Azure.
+// The token source must issue tokens for the Azure Resource Manager API, the probe VMs are created in the subscription,
+// in the resource group of their subnet.
+func NewClient(ctx context.Context, logger ocmlog.Logger, tokenSource oauth2.TokenSource, subscriptionID, region, instanceType string, tags map[string]string) (*Client, error) {
+	// initialize actual client
+	return newClient(ctx, logger, tokenSource, subscriptionID, region, instanceType, tags)
+}
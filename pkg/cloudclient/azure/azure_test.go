@@ -0,0 +1,271 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/validatortest"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// fakeLookupIP resolves quay.io to 198.51.100.7 and every other host to 203.0.113.10
+func fakeLookupIP(host string) ([]net.IP, error) {
+	if host == "quay.io" {
+		return []net.IP{net.ParseIP("198.51.100.7")}, nil
+	}
+	return []net.IP{net.ParseIP("203.0.113.10")}, nil
+}
+
+func TestValidateEgress(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval, runCommandPollInterval = interval, interval }(operationPollInterval)
+	operationPollInterval, runCommandPollInterval = time.Millisecond, time.Millisecond
+	defer func(lookup func(string) ([]net.IP, error)) { lookupIP = lookup }(lookupIP)
+	lookupIP = fakeLookupIP
+
+	const (
+		subnetID = vnetID + "/subnets/workers"
+		nicPath  = rgPath + "/providers/Microsoft.Network/networkInterfaces/osd-network-verifier-*"
+		vmPath   = rgPath + "/providers/Microsoft.Compute/virtualMachines/osd-network-verifier-*"
+		nsgID    = rgPath + "/providers/Microsoft.Network/networkSecurityGroups/workers-nsg"
+	)
+	api := fakeapi.New(t).
+		On("PUT", nicPath, fakeapi.Response{}).
+		On("GET", nicPath, fakeapi.Response{Body: fakeapi.JSON(t, `{"properties": {"provisioningState": "Succeeded"}}`)}).
+		On("PUT", vmPath, fakeapi.Response{Status: http.StatusCreated, Header: map[string]string{"Azure-AsyncOperation": opPrefix + "/operations/vm"}}).
+		On("GET", opPrefix+"/operations/vm",
+			fakeapi.Response{Body: map[string]string{"status": "InProgress"}},
+			fakeapi.Response{Body: map[string]string{"status": "Succeeded"}}).
+		On("GET", vmPath, fakeapi.Response{Body: fakeapi.JSON(t, `{"properties": {"provisioningState": "Succeeded"}}`)}).
+		On("POST", nicPath+"/effectiveRouteTable", fakeapi.Response{Status: http.StatusAccepted, Header: map[string]string{"Location": opPrefix + "/operationResults/routes"}}).
+		On("GET", opPrefix+"/operationResults/routes", fakeapi.Response{Body: fakeapi.JSON(t, `{"value": [
+			{"source": "Default", "state": "Active", "addressPrefix": ["10.0.0.0/16"], "nextHopType": "VnetLocal"},
+			{"source": "Default", "state": "Invalid", "addressPrefix": ["0.0.0.0/0"], "nextHopType": "Internet"},
+			{"source": "User", "state": "Active", "addressPrefix": ["0.0.0.0/0"], "nextHopType": "VirtualAppliance", "nextHopIpAddress": ["10.0.100.4"]}
+		]}`)}).
+		On("POST", nicPath+"/effectiveNetworkSecurityGroups", fakeapi.Response{Status: http.StatusAccepted, Header: map[string]string{"Location": opPrefix + "/operationResults/nsg"}}).
+		On("GET", opPrefix+"/operationResults/nsg", fakeapi.Response{Body: fakeapi.JSON(t, `{"value": [{
+			"networkSecurityGroup": {"id": "`+nsgID+`"},
+			"effectiveSecurityRules": [
+				{"name": "securityRules/deny-quay", "protocol": "Tcp", "destinationAddressPrefix": "198.51.100.7/32", "destinationPortRange": "443-443",
+				 "access": "Deny", "priority": 100, "direction": "Outbound"},
+				{"name": "defaultSecurityRules/AllowInternetOutBound", "protocol": "All", "destinationAddressPrefix": "Internet",
+				 "expandedDestinationAddressPrefix": ["1.0.0.0/8", "2.0.0.0/7"], "destinationPortRange": "0-65535",
+				 "access": "Allow", "priority": 65001, "direction": "Outbound"},
+				{"name": "defaultSecurityRules/DenyAllOutBound", "protocol": "All", "destinationAddressPrefix": "0.0.0.0/0", "destinationPortRange": "0-65535",
+				 "access": "Deny", "priority": 65500, "direction": "Outbound"}
+			]
+		}]}`)}).
+		On("POST", vmPath+"/runCommand",
+			fakeapi.Response{Body: fakeapi.JSON(t, `{"value": [{"code": "ProvisioningState/succeeded", "message": "Enable succeeded: \n[stdout]\nUSERDATA BEGIN\n"}]}`)},
+			fakeapi.Response{Status: http.StatusAccepted, Header: map[string]string{"Location": opPrefix + "/operationResults/run"}}).
+		On("GET", opPrefix+"/operationResults/run", fakeapi.Response{Body: fakeapi.JSON(t, `{"value": [{"code": "ProvisioningState/succeeded",
+			"message": "Enable succeeded: \n[stdout]\nUSERDATA BEGIN\nUsing the azure endpoint profile\nNot all endpoints were reachable:\nUnable to reach quay.io:443 within specified timeout after 3 retries\nUSERDATA END\n[stderr]\n"}]}`)}).
+		On("DELETE", vmPath, fakeapi.Response{Status: http.StatusAccepted})
+
+	cli := newFakeClient(t, api)
+	cli.tags = map[string]string{"osd-network-verifier": "owned"}
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: subnetID, KmsKeyID: rgPath + "/providers/Microsoft.Compute/diskEncryptionSets/des", Timeout: time.Second})
+
+	failures, exceptions, errs := out.Parse()
+	// the failed findings are reported as failures too
+	if assert.Len(t, failures, 2) {
+		assert.Contains(t, failures[1].Error(), "Unable to reach quay.io:443")
+	}
+	assert.Empty(t, exceptions)
+	assert.Empty(t, errs)
+	findings := out.Findings()
+	if assert.Len(t, findings, 3) {
+		assert.Equal(t, output.Finding{Check: "effective-route", Resource: findings[0].Resource, Status: output.StatusPassed,
+			Message: "the user-defined default route sends egress to the virtual appliance 10.0.100.4, it must allow the egress endpoints"}, findings[0])
+		assert.Equal(t, output.Finding{Check: "effective-nsg", Resource: "networkSecurityGroups/workers-nsg", Status: output.StatusFailed,
+			Message: "egress to quay.io (198.51.100.7) on port 443 is denied by rule securityRules/deny-quay"}, findings[1])
+		assert.Equal(t, "effective-nsg", findings[2].Check)
+		assert.True(t, strings.HasPrefix(findings[2].Message, "rule defaultSecurityRules/AllowInternetOutBound allows egress to registry.redhat.io:443, sso.redhat.com:443"), findings[2].Message)
+		assert.Contains(t, findings[2].Message, "arosvc.eastus.data.azurecr.io:443")
+	}
+
+	nics := api.Received("PUT", nicPath)
+	if assert.Len(t, nics, 1) {
+		var nic networkInterface
+		assert.NoError(t, json.Unmarshal(nics[0].Body, &nic))
+		assert.Equal(t, subnetID, nic.Properties.IPConfigurations[0].Properties.Subnet.ID)
+		assert.Nil(t, nic.Properties.NetworkSecurityGroup)
+		assert.Equal(t, "owned", nic.Tags["osd-network-verifier"])
+	}
+	vms := api.Received("PUT", vmPath)
+	if assert.Len(t, vms, 1) {
+		var vm virtualMachine
+		assert.NoError(t, json.Unmarshal(vms[0].Body, &vm))
+		assert.Equal(t, "eastus", vm.Location)
+		assert.Equal(t, defaultInstanceType, vm.Properties.HardwareProfile.VMSize)
+		assert.Equal(t, defaultImage, vm.Properties.StorageProfile.ImageReference)
+		assert.Equal(t, "Delete", vm.Properties.StorageProfile.OSDisk.DeleteOption)
+		assert.Equal(t, rgPath+"/providers/Microsoft.Compute/diskEncryptionSets/des", vm.Properties.StorageProfile.OSDisk.ManagedDisk.DiskEncryptionSet.ID)
+		assert.Equal(t, "Delete", vm.Properties.NetworkProfile.NetworkInterfaces[0].Properties.DeleteOption)
+		assert.True(t, vm.Properties.DiagnosticsProfile.BootDiagnostics.Enabled)
+		customData, err := base64.StdEncoding.DecodeString(vm.Properties.OSProfile.CustomData)
+		assert.NoError(t, err)
+		assert.Contains(t, string(customData), `--env "PLATFORM=azure" --env "AZURE_REGION=eastus"`)
+		assert.Contains(t, string(customData), "--timeout=1s")
+	}
+	runs := api.Received("POST", vmPath+"/runCommand")
+	if assert.Len(t, runs, 2) {
+		var input runCommandInput
+		assert.NoError(t, json.Unmarshal(runs[0].Body, &input))
+		// the output is truncated to its last 4096 bytes, only the parsed lines are read
+		assert.Equal(t, []string{probeOutputScript}, input.Script)
+		assert.Contains(t, input.Script[0], "grep -E 'Unable to reach|")
+	}
+	assert.Len(t, api.Received("DELETE", vmPath), 1)
+}
+
+func TestValidateEgressValidatorOutput(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval, runCommandPollInterval = interval, interval }(operationPollInterval)
+	operationPollInterval, runCommandPollInterval = time.Millisecond, time.Millisecond
+	result := validatortest.Run(t, PlatformName)
+
+	// the run-command script reads the output of the validator on the VM
+	logFile := filepath.Join(t.TempDir(), "userdata-output")
+	assert.NoError(t, ioutil.WriteFile(logFile, []byte(result.Output), 0600))
+	grepped, err := exec.Command("sh", "-c", strings.Replace(probeOutputScript, "/var/log/userdata-output", logFile, 1)).Output()
+	assert.NoError(t, err)
+	message, err := json.Marshal("Enable succeeded: \n[stdout]\n" + string(grepped) + "\n[stderr]\n")
+	assert.NoError(t, err)
+
+	const (
+		nicPath = rgPath + "/providers/Microsoft.Network/networkInterfaces/osd-network-verifier-*"
+		vmPath  = rgPath + "/providers/Microsoft.Compute/virtualMachines/osd-network-verifier-*"
+	)
+	api := fakeapi.New(t).
+		On("PUT", nicPath, fakeapi.Response{}).
+		On("GET", nicPath, fakeapi.Response{Body: map[string]interface{}{}}).
+		On("PUT", vmPath, fakeapi.Response{Status: http.StatusCreated, Header: map[string]string{"Azure-AsyncOperation": opPrefix + "/operations/vm"}}).
+		On("GET", opPrefix+"/operations/vm", fakeapi.Response{Body: map[string]string{"status": "Succeeded"}}).
+		On("GET", vmPath, fakeapi.Response{Body: fakeapi.JSON(t, `{"properties": {"provisioningState": "Succeeded"}}`)}).
+		On("POST", nicPath+"/effectiveRouteTable", armFailure(http.StatusForbidden, "AuthorizationFailed", "not allowed")).
+		On("POST", nicPath+"/effectiveNetworkSecurityGroups", armFailure(http.StatusForbidden, "AuthorizationFailed", "not allowed")).
+		On("POST", vmPath+"/runCommand", fakeapi.Response{Body: fakeapi.JSON(t, `{"value": [{"code": "ProvisioningState/succeeded", "message": `+string(message)+`}]}`)}).
+		On("DELETE", vmPath, fakeapi.Response{Status: http.StatusAccepted})
+
+	cli := newFakeClient(t, api)
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: vnetID + "/subnets/workers", Timeout: time.Second})
+
+	failures, exceptions, errs := out.Parse()
+	if assert.Len(t, failures, 1) {
+		assert.Contains(t, failures[0].Error(), result.Unreachable)
+	}
+	assert.Empty(t, exceptions)
+	assert.Empty(t, errs)
+
+	// the custom data runs the pinned image with the Azure profile
+	vms := api.Received("PUT", vmPath)
+	if assert.Len(t, vms, 1) {
+		var vm virtualMachine
+		assert.NoError(t, json.Unmarshal(vms[0].Body, &vm))
+		customData, err := base64.StdEncoding.DecodeString(vm.Properties.OSProfile.CustomData)
+		assert.NoError(t, err)
+		assert.Contains(t, string(customData), `docker run --env "PLATFORM=azure"`)
+		assert.Contains(t, string(customData), helpers.NetworkValidatorImage)
+	}
+}
+
+func TestValidateEgressVMCreationFailure(t *testing.T) {
+	const nicPath = rgPath + "/providers/Microsoft.Network/networkInterfaces/osd-network-verifier-*"
+	api := fakeapi.New(t).
+		On("PUT", nicPath, fakeapi.Response{}).
+		On("GET", nicPath, fakeapi.Response{Body: map[string]interface{}{}}).
+		On("PUT", rgPath+"/providers/Microsoft.Compute/virtualMachines/*", armFailure(http.StatusConflict, "OperationNotAllowed", "quota exceeded")).
+		On("DELETE", nicPath, fakeapi.Response{Status: http.StatusAccepted})
+	cli := newFakeClient(t, api)
+
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: vnetID + "/subnets/workers"})
+	_, _, errs := out.Parse()
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "OperationNotAllowed")
+	}
+	// the network interface is left behind unless deleted explicitly
+	assert.Len(t, api.Received("DELETE", nicPath), 1)
+}
+
+func TestValidateEgressVMOperationFailure(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+	operationPollInterval = time.Millisecond
+
+	const (
+		nicPath = rgPath + "/providers/Microsoft.Network/networkInterfaces/osd-network-verifier-*"
+		vmPath  = rgPath + "/providers/Microsoft.Compute/virtualMachines/osd-network-verifier-*"
+	)
+	api := fakeapi.New(t).
+		On("PUT", nicPath, fakeapi.Response{}).
+		On("GET", nicPath, fakeapi.Response{Body: map[string]interface{}{}}).
+		On("PUT", vmPath, fakeapi.Response{Status: http.StatusCreated, Header: map[string]string{"Azure-AsyncOperation": opPrefix + "/operations/vm"}}).
+		On("GET", opPrefix+"/operations/vm",
+			fakeapi.Response{Body: map[string]string{"status": "InProgress"}},
+			fakeapi.Response{Body: fakeapi.JSON(t, `{"status": "Failed", "error": {"code": "OSProvisioningTimedOut", "message": "OS provisioning did not finish in the allotted time"}}`)}).
+		On("DELETE", vmPath, fakeapi.Response{Status: http.StatusAccepted})
+	cli := newFakeClient(t, api)
+
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: vnetID + "/subnets/workers"})
+	_, _, errs := out.Parse()
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "OSProvisioningTimedOut")
+	}
+	// the VM exists once its creation was accepted, its disk and network interface are deleted with it
+	assert.Len(t, api.Received("DELETE", vmPath), 1)
+	assert.Empty(t, api.Received("DELETE", nicPath))
+}
+
+func TestValidateEgressErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts options.EgressOptions
+		err  string
+	}{
+		{name: "unsupported mode", opts: options.EgressOptions{SubnetID: vnetID + "/subnets/workers", Mode: options.EgressModeStatic},
+			err: `egress mode "static" is not supported on Azure`},
+		{name: "subnet name", opts: options.EgressOptions{SubnetID: "workers"}, err: "must be given by its resource ID"},
+		{name: "several security groups", opts: options.EgressOptions{SubnetID: vnetID + "/subnets/workers", SecurityGroupIDs: []string{"a", "b"}},
+			err: "at most one network security group"},
+	}
+	for _, test := range tests {
+		cli := newFakeClient(t, fakeapi.New(t))
+		_, _, errs := cli.ValidateEgress(context.TODO(), test.opts).Parse()
+		if assert.Len(t, errs, 1, test.name) {
+			assert.Contains(t, errs[0].Error(), test.err, test.name)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+	cli, err := NewClient(context.TODO(), &ocmlog.StdLogger{}, ts, "sub", "East US", "", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "eastus", cli.region)
+		assert.Equal(t, defaultInstanceType, cli.instanceType)
+		assert.Equal(t, defaultARMEndpoint, cli.arm.endpoint)
+	}
+
+	for _, args := range []struct {
+		ts             oauth2.TokenSource
+		subscriptionID string
+	}{{nil, "sub"}, {ts, ""}} {
+		_, err := NewClient(context.TODO(), &ocmlog.StdLogger{}, args.ts, args.subscriptionID, "eastus", "", nil)
+		assert.Error(t, err, fmt.Sprintf("%v", args))
+	}
+}
@@ -0,0 +1,258 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+const (
+	// controlPlaneNodes is the number of control plane nodes of a cluster
+	controlPlaneNodes = 3
+	// capacityHeadroomFactor is the ratio between the usable and the needed addresses of a subnet under which
+	// its capacity is reported as a warning, as it leaves little room to scale the cluster
+	capacityHeadroomFactor = 2
+)
+
+// reservedSubnets are the names of the subnets dedicated to Azure services, which cannot host VMs
+var reservedSubnets = map[string]bool{
+	"gatewaysubnet":                 true,
+	"azurefirewallsubnet":           true,
+	"azurefirewallmanagementsubnet": true,
+	"azurebastionsubnet":            true,
+	"routeserversubnet":             true,
+}
+
+// byoVPCValidator verifies the documented BYOVPC requirements for the given subnets.
+// The first subnet hosts the control plane, the others the compute nodes.
+// Basic workflow is:
+// - ask the ARM API for the subnets and their virtual network
+// - ensure the virtual network is in the region, and every subnet belongs to it and can host VMs
+// - ensure a control plane and a compute subnet are given, with enough addresses for their nodes and the load balancers
+// - ensure the route table and NAT gateway of every subnet give it egress to the internet
+// - evaluate the network security group of every subnet against the egress endpoints
+// - return `c.output` holding a finding for every requirement
+func (c *Client) byoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output {
+	c.logger.Info(ctx, "Verifying BYOVPC configuration for subnets %v", opts.SubnetIDs)
+	var subnets []*subnet
+	for _, id := range opts.SubnetIDs {
+		var s subnet
+		if err := c.arm.get(ctx, id, networkAPIVersion, &s); err != nil {
+			if class, _ := classifyError(err); class == errorClassNotFound {
+				c.output.AddFinding(output.Finding{Check: "subnet", Resource: id, Status: output.StatusFailed,
+					Message: "subnet not found"})
+			} else {
+				c.addUnknownFinding("subnet", id, "get the subnet", err)
+			}
+			continue
+		}
+		subnets = append(subnets, &s)
+	}
+	if len(subnets) == 0 {
+		return c.output.AddError(fmt.Errorf("none of the subnets %v were found", opts.SubnetIDs)) // fatal
+	}
+
+	vnetID := opts.VpcID
+	if vnetID == "" {
+		vnetID = parentID(subnets[0].ID)
+		c.logger.Debug(ctx, "No virtual network given, using virtual network %s of subnet %s", vnetID, subnets[0].Name)
+	}
+	var vnet virtualNetwork
+	if err := c.arm.get(ctx, vnetID, networkAPIVersion, &vnet); err != nil {
+		return c.output.AddError(fmt.Errorf("unable to get virtual network %s: %w", vnetID, err)) // fatal
+	}
+
+	c.checkNetworkLocation(&vnet)
+	for _, s := range subnets {
+		c.checkSubnetPlacement(s, &vnet, sameID(s.ID, opts.SubnetIDs[0]))
+	}
+	c.checkSubnetCapacity(opts.SubnetIDs, subnets, opts)
+
+	var eps []endpoints.Endpoint
+	var addresses map[string][]net.IP
+	for _, s := range subnets {
+		c.checkSubnetEgress(ctx, s)
+		if s.Properties.NetworkSecurityGroup == nil {
+			c.output.AddFinding(output.Finding{Check: "nsg", Resource: s.Name, Status: output.StatusPassed,
+				Message: "no network security group is associated with the subnet, egress is not filtered"})
+			continue
+		}
+		if eps == nil {
+			var err error
+			if eps, err = c.defaultEndpoints(); err != nil {
+				return c.output.AddError(err) // fatal
+			}
+			addresses = endpoints.ResolveIPv4(ctx, c.logger, &c.output, "nsg", eps, lookupIP)
+		}
+		c.checkSubnetSecurityGroup(ctx, s, eps, addresses)
+	}
+
+	return &c.output
+}
+
+// checkNetworkLocation ensures the virtual network is in the region of the cluster, VMs can only use networks of their region
+func (c *Client) checkNetworkLocation(vnet *virtualNetwork) {
+	f := output.Finding{Check: "network-location", Resource: vnet.Name, Status: output.StatusPassed,
+		Message: fmt.Sprintf("virtual network %s in region %s", strings.Join(vnet.Properties.AddressSpace.AddressPrefixes, ", "), vnet.Location)}
+	if !strings.EqualFold(vnet.Location, c.region) {
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("virtual network is in region %s, not %s", vnet.Location, c.region)
+	}
+	c.output.AddFinding(f)
+}
+
+// checkSubnetPlacement ensures the subnet belongs to the virtual network and can host VMs.
+// The control plane subnet hosts the private link service of private clusters, which needs its network policies disabled.
+func (c *Client) checkSubnetPlacement(s *subnet, vnet *virtualNetwork, controlPlane bool) {
+	f := output.Finding{Check: "subnet", Resource: s.Name, Status: output.StatusPassed,
+		Message: fmt.Sprintf("subnet %s in virtual network %s", subnetPrefix(s), vnet.Name)}
+	switch {
+	case !sameID(parentID(s.ID), vnet.ID):
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("subnet belongs to virtual network %s, not %s", resourceName(parentID(s.ID)), vnet.Name)
+	case reservedSubnets[strings.ToLower(s.Name)]:
+		f.Status = output.StatusFailed
+		f.Message = "subnet is reserved for the Azure service of the same name, it cannot host the cluster's nodes"
+	case len(s.Properties.Delegations) > 0:
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("subnet is delegated to %s, it cannot host the cluster's nodes", s.Properties.Delegations[0].Properties.ServiceName)
+	case controlPlane && !strings.EqualFold(s.Properties.PrivateLinkServiceNetworkPolicies, "Disabled"):
+		f.Status = output.StatusWarning
+		f.Message = "private link service network policies are enabled on the control plane subnet, they must be disabled for private clusters"
+	}
+	c.output.AddFinding(f)
+}
+
+// subnetPrefix returns the address prefix of the subnet, subnets may have several
+func subnetPrefix(s *subnet) string {
+	if s.Properties.AddressPrefix != "" {
+		return s.Properties.AddressPrefix
+	}
+	if len(s.Properties.AddressPrefixes) > 0 {
+		return s.Properties.AddressPrefixes[0]
+	}
+
+	return ""
+}
+
+// checkSubnetCapacity ensures a control plane and a compute subnet are given, and that each of them has enough
+// addresses for its nodes and the load balancers. Compute nodes are spread across the compute subnets.
+func (c *Client) checkSubnetCapacity(ids []string, subnets []*subnet, opts options.ByoVPCOptions) {
+	if len(ids) < 2 {
+		c.output.AddFinding(output.Finding{Check: "subnet-capacity", Resource: strings.Join(ids, ","), Status: output.StatusFailed,
+			Message: "a cluster on Azure needs a control plane and a compute subnet"})
+		return
+	}
+	computeSubnets := 0
+	for _, s := range subnets {
+		if !sameID(s.ID, ids[0]) {
+			computeSubnets++
+		}
+	}
+	nodesPerSubnet := 0
+	if computeSubnets > 0 {
+		nodesPerSubnet = (opts.ExpectedNodes + computeSubnets - 1) / computeSubnets
+	}
+
+	for _, s := range subnets {
+		nodes, role := nodesPerSubnet, "compute"
+		if sameID(s.ID, ids[0]) {
+			nodes, role = controlPlaneNodes, "control plane"
+		}
+		needed := nodes + opts.LoadBalancerIPs
+		usage := fmt.Sprintf("%d %s nodes and load balancers", nodes, role)
+
+		_, cidr, err := net.ParseCIDR(subnetPrefix(s))
+		if err != nil {
+			c.output.AddFinding(output.Finding{Check: "subnet-capacity", Resource: s.Name, Status: output.StatusUnknown,
+				Message: fmt.Sprintf("unable to parse the subnet prefix %q", subnetPrefix(s))})
+			continue
+		}
+		usable := subnetUsableAddresses(cidr)
+		f := output.Finding{Check: "subnet-capacity", Resource: s.Name, Status: output.StatusPassed,
+			Message: fmt.Sprintf("%d usable addresses, %d needed for %s", usable, needed, usage)}
+		switch {
+		case usable < needed:
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("subnet prefix %s only has %d usable addresses, %d are needed for %s", cidr, usable, needed, usage)
+		case usable < needed*capacityHeadroomFactor:
+			f.Status = output.StatusWarning
+			f.Message = fmt.Sprintf("%d usable addresses, %d needed for %s, leaving little room to scale", usable, needed, usage)
+		}
+		c.output.AddFinding(f)
+	}
+}
+
+// subnetUsableAddresses returns the number of addresses of an IPv4 subnet, minus the 5 Azure reserves
+func subnetUsableAddresses(cidr *net.IPNet) int {
+	ones, bits := cidr.Mask.Size()
+	if bits-ones >= 31 {
+		return math.MaxInt32
+	}
+
+	return 1<<(bits-ones) - 5
+}
+
+// checkSubnetEgress ensures the subnet reaches the internet
+// Basic workflow is:
+// - ask the ARM API for the route table of the subnet, if any, and pick its default route
+// - without one, the system default route sends egress to the internet
+// - report where the default route sends egress, and whether a NAT gateway, the load balancer or nothing translates it
+func (c *Client) checkSubnetEgress(ctx context.Context, s *subnet) {
+	defaultRoute := &effectiveRoute{Source: "Default", NextHopType: "Internet"}
+	if s.Properties.RouteTable != nil {
+		var table routeTable
+		if err := c.arm.get(ctx, s.Properties.RouteTable.ID, networkAPIVersion, &table); err != nil {
+			c.addUnknownFinding("outbound", s.Name, "get the route table", err)
+			return
+		}
+		for _, r := range table.Properties.Routes {
+			if r.Properties.AddressPrefix == "0.0.0.0/0" {
+				defaultRoute = &effectiveRoute{Source: "User", Name: r.Name, NextHopType: r.Properties.NextHopType}
+				if r.Properties.NextHopIPAddress != "" {
+					defaultRoute.NextHopIPAddress = []string{r.Properties.NextHopIPAddress}
+				}
+			}
+		}
+	}
+
+	f := defaultRouteFinding("outbound", s.Name, defaultRoute)
+	if !strings.EqualFold(defaultRoute.NextHopType, "Internet") {
+		c.output.AddFinding(f)
+		return
+	}
+	switch {
+	case s.Properties.NatGateway != nil:
+		f.Message += fmt.Sprintf(" through NAT gateway %s", resourceName(s.Properties.NatGateway.ID))
+	case s.Properties.DefaultOutboundAccess != nil && !*s.Properties.DefaultOutboundAccess:
+		f.Status = output.StatusWarning
+		f.Message += ", but the subnet has no NAT gateway and default outbound access is disabled, " +
+			"only the outbound rules of the cluster's public load balancer give it egress"
+	default:
+		f.Message += " through the outbound rules of the cluster's load balancer or default outbound access"
+	}
+	c.output.AddFinding(f)
+}
+
+// checkSubnetSecurityGroup evaluates the network security group of the subnet against the egress endpoints.
+// Service tags other than Internet are not expanded outside of the effective rules of a running VM.
+func (c *Client) checkSubnetSecurityGroup(ctx context.Context, s *subnet, eps []endpoints.Endpoint, addresses map[string][]net.IP) {
+	var nsg networkSecurityGroup
+	if err := c.arm.get(ctx, s.Properties.NetworkSecurityGroup.ID, networkAPIVersion, &nsg); err != nil {
+		c.addUnknownFinding("nsg", s.Name, "get the network security group", err)
+		return
+	}
+	var rules []securityRule
+	for _, r := range append(nsg.Properties.SecurityRules, nsg.Properties.DefaultSecurityRules...) {
+		rule := r.Properties
+		rule.Name = r.Name
+		rules = append(rules, rule)
+	}
+	c.addSecurityRuleFindings("nsg", nsg.Name, rules, eps, addresses)
+}
@@ -0,0 +1,124 @@
+package azure
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByoVPCValidator(t *testing.T) {
+	defer func(lookup func(string) ([]net.IP, error)) { lookupIP = lookup }(lookupIP)
+	lookupIP = fakeLookupIP
+
+	const (
+		routeTableID = rgPath + "/providers/Microsoft.Network/routeTables/firewall"
+		nsgID        = rgPath + "/providers/Microsoft.Network/networkSecurityGroups/workers-nsg"
+	)
+	api := fakeapi.New(t).
+		On("GET", vnetID+"/subnets/master", fakeapi.Response{Body: fakeapi.JSON(t, `{"id": "`+vnetID+`/subnets/master", "name": "master", "properties": {
+			"addressPrefix": "10.0.0.0/27", "privateLinkServiceNetworkPolicies": "Enabled",
+			"natGateway": {"id": "`+rgPath+`/providers/Microsoft.Network/natGateways/nat"}}}`)}).
+		On("GET", vnetID+"/subnets/workers", fakeapi.Response{Body: fakeapi.JSON(t, `{"id": "`+vnetID+`/subnets/workers", "name": "workers", "properties": {
+			"addressPrefix": "10.0.2.0/23", "routeTable": {"id": "`+routeTableID+`"}, "networkSecurityGroup": {"id": "`+nsgID+`"}}}`)}).
+		On("GET", vnetID+"/subnets/appgw", fakeapi.Response{Body: fakeapi.JSON(t, `{"id": "`+vnetID+`/subnets/appgw", "name": "appgw", "properties": {
+			"addressPrefix": "10.0.4.0/24", "defaultOutboundAccess": false,
+			"delegations": [{"name": "delegation", "properties": {"serviceName": "Microsoft.Web/serverFarms"}}]}}`)}).
+		On("GET", vnetID+"/subnets/missing", armFailure(http.StatusNotFound, "NotFound", "resource not found")).
+		On("GET", vnetID, fakeapi.Response{Body: fakeapi.JSON(t, `{"id": "`+vnetID+`", "name": "vnet", "location": "eastus",
+			"properties": {"addressSpace": {"addressPrefixes": ["10.0.0.0/16"]}}}`)}).
+		On("GET", routeTableID, fakeapi.Response{Body: fakeapi.JSON(t, `{"name": "firewall", "properties": {"routes": [
+			{"name": "local", "properties": {"addressPrefix": "10.0.0.0/16", "nextHopType": "VnetLocal"}},
+			{"name": "default", "properties": {"addressPrefix": "0.0.0.0/0", "nextHopType": "None"}}]}}`)}).
+		On("GET", nsgID, fakeapi.Response{Body: fakeapi.JSON(t, `{"name": "workers-nsg", "properties": {
+			"securityRules": [
+				{"name": "allow-azure", "properties": {"protocol": "Tcp", "destinationAddressPrefix": "AzureCloud", "destinationPortRange": "443",
+				 "access": "Allow", "priority": 100, "direction": "Outbound"}},
+				{"name": "deny-internet", "properties": {"protocol": "*", "destinationAddressPrefix": "Internet", "destinationPortRange": "*",
+				 "access": "Deny", "priority": 4096, "direction": "Outbound"}}
+			],
+			"defaultSecurityRules": [
+				{"name": "AllowInternetOutBound", "properties": {"protocol": "*", "destinationAddressPrefix": "Internet", "destinationPortRange": "*",
+				 "access": "Allow", "priority": 65001, "direction": "Outbound"}}
+			]}}`)})
+	cli := newFakeClient(t, api)
+
+	out := cli.ByoVPCValidator(context.TODO(), options.ByoVPCOptions{
+		SubnetIDs: []string{vnetID + "/subnets/master", vnetID + "/subnets/workers", vnetID + "/subnets/appgw", vnetID + "/subnets/missing"},
+	})
+
+	_, _, errs := out.Parse()
+	assert.Empty(t, errs)
+	byCheck := map[string][]output.Finding{}
+	for _, f := range out.Findings() {
+		byCheck[f.Check] = append(byCheck[f.Check], f)
+	}
+	assert.Equal(t, []output.Finding{
+		{Check: "subnet", Resource: vnetID + "/subnets/missing", Status: output.StatusFailed, Message: "subnet not found"},
+		{Check: "subnet", Resource: "master", Status: output.StatusWarning,
+			Message: "private link service network policies are enabled on the control plane subnet, they must be disabled for private clusters"},
+		{Check: "subnet", Resource: "workers", Status: output.StatusPassed, Message: "subnet 10.0.2.0/23 in virtual network vnet"},
+		{Check: "subnet", Resource: "appgw", Status: output.StatusFailed,
+			Message: "subnet is delegated to Microsoft.Web/serverFarms, it cannot host the cluster's nodes"},
+	}, byCheck["subnet"])
+	assert.Equal(t, []output.Finding{
+		{Check: "network-location", Resource: "vnet", Status: output.StatusPassed, Message: "virtual network 10.0.0.0/16 in region eastus"},
+	}, byCheck["network-location"])
+	assert.Equal(t, []output.Finding{
+		{Check: "subnet-capacity", Resource: "master", Status: output.StatusPassed, Message: "27 usable addresses, 11 needed for 3 control plane nodes and load balancers"},
+		{Check: "subnet-capacity", Resource: "workers", Status: output.StatusPassed, Message: "507 usable addresses, 13 needed for 5 compute nodes and load balancers"},
+		{Check: "subnet-capacity", Resource: "appgw", Status: output.StatusPassed, Message: "251 usable addresses, 13 needed for 5 compute nodes and load balancers"},
+	}, byCheck["subnet-capacity"])
+	assert.Equal(t, []output.Finding{
+		{Check: "outbound", Resource: "master", Status: output.StatusPassed,
+			Message: "the system default route sends egress to the internet through NAT gateway natGateways/nat"},
+		{Check: "outbound", Resource: "workers", Status: output.StatusFailed,
+			Message: "the user-defined default route drops egress (next hop None), the cluster cannot reach the internet"},
+		{Check: "outbound", Resource: "appgw", Status: output.StatusWarning,
+			Message: "the system default route sends egress to the internet, but the subnet has no NAT gateway and default outbound access is disabled, " +
+				"only the outbound rules of the cluster's public load balancer give it egress"},
+	}, byCheck["outbound"])
+
+	nsg := byCheck["nsg"]
+	if assert.NotEmpty(t, nsg) {
+		assert.Equal(t, output.Finding{Check: "nsg", Resource: "master", Status: output.StatusPassed,
+			Message: "no network security group is associated with the subnet, egress is not filtered"}, nsg[0])
+		assert.Equal(t, output.Finding{Check: "nsg", Resource: "workers-nsg", Status: output.StatusWarning,
+			Message: "egress to registry.redhat.io (203.0.113.10) on port 443 is denied by rule deny-internet, unless rule allow-azure allows it through a service tag"}, nsg[1])
+		for _, f := range nsg[2:] {
+			if f.Resource == "workers-nsg" && f.Message == "egress to sso.redhat.com (203.0.113.10) on port 80 is denied by rule deny-internet" {
+				assert.Equal(t, output.StatusFailed, f.Status)
+				return
+			}
+		}
+		t.Errorf("port 80 is not reported as denied: %v", nsg)
+	}
+}
+
+func TestByoVPCValidatorMissingSubnets(t *testing.T) {
+	api := fakeapi.New(t).
+		On("GET", vnetID+"/subnets/*", armFailure(http.StatusForbidden, "AuthorizationFailed", "no read permission"))
+	cli := newFakeClient(t, api)
+
+	out := cli.ByoVPCValidator(context.TODO(), options.ByoVPCOptions{SubnetIDs: []string{vnetID + "/subnets/master", vnetID + "/subnets/workers"}})
+
+	_, _, errs := out.Parse()
+	// one per subnet, and the fatal one
+	if assert.Len(t, errs, 3) {
+		assert.Contains(t, errs[2].Error(), "none of the subnets")
+	}
+	assert.Equal(t, output.Finding{Check: "subnet", Resource: vnetID + "/subnets/master", Status: output.StatusUnknown,
+		Message: "unable to get the subnet: access denied (AuthorizationFailed)"}, out.Findings()[0])
+}
+
+func TestSubnetUsableAddresses(t *testing.T) {
+	for cidr, expected := range map[string]int{"10.0.0.0/29": 3, "10.0.0.0/27": 27, "10.0.0.0/16": 65531} {
+		_, n, _ := net.ParseCIDR(cidr)
+		assert.Equal(t, expected, subnetUsableAddresses(n), cidr)
+	}
+}
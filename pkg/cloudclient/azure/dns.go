@@ -0,0 +1,177 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// azureDNS is the address of the Azure-provided DNS, which resolves the private DNS zones linked to the network
+const azureDNS = "168.63.129.16"
+
+// ownedDomains are the domains of the Azure APIs and of the OpenShift services the cluster depends on,
+// a private DNS zone for any of their names hijacks them
+var ownedDomains = []string{
+	"azure.com",
+	"microsoftonline.com",
+	"azurecr.io",
+	"windows.net",
+	"openshift.com",
+	"openshiftapps.com",
+	"redhat.com",
+	"redhat.io",
+	"quay.io",
+}
+
+// nodeDomain holds the internal names of the VMs, which must be resolved by the Azure-provided DNS
+const nodeDomain = "internal.cloudapp.net"
+
+// hijackedNames returns the Azure, OpenShift and node names answered by the zone instead of their authoritative servers:
+// the owned domains it covers or lies within, and the node domain it covers.
+// Private Link zones, e.g. privatelink.azurecr.io, only answer for the names public DNS aliases to them.
+func hijackedNames(domain string) []string {
+	if strings.HasPrefix(domain, "privatelink.") {
+		return nil
+	}
+	var hijacked []string
+	for _, owned := range append(ownedDomains, nodeDomain) {
+		switch {
+		case helpers.DomainCovers(domain, owned):
+			hijacked = append(hijacked, owned)
+		case helpers.DomainCovers(owned, domain) && owned != nodeDomain:
+			hijacked = append(hijacked, domain)
+		}
+	}
+
+	return hijacked
+}
+
+// verifyDns performs verification process for the virtual network's DNS
+// Basic workflow is:
+// - ask the ARM API for the virtual network, and warn when custom DNS servers replace the Azure-provided DNS
+// - ask the ARM API for the private DNS zones of the subscription, and keep the ones linked to the virtual network
+// - fail when they hijack the Azure APIs, the OpenShift domains or the node names, warn when they shadow the egress endpoints
+func (c *Client) verifyDns(ctx context.Context, opts options.DnsOptions) *output.Output {
+	c.logger.Info(ctx, "Verifying DNS config for virtual network %s", opts.VpcID)
+	var vnet virtualNetwork
+	if err := c.arm.get(ctx, opts.VpcID, networkAPIVersion, &vnet); err != nil {
+		return c.output.AddError(fmt.Errorf("unable to get virtual network %s: %w", opts.VpcID, err)) // fatal
+	}
+
+	eps, err := c.defaultEndpoints()
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	c.verifyDnsServers(&vnet)
+	c.verifyPrivateZones(ctx, &vnet, endpoints.Hosts(eps))
+
+	return &c.output
+}
+
+// verifyDnsServers warns when the virtual network uses custom DNS servers, which must resolve public names,
+// the node names and the linked private DNS zones by forwarding to the Azure-provided DNS
+func (c *Client) verifyDnsServers(vnet *virtualNetwork) {
+	if vnet.Properties.DhcpOptions == nil || len(vnet.Properties.DhcpOptions.DnsServers) == 0 {
+		c.output.AddFinding(output.Finding{Check: "dns-servers", Resource: vnet.Name, Status: output.StatusPassed,
+			Message: "the virtual network uses the Azure-provided DNS"})
+		return
+	}
+	servers := vnet.Properties.DhcpOptions.DnsServers
+	if len(servers) == 1 && servers[0] == azureDNS {
+		c.output.AddFinding(output.Finding{Check: "dns-servers", Resource: vnet.Name, Status: output.StatusPassed,
+			Message: fmt.Sprintf("the virtual network uses the Azure-provided DNS %s", azureDNS)})
+		return
+	}
+	c.output.AddFinding(output.Finding{Check: "dns-servers", Resource: vnet.Name, Status: output.StatusWarning,
+		Message: fmt.Sprintf("the virtual network uses the custom DNS servers %s, they must resolve public names and the node names, "+
+			"and forward to %s for the private DNS zones linked to the network", strings.Join(servers, ", "), azureDNS)})
+}
+
+// verifyPrivateZones verifies the private DNS zones linked to the virtual network
+// Basic workflow is:
+// - ask the ARM API for the private DNS zones of the client's subscription, and the virtual network links of each of them
+// - keep the zones linked to the virtual network, and warn about the links which are not provisioned yet
+// - fail when a zone hijacks the Azure APIs, the OpenShift domains or the node names
+// - warn when a zone shadows egress endpoints, which then only resolve if the zone has records for them
+// - otherwise warn that the zones of other subscriptions, e.g. a hub subscription, were not checked
+func (c *Client) verifyPrivateZones(ctx context.Context, vnet *virtualNetwork, hosts []string) {
+	var zones []privateDnsZone
+	zonesID := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Network/privateDnsZones", c.subscriptionID)
+	err := c.arm.list(ctx, zonesID, privateDnsAPIVersion, func(value json.RawMessage) error {
+		var page []privateDnsZone
+		if err := json.Unmarshal(value, &page); err != nil {
+			return err
+		}
+		zones = append(zones, page...)
+		return nil
+	})
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-private-zone", vnet.Name, "list the private DNS zones", err)
+		return
+	}
+
+	linked, flagged := 0, false
+	for _, zone := range zones {
+		link, err := c.virtualNetworkLink(ctx, zone, vnet)
+		if err != nil {
+			c.addUncheckedFinding(ctx, "dns-private-zone", zone.Name, "list the virtual network links", err)
+			flagged = true
+			continue
+		}
+		if link == nil {
+			continue
+		}
+		linked++
+
+		if !strings.EqualFold(link.Properties.VirtualNetworkLinkState, "Completed") {
+			c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone.Name, Status: output.StatusWarning,
+				Message: fmt.Sprintf("link %s to the virtual network is %s, the zone may not resolve yet", link.Name, link.Properties.VirtualNetworkLinkState)})
+			flagged = true
+		}
+		name := helpers.NormalizeDomain(zone.Name)
+		hijacked := hijackedNames(name)
+		shadowed := helpers.WithoutNames(helpers.CoveredNames(name, hosts), hijacked)
+		switch {
+		case len(hijacked) > 0:
+			c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone.Name, Status: output.StatusFailed,
+				Message: fmt.Sprintf("private DNS zone %s hijacks %s, names missing from the zone do not resolve in the network", name, strings.Join(hijacked, ", "))})
+			flagged = true
+		case len(shadowed) > 0 && !strings.HasPrefix(name, "privatelink."):
+			c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone.Name, Status: output.StatusWarning,
+				Message: fmt.Sprintf("private DNS zone %s shadows the egress endpoints %s, they only resolve if the zone has records for them", name, strings.Join(shadowed, ", "))})
+			flagged = true
+		}
+	}
+
+	if !flagged {
+		// zones are often kept in a hub subscription, their links to the network can't be listed from this one
+		c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: vnet.Name, Status: output.StatusWarning,
+			Message: fmt.Sprintf("none of the %d private DNS zones of subscription %s linked to the network shadows the Azure APIs, "+
+				"the OpenShift domains or the egress endpoints, zones of other subscriptions were not checked", linked, c.subscriptionID)})
+	}
+}
+
+// virtualNetworkLink returns the link of the zone to the virtual network, if any
+func (c *Client) virtualNetworkLink(ctx context.Context, zone privateDnsZone, vnet *virtualNetwork) (*virtualNetworkLink, error) {
+	var link *virtualNetworkLink
+	err := c.arm.list(ctx, zone.ID+"/virtualNetworkLinks", privateDnsAPIVersion, func(value json.RawMessage) error {
+		var page []virtualNetworkLink
+		if err := json.Unmarshal(value, &page); err != nil {
+			return err
+		}
+		for i, l := range page {
+			if l.Properties.VirtualNetwork != nil && sameID(l.Properties.VirtualNetwork.ID, vnet.ID) {
+				link = &page[i]
+			}
+		}
+		return nil
+	})
+
+	return link, err
+}
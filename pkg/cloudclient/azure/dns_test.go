@@ -0,0 +1,103 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+const zonesPath = "/subscriptions/sub/providers/Microsoft.Network/privateDnsZones"
+
+// zone returns a private DNS zone of the resource group rg, named after its domain
+func zone(domain string) privateDnsZone {
+	return privateDnsZone{ID: rgPath + "/providers/Microsoft.Network/privateDnsZones/" + domain, Name: domain}
+}
+
+// links returns the virtual network links of a zone to the given networks, in the given state
+func links(state string, networks ...string) fakeapi.Response {
+	var value []map[string]interface{}
+	for i, network := range networks {
+		value = append(value, map[string]interface{}{
+			"name": "link" + string(rune('a'+i)),
+			"properties": map[string]interface{}{
+				"virtualNetwork":          map[string]string{"id": network},
+				"virtualNetworkLinkState": state,
+			},
+		})
+	}
+	return fakeapi.Response{Body: map[string]interface{}{"value": value}}
+}
+
+func TestVerifyDns(t *testing.T) {
+	const otherVnetID = rgPath + "/providers/Microsoft.Network/virtualNetworks/other"
+	zones := []privateDnsZone{
+		zone("privatelink.azurecr.io"),
+		zone("azurecr.io"),
+		zone("corp.example.com"),
+		zone("redhat.com"),
+		zone("microsoftonline.com"),
+		zone("openshift.org"),
+	}
+	api := fakeapi.New(t).
+		On("GET", vnetID, fakeapi.Response{Body: fakeapi.JSON(t, `{"id": "`+vnetID+`", "name": "vnet", "location": "eastus",
+			"properties": {"dhcpOptions": {"dnsServers": ["10.0.0.4", "10.0.0.5"]}}}`)}).
+		On("GET", zonesPath, fakeapi.Response{Body: map[string]interface{}{"value": zones}}).
+		On("GET", zones[0].ID+"/virtualNetworkLinks", links("Completed", vnetID)).
+		On("GET", zones[1].ID+"/virtualNetworkLinks", links("Completed", otherVnetID, vnetID)).
+		On("GET", zones[2].ID+"/virtualNetworkLinks", links("InProgress", vnetID)).
+		On("GET", zones[3].ID+"/virtualNetworkLinks", links("Completed", otherVnetID)).
+		On("GET", zones[4].ID+"/virtualNetworkLinks", armFailure(http.StatusForbidden, "AuthorizationFailed", "no read permission")).
+		On("GET", zones[5].ID+"/virtualNetworkLinks", links("Completed", "/subscriptions/SUB/resourceGroups/RG/providers/Microsoft.Network/virtualNetworks/VNET"))
+	cli := newFakeClient(t, api)
+
+	out := cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: vnetID})
+
+	// a missing permission on a zone leaves it unchecked without failing the verification
+	_, _, errs := out.Parse()
+	assert.Empty(t, errs)
+	assert.Equal(t, []output.Finding{
+		{Check: "dns-servers", Resource: "vnet", Status: output.StatusWarning,
+			Message: "the virtual network uses the custom DNS servers 10.0.0.4, 10.0.0.5, they must resolve public names and the node names, " +
+				"and forward to 168.63.129.16 for the private DNS zones linked to the network"},
+		{Check: "dns-private-zone", Resource: "azurecr.io", Status: output.StatusFailed,
+			Message: "private DNS zone azurecr.io hijacks azurecr.io, names missing from the zone do not resolve in the network"},
+		{Check: "dns-private-zone", Resource: "corp.example.com", Status: output.StatusWarning,
+			Message: "link linka to the virtual network is InProgress, the zone may not resolve yet"},
+		{Check: "dns-private-zone", Resource: "microsoftonline.com", Status: output.StatusUnknown,
+			Message: "unable to list the virtual network links: access denied (AuthorizationFailed)"},
+		{Check: "dns-private-zone", Resource: "openshift.org", Status: output.StatusWarning,
+			Message: "private DNS zone openshift.org shadows the egress endpoints openshift.org, they only resolve if the zone has records for them"},
+	}, out.Findings())
+}
+
+func TestVerifyDnsDefaultConfiguration(t *testing.T) {
+	api := fakeapi.New(t).
+		On("GET", vnetID, fakeapi.Response{Body: fakeapi.JSON(t, `{"id": "`+vnetID+`", "name": "vnet", "location": "eastus", "properties": {}}`)}).
+		On("GET", zonesPath, fakeapi.Response{Body: map[string]interface{}{"value": []privateDnsZone{zone("privatelink.blob.core.windows.net")}}}).
+		On("GET", zone("privatelink.blob.core.windows.net").ID+"/virtualNetworkLinks", links("Completed", vnetID))
+	cli := newFakeClient(t, api)
+
+	out := cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: vnetID})
+
+	assert.True(t, out.IsSuccessful())
+	assert.Equal(t, []output.Finding{
+		{Check: "dns-servers", Resource: "vnet", Status: output.StatusPassed, Message: "the virtual network uses the Azure-provided DNS"},
+		{Check: "dns-private-zone", Resource: "vnet", Status: output.StatusWarning,
+			Message: "none of the 1 private DNS zones of subscription sub linked to the network shadows the Azure APIs, " +
+				"the OpenShift domains or the egress endpoints, zones of other subscriptions were not checked"},
+	}, out.Findings())
+}
+
+func TestHijackedNames(t *testing.T) {
+	assert.Equal(t, []string{"azure.com", "microsoftonline.com", "azurecr.io", "windows.net", "openshift.com", "openshiftapps.com",
+		"redhat.com", "redhat.io", "quay.io", "internal.cloudapp.net"}, hijackedNames(""))
+	assert.Equal(t, []string{"management.azure.com"}, hijackedNames("management.azure.com"))
+	assert.Equal(t, []string{"internal.cloudapp.net"}, hijackedNames("cloudapp.net"))
+	assert.Empty(t, hijackedNames("privatelink.azurecr.io"))
+	assert.Empty(t, hijackedNames("corp.example.com"))
+}
@@ -0,0 +1,57 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// errorClass tells apart the ARM API errors a user can act on: a wrong ID, a missing permission or too many calls
+type errorClass string
+
+const (
+	errorClassNotFound     errorClass = "not found"
+	errorClassAccessDenied errorClass = "access denied"
+	errorClassThrottled    errorClass = "throttled"
+	errorClassUnknown      errorClass = "unclassified error"
+)
+
+// classifyError returns the class and the code of an error returned by the ARM API
+func classifyError(err error) (errorClass, string) {
+	var apiErr *armError
+	if !errors.As(err, &apiErr) {
+		return errorClassUnknown, ""
+	}
+
+	// e.g. ResourceNotFound, AuthorizationFailed, TooManyRequests
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return errorClassNotFound, apiErr.Code
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errorClassAccessDenied, apiErr.Code
+	case http.StatusTooManyRequests:
+		return errorClassThrottled, apiErr.Code
+	}
+
+	return errorClassUnknown, apiErr.Code
+}
+
+// addUnknownFinding reports a requirement that could not be verified because of an ARM API error,
+// and returns the class of the error
+func (c *Client) addUnknownFinding(check, resource, action string, err error) errorClass {
+	class, code := classifyError(err)
+	c.output.AddUnknownFinding(check, resource, action, string(class), code)
+	c.output.AddError(fmt.Errorf("unable to %s of %s: %w", action, resource, err))
+
+	return class
+}
+
+// addUncheckedFinding reports a check beyond the requirements, e.g. the effective routes of the probe, that could not be
+// run because of an ARM API error, usually a missing permission. Unlike addUnknownFinding, the error is only logged and
+// doesn't fail the verification.
+func (c *Client) addUncheckedFinding(ctx context.Context, check, resource, action string, err error) {
+	class, code := classifyError(err)
+	c.output.AddUnknownFinding(check, resource, action, string(class), code)
+	c.logger.Debug(ctx, "Unable to %s of %s: %s", action, resource, err)
+}
@@ -0,0 +1,28 @@
+package azure
+
+import (
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+)
+
+// newFakeClient returns a client of the subscription "sub" in eastus, talking to the fake API
+func newFakeClient(t *testing.T, api *fakeapi.Server) *Client {
+	server := api.Start()
+
+	return &Client{
+		subscriptionID: "sub",
+		region:         "eastus",
+		instanceType:   defaultInstanceType,
+		arm:            &armClient{endpoint: server.URL, httpClient: server.Client()},
+		logger:         &ocmlog.StdLogger{},
+	}
+}
+
+// armFailure returns the body of an error returned by the ARM API
+func armFailure(status int, code, message string) fakeapi.Response {
+	return fakeapi.Response{Status: status, Body: map[string]interface{}{
+		"error": map[string]string{"code": code, "message": message},
+	}}
+}
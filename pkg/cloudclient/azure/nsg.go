@@ -0,0 +1,178 @@
+package azure
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// lookupIP resolves endpoint hosts, it can be replaced in tests
+var lookupIP = net.LookupIP
+
+// internetPrefixes are the destinations of the rules matching any public address
+var internetPrefixes = map[string]bool{"*": true, "0.0.0.0/0": true, "internet": true}
+
+// securityRule is a rule of a network security group, as found in the properties of the group's rules
+// and among the effective rules of a network interface
+type securityRule struct {
+	Name                       string   `json:"name"`
+	Protocol                   string   `json:"protocol"`
+	DestinationAddressPrefix   string   `json:"destinationAddressPrefix"`
+	DestinationAddressPrefixes []string `json:"destinationAddressPrefixes"`
+	// ExpandedDestinationAddressPrefix holds the ranges of the service tag of an effective rule
+	ExpandedDestinationAddressPrefix []string `json:"expandedDestinationAddressPrefix"`
+	DestinationPortRange             string   `json:"destinationPortRange"`
+	DestinationPortRanges            []string `json:"destinationPortRanges"`
+	Access                           string   `json:"access"`
+	Priority                         int      `json:"priority"`
+	Direction                        string   `json:"direction"`
+}
+
+// nsgDecision is the outcome of evaluating a flow against a network security group, and the rule deciding it
+type nsgDecision struct {
+	allowed bool
+	rule    string
+	// unresolved is an allow rule with a higher priority than the deciding one, whose service tag could not be expanded,
+	// which may match the flow
+	unresolved string
+}
+
+// evaluateSecurityRules returns the decision for outbound TCP traffic to the address and port.
+// Rules are evaluated by priority, lower values first, and the first matching rule decides. Flows matching
+// no rule are allowed, network security groups end with default rules allowing the internet and denying the rest.
+func evaluateSecurityRules(rules []securityRule, ip net.IP, port int) nsgDecision {
+	sorted := make([]securityRule, 0, len(rules))
+	for _, rule := range rules {
+		if strings.EqualFold(rule.Direction, "Outbound") && protocolMatches(rule.Protocol) && portMatches(rule, port) {
+			sorted = append(sorted, rule)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+
+	var unresolved string
+	for _, rule := range sorted {
+		matches, resolved := destinationMatches(rule, ip)
+		if !resolved && unresolved == "" && strings.EqualFold(rule.Access, "Allow") {
+			unresolved = rule.Name
+		}
+		if matches {
+			return nsgDecision{allowed: strings.EqualFold(rule.Access, "Allow"), rule: "rule " + rule.Name, unresolved: unresolved}
+		}
+	}
+
+	return nsgDecision{allowed: true, rule: "no rule", unresolved: unresolved}
+}
+
+// protocolMatches returns true if the rule applies to TCP
+func protocolMatches(protocol string) bool {
+	switch strings.ToLower(protocol) {
+	case "*", "all", "tcp":
+		return true
+	}
+
+	return false
+}
+
+// portMatches returns true if the port is within one of the destination port ranges of the rule, e.g. "*", "443" or "80-443"
+func portMatches(rule securityRule, port int) bool {
+	ranges := rule.DestinationPortRanges
+	if rule.DestinationPortRange != "" {
+		ranges = append(ranges, rule.DestinationPortRange)
+	}
+
+	return helpers.PortRangesContain(ranges, port)
+}
+
+// destinationMatches returns true if the address is within the destination of the rule. Resolved is false when
+// the destination holds a service tag other than Internet, whose ranges are only known for the effective rules.
+// VirtualNetwork and AzureLoadBalancer never match the public addresses of the endpoints.
+func destinationMatches(rule securityRule, ip net.IP) (matches, resolved bool) {
+	prefixes := append([]string{rule.DestinationAddressPrefix}, rule.DestinationAddressPrefixes...)
+	if len(rule.ExpandedDestinationAddressPrefix) > 0 {
+		// effective rules expand their service tags into ranges
+		for _, prefix := range prefixes {
+			if internetPrefixes[strings.ToLower(prefix)] {
+				return true, true
+			}
+		}
+		prefixes = rule.ExpandedDestinationAddressPrefix
+	}
+
+	resolved = true
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			continue
+		}
+		if internetPrefixes[strings.ToLower(prefix)] {
+			return true, true
+		}
+		if _, cidr, err := net.ParseCIDR(prefix); err == nil {
+			if cidr.Contains(ip) {
+				return true, true
+			}
+			continue
+		}
+		if address := net.ParseIP(prefix); address != nil {
+			if address.Equal(ip) {
+				return true, true
+			}
+			continue
+		}
+		switch strings.ToLower(prefix) {
+		case "virtualnetwork", "azureloadbalancer":
+		default:
+			resolved = false
+		}
+	}
+
+	return false, resolved
+}
+
+// addSecurityRuleFindings reports the endpoint flows the rules of a network security group deny, and the rules allowing the others.
+// Denied flows which a rule with an unexpanded service tag may allow are reported as warnings.
+func (c *Client) addSecurityRuleFindings(check, resource string, rules []securityRule, eps []endpoints.Endpoint, addresses map[string][]net.IP) {
+	allowedBy := map[string][]string{}
+	for _, e := range eps {
+		for _, port := range e.Ports {
+			var decision nsgDecision
+			// report the first denied address only, the others are usually denied by the same rule
+			for _, ip := range addresses[e.Host] {
+				decision = evaluateSecurityRules(rules, ip, port)
+				if decision.allowed {
+					continue
+				}
+				f := output.Finding{Check: check, Resource: resource, Status: output.StatusFailed,
+					Message: fmt.Sprintf("egress to %s (%s) on port %d is denied by %s", e.Host, ip, port, decision.rule)}
+				if decision.unresolved != "" {
+					f.Status = output.StatusWarning
+					f.Message += fmt.Sprintf(", unless rule %s allows it through a service tag", decision.unresolved)
+				}
+				c.output.AddFinding(f)
+				break
+			}
+			if decision.allowed {
+				allowedBy[decision.rule] = append(allowedBy[decision.rule], fmt.Sprintf("%s:%d", e.Host, port))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(allowedBy))
+	for rule := range allowedBy {
+		names = append(names, rule)
+	}
+	sort.Strings(names)
+	for _, rule := range names {
+		c.output.AddFinding(output.Finding{Check: check, Resource: resource, Status: output.StatusPassed,
+			Message: fmt.Sprintf("%s allows egress to %s", rule, strings.Join(allowedBy[rule], ", "))})
+	}
+}
+
+// defaultEndpoints returns the egress endpoints of the Azure profile for the client's region
+func (c *Client) defaultEndpoints() ([]endpoints.Endpoint, error) {
+	return endpoints.Default(PlatformName, map[string]string{"AZURE_REGION": c.region})
+}
@@ -0,0 +1,55 @@
+package azure
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateSecurityRules(t *testing.T) {
+	defaults := []securityRule{
+		{Name: "AllowInternetOutBound", Protocol: "*", DestinationAddressPrefix: "Internet", DestinationPortRange: "*", Access: "Allow", Priority: 65001, Direction: "Outbound"},
+		{Name: "DenyAllOutBound", Protocol: "*", DestinationAddressPrefix: "*", DestinationPortRange: "*", Access: "Deny", Priority: 65500, Direction: "Outbound"},
+	}
+	ip := net.ParseIP("203.0.113.10")
+	tests := []struct {
+		name     string
+		rules    []securityRule
+		port     int
+		expected nsgDecision
+	}{
+		{name: "default rules", rules: defaults, port: 443,
+			expected: nsgDecision{allowed: true, rule: "rule AllowInternetOutBound"}},
+		{name: "no rule", port: 443,
+			expected: nsgDecision{allowed: true, rule: "no rule"}},
+		{name: "deny by prefix", port: 443, rules: append([]securityRule{
+			{Name: "deny-range", Protocol: "Tcp", DestinationAddressPrefixes: []string{"198.51.100.0/24", "203.0.113.0/24"}, DestinationPortRanges: []string{"80", "443"},
+				Access: "Deny", Priority: 200, Direction: "Outbound"},
+		}, defaults...), expected: nsgDecision{allowed: false, rule: "rule deny-range"}},
+		{name: "other port", port: 9997, rules: append([]securityRule{
+			{Name: "deny-web", Protocol: "Tcp", DestinationAddressPrefix: "*", DestinationPortRange: "80-443", Access: "Deny", Priority: 200, Direction: "Outbound"},
+		}, defaults...), expected: nsgDecision{allowed: true, rule: "rule AllowInternetOutBound"}},
+		{name: "udp and inbound rules are ignored", port: 443, rules: append([]securityRule{
+			{Name: "deny-udp", Protocol: "Udp", DestinationAddressPrefix: "*", DestinationPortRange: "*", Access: "Deny", Priority: 100, Direction: "Outbound"},
+			{Name: "deny-in", Protocol: "Tcp", DestinationAddressPrefix: "*", DestinationPortRange: "*", Access: "Deny", Priority: 100, Direction: "Inbound"},
+		}, defaults...), expected: nsgDecision{allowed: true, rule: "rule AllowInternetOutBound"}},
+		{name: "lower priority value wins", port: 443, rules: append([]securityRule{
+			{Name: "deny-internet", Protocol: "*", DestinationAddressPrefix: "Internet", DestinationPortRange: "*", Access: "Deny", Priority: 4000, Direction: "Outbound"},
+			{Name: "allow-host", Protocol: "Tcp", DestinationAddressPrefix: "203.0.113.10", DestinationPortRange: "443", Access: "Allow", Priority: 300, Direction: "Outbound"},
+		}, defaults...), expected: nsgDecision{allowed: true, rule: "rule allow-host"}},
+		{name: "unexpanded service tag", port: 443, rules: append([]securityRule{
+			{Name: "allow-azure", Protocol: "Tcp", DestinationAddressPrefix: "AzureCloud", DestinationPortRange: "443", Access: "Allow", Priority: 100, Direction: "Outbound"},
+			{Name: "allow-vnet", Protocol: "*", DestinationAddressPrefix: "VirtualNetwork", DestinationPortRange: "*", Access: "Allow", Priority: 110, Direction: "Outbound"},
+			{Name: "deny-internet", Protocol: "*", DestinationAddressPrefix: "Internet", DestinationPortRange: "*", Access: "Deny", Priority: 4000, Direction: "Outbound"},
+		}, defaults...), expected: nsgDecision{allowed: false, rule: "rule deny-internet", unresolved: "allow-azure"}},
+		{name: "expanded service tag", port: 443, rules: append([]securityRule{
+			{Name: "allow-azure", Protocol: "Tcp", DestinationAddressPrefix: "AzureCloud", ExpandedDestinationAddressPrefix: []string{"203.0.113.0/25"},
+				DestinationPortRange: "443", Access: "Allow", Priority: 100, Direction: "Outbound"},
+			{Name: "deny-internet", Protocol: "*", DestinationAddressPrefix: "Internet", DestinationPortRange: "*", Access: "Deny", Priority: 4000, Direction: "Outbound"},
+		}, defaults...), expected: nsgDecision{allowed: true, rule: "rule allow-azure"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, evaluateSecurityRules(test.rules, ip, test.port), test.name)
+	}
+}
@@ -0,0 +1,371 @@
+package azure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// Ubuntu runs cloud-init, which installs docker to run the network validator image
+	defaultImage = imageReference{
+		Publisher: "Canonical",
+		Offer:     "0001-com-ubuntu-server-jammy",
+		Sku:       "22_04-lts-gen2",
+		Version:   "latest",
+	}
+	defaultInstanceType        = "Standard_B2s"
+	userdataEndVerifier string = "USERDATA END"
+	adminUsername       string = "osdnetworkverifier"
+	// run-command only returns the last 4096 bytes of the output, so only the lines the client parses are read
+	probeOutputScript string = "grep -E 'Unable to reach|Cannot|Could not|Failed|command not found|endpoint profile|Success!|Not all endpoints were reachable|" +
+		userdataEndVerifier + "' /var/log/userdata-output || true"
+
+	// The probe usually completes within a few minutes, the intervals can be shortened in tests
+	operationPollInterval  = 5 * time.Second
+	operationPollTimeout   = 5 * time.Minute
+	runCommandPollInterval = 30 * time.Second
+	runCommandPollTimeout  = 10 * time.Minute
+)
+
+func newClient(ctx context.Context, logger ocmlog.Logger, tokenSource oauth2.TokenSource, subscriptionID, region, instanceType string, tags map[string]string) (*Client, error) {
+	if tokenSource == nil {
+		return nil, fmt.Errorf("an Azure token source is required")
+	}
+	if subscriptionID == "" {
+		return nil, fmt.Errorf("an Azure subscription ID is required")
+	}
+	if instanceType == "" {
+		instanceType = defaultInstanceType
+	}
+
+	return &Client{
+		subscriptionID: subscriptionID,
+		// regions are given as location names, e.g. eastus
+		region:       strings.ToLower(strings.ReplaceAll(region, " ", "")),
+		instanceType: instanceType,
+		arm:          &armClient{endpoint: defaultARMEndpoint, httpClient: oauth2.NewClient(ctx, tokenSource)},
+		tags:         tags,
+		logger:       logger,
+	}, nil
+}
+
+// validateEgress performs validation process for egress
+// Basic workflow is:
+// - run the probe VM in the subnet, and verify the effective routes and security rules of its network interface
+// - make sure the network validator verified the endpoint profile of the platform
+// - find unreachable endpoints & parse output
+// - return `c.output` which stores the execution results
+func (c *Client) validateEgress(ctx context.Context, opts options.EgressOptions) *output.Output {
+	probeOutput, err := c.runProbe(ctx, opts)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	if helpers.ValidatorIgnoredProfile(probeOutput, PlatformName) {
+		return c.output.AddError(fmt.Errorf("network validator image %s doesn't support endpoint profiles, "+
+			"it verified the AWS endpoints instead of the %s ones", helpers.NetworkValidatorImage, PlatformName)) // fatal
+	}
+	c.addEgressResults(probeOutput)
+
+	return &c.output
+}
+
+// runProbe launches the probe VM in the subnet and returns the output of the network validator
+// Basic workflow is:
+// - generate the custom data running the network validator image
+// - create a network interface without public address in the subnet, and the VM using it, in the resource group of the subnet
+// - once the creation of the VM is accepted, wait for it to run
+// - verify the effective routes and security rules of the network interface, which are only known while the VM runs
+// - read the output with run-command once the custom data has completed
+// - delete the VM, its disk and its network interface
+func (c *Client) runProbe(ctx context.Context, opts options.EgressOptions) (string, error) {
+	c.logger.Debug(ctx, "Using configured timeout of %s for each egress request", opts.Timeout.String())
+	if len(opts.SecurityGroupIDs) > 1 {
+		return "", fmt.Errorf("a network interface has at most one network security group, got %v", opts.SecurityGroupIDs)
+	}
+	group := resourceGroupID(opts.SubnetID)
+	if group == "" {
+		return "", fmt.Errorf("subnet %q must be given by its resource ID", opts.SubnetID)
+	}
+	customData := os.Expand(helpers.CustomDataTemplate, func(varName string) string {
+		return map[string]string{
+			"PLATFORM":                 PlatformName,
+			"AZURE_REGION":             c.region,
+			"USERDATA_BEGIN":           "USERDATA BEGIN",
+			"USERDATA_END":             userdataEndVerifier,
+			"VALIDATOR_START_VERIFIER": "VALIDATOR START",
+			"VALIDATOR_END_VERIFIER":   "VALIDATOR END",
+			"VALIDATOR_IMAGE":          helpers.NetworkValidatorImage,
+			"TIMEOUT":                  opts.Timeout.String(),
+		}[varName]
+	})
+	c.logger.Debug(ctx, "Generated custom data:\n---\n%s\n---", customData)
+
+	name, err := randomName()
+	if err != nil {
+		return "", err
+	}
+	nicID := group + "/providers/Microsoft.Network/networkInterfaces/" + name
+	if err := c.createNetworkInterface(ctx, nicID, opts); err != nil {
+		return "", err
+	}
+	vmID := group + "/providers/Microsoft.Compute/virtualMachines/" + name
+	resp, err := c.createVM(ctx, vmID, nicID, customData, opts)
+	if err != nil {
+		// the network interface is only deleted with the VM once attached
+		c.deleteResource(ctx, nicID, networkAPIVersion)
+		return "", err
+	}
+	// try to delete the VM whatever happens once its creation was accepted, the operation may fail or time out
+	// after the VM was created. Its disk and network interface are deleted with it.
+	defer c.deleteResource(ctx, vmID, computeAPIVersion)
+
+	c.logger.Debug(ctx, "Waiting for VM %s to be created", name)
+	var vm virtualMachine
+	if err := c.arm.complete(ctx, vmID, computeAPIVersion, resp, &vm); err != nil {
+		return "", err
+	}
+	c.logger.Debug(ctx, "VM %s is %s", name, vm.Properties.ProvisioningState)
+
+	c.verifyEffectiveRoutes(ctx, nicID)
+	c.verifyEffectiveSecurityRules(ctx, nicID)
+
+	c.logger.Info(ctx, "Gathering and parsing run-command output...")
+	return c.getProbeOutput(ctx, vmID)
+}
+
+// createNetworkInterface creates the network interface of the probe without public address, so that egress goes through
+// the subnet's NAT gateway, firewall or load balancer like the cluster's nodes
+func (c *Client) createNetworkInterface(ctx context.Context, id string, opts options.EgressOptions) error {
+	config := ipConfiguration{Name: "ipconfig1"}
+	config.Properties.Subnet = subResource{ID: opts.SubnetID}
+	config.Properties.PrivateIPAllocationMethod = "Dynamic"
+
+	nic := networkInterface{Location: c.region, Tags: c.tags}
+	nic.Properties.IPConfigurations = []ipConfiguration{config}
+	if len(opts.SecurityGroupIDs) == 1 {
+		nic.Properties.NetworkSecurityGroup = &subResource{ID: opts.SecurityGroupIDs[0]}
+	}
+
+	c.logger.Info(ctx, "Creating network interface %s", resourceName(id))
+	if err := c.arm.put(ctx, id, networkAPIVersion, nic, &nic); err != nil {
+		return fmt.Errorf("unable to create network interface %s: %w", resourceName(id), err)
+	}
+
+	return nil
+}
+
+// createVM requests the creation of the probe VM, and returns the response to wait on for it to run.
+// Its disk and network interface are deleted with it, and boot diagnostics keep its serial console for troubleshooting.
+func (c *Client) createVM(ctx context.Context, id, nicID, customData string, opts options.EgressOptions) (*http.Response, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	vm := virtualMachine{Location: c.region, Tags: c.tags}
+	vm.Properties.HardwareProfile.VMSize = c.instanceType
+	vm.Properties.StorageProfile.ImageReference = defaultImage
+	if opts.CloudImageID != "" {
+		vm.Properties.StorageProfile.ImageReference = imageReference{ID: opts.CloudImageID}
+	}
+	disk := osDisk{CreateOption: "FromImage", DeleteOption: "Delete"}
+	disk.ManagedDisk.StorageAccountType = "Standard_LRS"
+	if opts.KmsKeyID != "" {
+		disk.ManagedDisk.DiskEncryptionSet = &subResource{ID: opts.KmsKeyID}
+	}
+	vm.Properties.StorageProfile.OSDisk = disk
+	vm.Properties.OSProfile.ComputerName = strings.TrimPrefix(resourceName(id), "virtualMachines/")
+	vm.Properties.OSProfile.AdminUsername = adminUsername
+	// nobody logs in, the password only satisfies the API
+	vm.Properties.OSProfile.AdminPassword = password
+	vm.Properties.OSProfile.CustomData = base64.StdEncoding.EncodeToString([]byte(customData))
+	nic := vmNetworkInterface{ID: nicID}
+	nic.Properties.Primary = true
+	nic.Properties.DeleteOption = "Delete"
+	vm.Properties.NetworkProfile.NetworkInterfaces = []vmNetworkInterface{nic}
+	vm.Properties.DiagnosticsProfile.BootDiagnostics.Enabled = true
+
+	c.logger.Info(ctx, "Creating VM %s in region %s", resourceName(id), c.region)
+	resp, err := c.arm.do(ctx, http.MethodPut, id, computeAPIVersion, vm, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create VM %s: %w", resourceName(id), err)
+	}
+
+	return resp, nil
+}
+
+// randomName returns a random name for the probe resources, so that concurrent runs don't collide
+func randomName() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("unable to generate the VM name: %w", err)
+	}
+
+	return "osd-network-verifier-" + hex.EncodeToString(suffix), nil
+}
+
+// randomPassword returns a password meeting the Azure complexity requirements
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate the VM password: %w", err)
+	}
+
+	return hex.EncodeToString(b) + "Aa1!", nil
+}
+
+// getProbeOutput waits for the custom data of the VM to complete and returns the relevant lines of its output, read with run-command
+func (c *Client) getProbeOutput(ctx context.Context, vmID string) (string, error) {
+	reVerify := regexp.MustCompile(userdataEndVerifier)
+	input := runCommandInput{CommandID: "RunShellScript", Script: []string{probeOutputScript}}
+
+	var contents string
+	err := helpers.PollImmediate(runCommandPollInterval, runCommandPollTimeout, func() (bool, error) {
+		var result runCommandResult
+		if err := c.arm.post(ctx, vmID+"/runCommand", computeAPIVersion, input, &result); err != nil {
+			return false, err
+		}
+		// the message holds the script's stdout and stderr, e.g. "Enable succeeded: \n[stdout]\n...\n[stderr]\n"
+		if len(result.Value) > 0 {
+			contents = result.Value[0].Message
+		}
+
+		if !reVerify.MatchString(contents) {
+			c.logger.Debug(ctx, "Output of %s doesn't contain the end of the custom data yet, continuing to wait...", resourceName(vmID))
+			return false, nil
+		}
+		c.logger.Debug(ctx, "Full run-command output:\n---\n%s\n---", contents)
+
+		return true, nil
+	})
+
+	return contents, err
+}
+
+// addEgressResults reports the endpoints the probe could not reach
+func (c *Client) addEgressResults(probeOutput string) {
+	// check output failures, report as exception if they occurred
+	var rgx = regexp.MustCompile(`(?m)^(.*Cannot.*)|(.*Could not.*)|(.*Failed.*)|(.*command not found.*)`)
+	if len(rgx.FindAllStringSubmatch(probeOutput, -1)) > 0 {
+		c.output.AddException(handledErrors.NewGenericError(
+			"internet connectivity problem: please ensure there's internet access in given vnet subnets"))
+	}
+
+	reUnreachableErrors := regexp.MustCompile(`Unable to reach (\S+)`)
+	c.output.SetEgressFailures(reUnreachableErrors.FindAllString(probeOutput, -1))
+}
+
+// verifyEffectiveRoutes reports where the default route of the network interface sends egress
+func (c *Client) verifyEffectiveRoutes(ctx context.Context, nicID string) {
+	var routes struct {
+		Value []effectiveRoute `json:"value"`
+	}
+	if err := c.arm.post(ctx, nicID+"/effectiveRouteTable", networkAPIVersion, nil, &routes); err != nil {
+		c.addUncheckedFinding(ctx, "effective-route", resourceName(nicID), "get the effective routes", err)
+		return
+	}
+
+	var defaultRoute *effectiveRoute
+	for i, r := range routes.Value {
+		if strings.EqualFold(r.State, "Active") && helpers.Contains(r.AddressPrefix, "0.0.0.0/0") {
+			defaultRoute = &routes.Value[i]
+		}
+	}
+	c.output.AddFinding(defaultRouteFinding("effective-route", resourceName(nicID), defaultRoute))
+}
+
+// defaultRouteFinding tells whether the default route reaches the internet:
+// directly, through a network virtual appliance or forced tunneling through a VPN or ExpressRoute gateway
+func defaultRouteFinding(check, resource string, r *effectiveRoute) output.Finding {
+	f := output.Finding{Check: check, Resource: resource, Status: output.StatusPassed}
+	if r == nil {
+		f.Status = output.StatusFailed
+		f.Message = "no default route, the cluster cannot reach the internet"
+		return f
+	}
+	switch strings.ToLower(r.NextHopType) {
+	case "internet":
+		f.Message = fmt.Sprintf("the %s default route sends egress to the internet", routeSource(r))
+	case "virtualappliance":
+		f.Message = fmt.Sprintf("the %s default route sends egress to the virtual appliance %s, it must allow the egress endpoints",
+			routeSource(r), strings.Join(r.NextHopIPAddress, ", "))
+	case "virtualnetworkgateway":
+		f.Status = output.StatusWarning
+		f.Message = fmt.Sprintf("the %s default route forces egress through the virtual network gateway, the on-premises network must allow the egress endpoints",
+			routeSource(r))
+	default:
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("the %s default route drops egress (next hop %s), the cluster cannot reach the internet", routeSource(r), r.NextHopType)
+	}
+
+	return f
+}
+
+// routeSource describes where the route comes from: the system routes, a route table or BGP
+func routeSource(r *effectiveRoute) string {
+	switch strings.ToLower(r.Source) {
+	case "default":
+		return "system"
+	case "user":
+		return "user-defined"
+	case "virtualnetworkgateway":
+		return "BGP"
+	}
+
+	return strings.ToLower(r.Source)
+}
+
+// verifyEffectiveSecurityRules evaluates the network security groups applied to the network interface, directly or
+// through its subnet, against the egress endpoints. Service tags are expanded in the effective rules.
+func (c *Client) verifyEffectiveSecurityRules(ctx context.Context, nicID string) {
+	var groups struct {
+		Value []effectiveNetworkSecurityGroup `json:"value"`
+	}
+	if err := c.arm.post(ctx, nicID+"/effectiveNetworkSecurityGroups", networkAPIVersion, nil, &groups); err != nil {
+		c.addUncheckedFinding(ctx, "effective-nsg", resourceName(nicID), "get the effective security rules", err)
+		return
+	}
+	if len(groups.Value) == 0 {
+		c.output.AddFinding(output.Finding{Check: "effective-nsg", Resource: resourceName(nicID), Status: output.StatusPassed,
+			Message: "no network security group applies to the network interface"})
+		return
+	}
+
+	eps, err := c.defaultEndpoints()
+	if err != nil {
+		c.output.AddError(err)
+		return
+	}
+	addresses := endpoints.ResolveIPv4(ctx, c.logger, &c.output, "effective-nsg", eps, lookupIP)
+	for _, g := range groups.Value {
+		resource := resourceName(nicID)
+		if g.NetworkSecurityGroup != nil {
+			resource = resourceName(g.NetworkSecurityGroup.ID)
+		}
+		c.addSecurityRuleFindings("effective-nsg", resource, g.EffectiveSecurityRules, eps, addresses)
+	}
+}
+
+// deleteResource deletes the resource, without waiting for the deletion to complete
+// uses c.output to store result of the execution
+func (c *Client) deleteResource(ctx context.Context, id, apiVersion string) {
+	c.logger.Info(ctx, "Deleting %s", resourceName(id))
+	err := c.arm.delete(ctx, id, apiVersion)
+	c.output.AddError(err)
+}
@@ -0,0 +1,201 @@
+package azure
+
+// subResource references another resource by its ID
+type subResource struct {
+	ID string `json:"id"`
+}
+
+// virtualNetwork is a Microsoft.Network/virtualNetworks resource
+type virtualNetwork struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Location   string `json:"location"`
+	Properties struct {
+		AddressSpace struct {
+			AddressPrefixes []string `json:"addressPrefixes"`
+		} `json:"addressSpace"`
+		// DhcpOptions lists the custom DNS servers of the network, Azure DNS is used when empty
+		DhcpOptions *struct {
+			DnsServers []string `json:"dnsServers"`
+		} `json:"dhcpOptions"`
+	} `json:"properties"`
+}
+
+// subnet is a Microsoft.Network/virtualNetworks/subnets resource
+type subnet struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Properties struct {
+		AddressPrefix        string       `json:"addressPrefix"`
+		AddressPrefixes      []string     `json:"addressPrefixes"`
+		NetworkSecurityGroup *subResource `json:"networkSecurityGroup"`
+		RouteTable           *subResource `json:"routeTable"`
+		NatGateway           *subResource `json:"natGateway"`
+		Delegations          []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				ServiceName string `json:"serviceName"`
+			} `json:"properties"`
+		} `json:"delegations"`
+		PrivateLinkServiceNetworkPolicies string `json:"privateLinkServiceNetworkPolicies"`
+		// DefaultOutboundAccess is false on private subnets, whose VMs get no implicit outbound address
+		DefaultOutboundAccess *bool `json:"defaultOutboundAccess"`
+	} `json:"properties"`
+}
+
+// networkSecurityGroup is a Microsoft.Network/networkSecurityGroups resource
+type networkSecurityGroup struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Properties struct {
+		SecurityRules        []nsgRule `json:"securityRules"`
+		DefaultSecurityRules []nsgRule `json:"defaultSecurityRules"`
+	} `json:"properties"`
+}
+
+// nsgRule is a rule of a network security group
+type nsgRule struct {
+	Name       string       `json:"name"`
+	Properties securityRule `json:"properties"`
+}
+
+// routeTable is a Microsoft.Network/routeTables resource
+type routeTable struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Properties struct {
+		Routes []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				AddressPrefix    string `json:"addressPrefix"`
+				NextHopType      string `json:"nextHopType"`
+				NextHopIPAddress string `json:"nextHopIpAddress"`
+			} `json:"properties"`
+		} `json:"routes"`
+	} `json:"properties"`
+}
+
+// effectiveRoute is a route applied to a network interface, from the system routes, the route table or BGP
+type effectiveRoute struct {
+	Name             string   `json:"name"`
+	Source           string   `json:"source"`
+	State            string   `json:"state"`
+	AddressPrefix    []string `json:"addressPrefix"`
+	NextHopType      string   `json:"nextHopType"`
+	NextHopIPAddress []string `json:"nextHopIpAddress"`
+}
+
+// effectiveNetworkSecurityGroup is a network security group applied to a network interface, directly or through its subnet
+type effectiveNetworkSecurityGroup struct {
+	NetworkSecurityGroup   *subResource   `json:"networkSecurityGroup"`
+	EffectiveSecurityRules []securityRule `json:"effectiveSecurityRules"`
+}
+
+// privateDnsZone is a Microsoft.Network/privateDnsZones resource, named after its domain
+type privateDnsZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// virtualNetworkLink is a Microsoft.Network/privateDnsZones/virtualNetworkLinks resource, making a zone visible from a network
+type virtualNetworkLink struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Properties struct {
+		VirtualNetwork          *subResource `json:"virtualNetwork"`
+		VirtualNetworkLinkState string       `json:"virtualNetworkLinkState"`
+	} `json:"properties"`
+}
+
+// networkInterface is the Microsoft.Network/networkInterfaces resource of the probe VM
+type networkInterface struct {
+	ID         string            `json:"id,omitempty"`
+	Location   string            `json:"location"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Properties struct {
+		IPConfigurations []ipConfiguration `json:"ipConfigurations"`
+		// NetworkSecurityGroup is applied on top of the subnet's
+		NetworkSecurityGroup *subResource `json:"networkSecurityGroup,omitempty"`
+	} `json:"properties"`
+}
+
+type ipConfiguration struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Subnet                    subResource `json:"subnet"`
+		PrivateIPAllocationMethod string      `json:"privateIPAllocationMethod"`
+	} `json:"properties"`
+}
+
+// virtualMachine is the Microsoft.Compute/virtualMachines resource of the probe VM
+type virtualMachine struct {
+	ID         string            `json:"id,omitempty"`
+	Location   string            `json:"location"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Properties struct {
+		HardwareProfile struct {
+			VMSize string `json:"vmSize"`
+		} `json:"hardwareProfile"`
+		StorageProfile struct {
+			ImageReference imageReference `json:"imageReference"`
+			OSDisk         osDisk         `json:"osDisk"`
+		} `json:"storageProfile"`
+		OSProfile struct {
+			ComputerName  string `json:"computerName"`
+			AdminUsername string `json:"adminUsername"`
+			AdminPassword string `json:"adminPassword,omitempty"`
+			CustomData    string `json:"customData,omitempty"`
+		} `json:"osProfile"`
+		NetworkProfile struct {
+			NetworkInterfaces []vmNetworkInterface `json:"networkInterfaces"`
+		} `json:"networkProfile"`
+		DiagnosticsProfile struct {
+			BootDiagnostics struct {
+				Enabled bool `json:"enabled"`
+			} `json:"bootDiagnostics"`
+		} `json:"diagnosticsProfile"`
+		ProvisioningState string `json:"provisioningState,omitempty"`
+	} `json:"properties"`
+}
+
+// imageReference is either a marketplace image or the ID of a custom image
+type imageReference struct {
+	ID        string `json:"id,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+	Offer     string `json:"offer,omitempty"`
+	Sku       string `json:"sku,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+type osDisk struct {
+	CreateOption string `json:"createOption"`
+	// DeleteOption deletes the disk with the VM
+	DeleteOption string `json:"deleteOption"`
+	ManagedDisk  struct {
+		StorageAccountType string       `json:"storageAccountType"`
+		DiskEncryptionSet  *subResource `json:"diskEncryptionSet,omitempty"`
+	} `json:"managedDisk"`
+}
+
+type vmNetworkInterface struct {
+	ID         string `json:"id"`
+	Properties struct {
+		Primary bool `json:"primary"`
+		// DeleteOption deletes the network interface with the VM
+		DeleteOption string `json:"deleteOption"`
+	} `json:"properties"`
+}
+
+// runCommandInput runs a shell script on the VM through its guest agent
+type runCommandInput struct {
+	CommandID string   `json:"commandId"`
+	Script    []string `json:"script"`
+}
+
+// runCommandResult holds the output of the script, the message of its first status holds stdout and stderr
+type runCommandResult struct {
+	Value []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"value"`
+}
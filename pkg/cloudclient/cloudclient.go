@@ -3,44 +3,100 @@ package cloudclient
 import (
 	"context"
 	"fmt"
-	"time"
+	"sort"
+	"sync"
 
-	awscredsv2 "github.com/aws/aws-sdk-go-v2/credentials"
-	awscredsv1 "github.com/aws/aws-sdk-go/aws/credentials"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
-	awsCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/aws"
-	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 	"github.com/openshift/osd-network-verifier/pkg/output"
-
-	"golang.org/x/oauth2/google"
 )
 
 // CloudClient defines the interface for a cloud agnostic implementation
 // For mocking: mockgen -source=pkg/cloudclient/cloudclient.go -package mocks -destination=pkg/cloudclient/mocks/mock_cloudclient.go
 type CloudClient interface {
 
-	// ByoVPCValidator validates the configuration given by the customer
-	ByoVPCValidator(ctx context.Context) error
+	// ByoVPCValidator validates the configuration given by the customer against:
+	// https://docs.openshift.com/rosa/rosa_getting_started/rosa-aws-prereqs.html#rosa-vpc_prerequisites
+	// Expected return value is *output.Output holding a finding for every checked requirement
+	ByoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output
 
 	// ValidateEgress validates that all required targets are reachable from the vpcsubnet
 	// target URLs: https://docs.openshift.com/rosa/rosa_getting_started/rosa-aws-prereqs.html#osd-aws-privatelink-firewall-prerequisites
 	// Expected return value is *output.Output that's storing failures, exceptions and errors
-	ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID string, kmsKeyID string, timeout time.Duration) *output.Output
+	ValidateEgress(ctx context.Context, opts options.EgressOptions) *output.Output
 
 	// VerifyDns verifies that a given VPC meets the DNS requirements specified in:
 	// https://docs.openshift.com/container-platform/4.10/installing/installing_aws/installing-aws-vpc.html
 	// Expected return value is *output.Output that's storing failures, exceptions and errors
-	VerifyDns(ctx context.Context, vpcID string) *output.Output
+	VerifyDns(ctx context.Context, opts options.DnsOptions) *output.Output
+}
+
+// Credentials is implemented by the typed credentials each provider accepts
+type Credentials interface {
+	// Platform returns the name of the platform the credentials are meant for
+	Platform() string
+}
+
+// Config holds the provider agnostic settings used when building a CloudClient
+type Config struct {
+	Region       string
+	InstanceType string
+	Tags         map[string]string
+	// Zone is the (optional) zone of the probe instances, only used by GCP
+	Zone string
+	// HostProject is the (optional) project owning the Shared VPC network, only used by GCP
+	HostProject string
+}
+
+// Provider builds a CloudClient for a single platform
+type Provider func(ctx context.Context, logger ocmlog.Logger, creds Credentials, config Config) (CloudClient, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+// Register makes a provider available under the given platform name.
+// It panics if the provider is nil or the platform is already registered.
+func Register(platform string, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if provider == nil {
+		panic("cloudclient: Register provider is nil")
+	}
+	if _, dup := providers[platform]; dup {
+		panic("cloudclient: Register called twice for platform " + platform)
+	}
+	providers[platform] = provider
 }
 
-func NewClient(ctx context.Context, logger ocmlog.Logger, creds interface{}, region, instanceType string, tags map[string]string) (CloudClient, error) {
-	switch c := creds.(type) {
-	case awscredsv1.Credentials, awscredsv2.StaticCredentialsProvider, string:
-		return awsCloudClient.NewClient(ctx, logger, c, region, instanceType, tags)
-	case *google.Credentials:
-		return gcpCloudClient.NewClient(ctx, logger, c, region, instanceType, tags)
-	default:
-		return nil, fmt.Errorf("unsupported credentials type %T", c)
+// Platforms returns a sorted list of the names of the registered platforms
+func Platforms() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	platforms := make([]string, 0, len(providers))
+	for p := range providers {
+		platforms = append(platforms, p)
+	}
+	sort.Strings(platforms)
+
+	return platforms
+}
+
+// NewClient builds a CloudClient using the provider registered for the given platform
+func NewClient(ctx context.Context, logger ocmlog.Logger, platform string, creds Credentials, config Config) (CloudClient, error) {
+	providersMu.RLock()
+	provider, ok := providers[platform]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported platform %q, expected one of %v", platform, Platforms())
+	}
+	if creds == nil {
+		return nil, fmt.Errorf("no credentials given for platform %s", platform)
+	}
+	if creds.Platform() != platform {
+		return nil, fmt.Errorf("credentials of type %T are meant for platform %s, not %s", creds, creds.Platform(), platform)
 	}
 
+	return provider(ctx, logger, creds, config)
 }
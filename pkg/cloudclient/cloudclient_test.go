@@ -0,0 +1,64 @@
+package cloudclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCredentials struct{}
+
+func (fakeCredentials) Platform() string { return "fake" }
+
+func TestRegisterProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	fakeClient := mocks.NewMockCloudClient(ctrl)
+
+	var gotConfig Config
+	Register("fake", func(ctx context.Context, logger ocmlog.Logger, creds Credentials, config Config) (CloudClient, error) {
+		gotConfig = config
+		return fakeClient, nil
+	})
+	defer func() {
+		providersMu.Lock()
+		delete(providers, "fake")
+		providersMu.Unlock()
+	}()
+
+	assert.Contains(t, Platforms(), "fake")
+	assert.Panics(t, func() { Register("fake", nil) }, "registering a nil provider must panic")
+
+	cli, err := NewClient(context.TODO(), &ocmlog.StdLogger{}, "fake", fakeCredentials{}, Config{Region: "somewhere-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, fakeClient, cli)
+	assert.Equal(t, "somewhere-1", gotConfig.Region)
+}
+
+func TestNewClientErrors(t *testing.T) {
+	ctx := context.TODO()
+	logger := &ocmlog.StdLogger{}
+	tests := []struct {
+		name     string
+		platform string
+		creds    Credentials
+	}{
+		{name: "unknown platform", platform: "unknown", creds: fakeCredentials{}},
+		{name: "missing credentials", platform: PlatformAWS},
+		{name: "credentials for another platform", platform: PlatformGCP, creds: AWSCredentials{}},
+		{name: "Azure credentials without token source", platform: PlatformAzure, creds: AzureCredentials{SubscriptionID: "sub"}},
+	}
+	for _, test := range tests {
+		if _, err := NewClient(ctx, logger, test.platform, test.creds, Config{}); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestBuiltinPlatforms(t *testing.T) {
+	assert.Subset(t, Platforms(), []string{PlatformAWS, PlatformGCP, PlatformAzure})
+}
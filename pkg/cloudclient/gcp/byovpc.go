@@ -0,0 +1,309 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"path"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+const (
+	// controlPlaneNodes is the number of control plane nodes of an OSD cluster
+	controlPlaneNodes = 3
+	// capacityHeadroomFactor is the ratio between the usable and the needed addresses of a subnetwork under which
+	// its capacity is reported as a warning, as it leaves little room to scale the cluster
+	capacityHeadroomFactor = 2
+)
+
+// byoVPCValidator verifies the documented BYOVPC requirements for the given subnetworks.
+// The first subnetwork hosts the control plane, the others the compute nodes.
+// Basic workflow is:
+// - ask Compute API for the subnetworks and their network
+// - ensure the network is not a legacy network, and every subnetwork is a regular subnetwork of the network in the region
+// - ensure a control plane and a compute subnetwork are given, with enough addresses for their nodes and the load balancers
+// - on a Shared VPC, ensure the project is a service project of the host project, allowed to use every subnetwork
+// - ask Compute API for the routes of the network and the Cloud Routers of the region
+// - ensure every subnetwork reaches the internet, through Cloud NAT when its default route uses the internet gateway
+// - ensure every subnetwork reaches Google APIs, through Private Google Access or Cloud NAT
+// - return `c.output` holding a finding for every requirement
+func (c *Client) byoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output {
+	c.logger.Info(ctx, "Verifying BYOVPC configuration for subnetworks %v", opts.SubnetIDs)
+	var subnets []*computev1.Subnetwork
+	for _, name := range opts.SubnetIDs {
+		subnet, err := c.computeService.Subnetworks.Get(c.networkProject(), c.region, path.Base(name)).Context(ctx).Do()
+		if err != nil {
+			if class, _ := classifyError(err); class == errorClassNotFound {
+				c.output.AddFinding(output.Finding{Check: "subnet", Resource: name, Status: output.StatusFailed,
+					Message: fmt.Sprintf("subnetwork not found in region %s", c.region)})
+			} else {
+				c.addUnknownFinding("subnet", name, "get the subnetwork", err)
+			}
+			continue
+		}
+		subnets = append(subnets, subnet)
+	}
+	if len(subnets) == 0 {
+		return c.output.AddError(fmt.Errorf("none of the subnetworks %v were found", opts.SubnetIDs)) // fatal
+	}
+
+	networkName := opts.VpcID
+	if networkName == "" {
+		networkName = subnets[0].Network
+		c.logger.Debug(ctx, "No network given, using network %s of subnetwork %s", networkName, subnets[0].Name)
+	}
+	network, err := c.computeService.Networks.Get(c.networkProject(), path.Base(networkName)).Context(ctx).Do()
+	if err != nil {
+		return c.output.AddError(fmt.Errorf("unable to get network %s: %w", networkName, err)) // fatal
+	}
+
+	c.checkNetworkMode(network)
+	for _, subnet := range subnets {
+		c.checkSubnetPlacement(subnet, network)
+	}
+	c.checkSubnetCapacity(opts.SubnetIDs, subnets, opts)
+	if c.sharedVPC() {
+		c.checkSharedVPC(ctx, subnets)
+	}
+
+	defaultRoute, err := c.defaultRoute(ctx, network)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	nats, err := c.subnetNats(ctx, network, subnets)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	for _, subnet := range subnets {
+		c.checkSubnetEgress(subnet, defaultRoute, nats[subnet.SelfLink])
+		c.checkPrivateGoogleAccess(subnet, defaultRoute, nats[subnet.SelfLink])
+	}
+
+	return &c.output
+}
+
+// checkNetworkMode ensures the network is a VPC network, legacy networks have no subnetworks.
+// Auto mode networks are reported as a warning, their subnetworks overlap the default pod network.
+func (c *Client) checkNetworkMode(network *computev1.Network) {
+	f := output.Finding{Check: "network-mode", Resource: network.Name, Status: output.StatusPassed,
+		Message: "custom mode VPC network"}
+	switch {
+	case network.IPv4Range != "":
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("legacy network with range %s, OSD requires a VPC network with subnetworks", network.IPv4Range)
+	case network.AutoCreateSubnetworks:
+		f.Status = output.StatusWarning
+		f.Message = fmt.Sprintf("auto mode VPC network, its subnetworks are created within 10.128.0.0/9 which overlaps the default pod network %s, "+
+			"a custom mode network is recommended", options.DefaultPodCIDR)
+	}
+	c.output.AddFinding(f)
+}
+
+// checkSubnetPlacement ensures the subnetwork belongs to the network and can host instances
+func (c *Client) checkSubnetPlacement(subnet *computev1.Subnetwork, network *computev1.Network) {
+	f := output.Finding{Check: "subnet", Resource: subnet.Name, Status: output.StatusPassed,
+		Message: fmt.Sprintf("subnetwork %s in network %s, region %s", subnet.IpCidrRange, network.Name, c.region)}
+	switch {
+	case c.networkKey(subnet.Network) != c.networkKey(network.SelfLink):
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("subnetwork belongs to network %s, not %s", path.Base(subnet.Network), network.Name)
+	// proxy-only and Private Service Connect subnetworks cannot host instances
+	case subnet.Purpose != "" && subnet.Purpose != "PRIVATE":
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("subnetwork is reserved for %s, it cannot host the cluster's nodes", subnet.Purpose)
+	}
+	c.output.AddFinding(f)
+}
+
+// checkSubnetCapacity ensures a control plane and a compute subnetwork are given, and that each of them has enough
+// addresses for its nodes and the load balancers. Compute nodes are spread across the compute subnetworks.
+func (c *Client) checkSubnetCapacity(names []string, subnets []*computev1.Subnetwork, opts options.ByoVPCOptions) {
+	if len(names) < 2 {
+		c.output.AddFinding(output.Finding{Check: "subnet-capacity", Resource: strings.Join(names, ","), Status: output.StatusFailed,
+			Message: "OSD on GCP needs a control plane and a compute subnetwork"})
+		return
+	}
+	controlPlane := path.Base(names[0])
+	computeSubnets := 0
+	for _, subnet := range subnets {
+		if subnet.Name != controlPlane {
+			computeSubnets++
+		}
+	}
+	nodesPerSubnet := 0
+	if computeSubnets > 0 {
+		nodesPerSubnet = (opts.ExpectedNodes + computeSubnets - 1) / computeSubnets
+	}
+
+	for _, subnet := range subnets {
+		nodes, role := nodesPerSubnet, "compute"
+		if subnet.Name == controlPlane {
+			nodes, role = controlPlaneNodes, "control plane"
+		}
+		needed := nodes + opts.LoadBalancerIPs
+		usage := fmt.Sprintf("%d %s nodes and load balancers", nodes, role)
+
+		_, cidr, err := net.ParseCIDR(subnet.IpCidrRange)
+		if err != nil {
+			c.output.AddFinding(output.Finding{Check: "subnet-capacity", Resource: subnet.Name, Status: output.StatusUnknown,
+				Message: fmt.Sprintf("unable to parse the subnetwork range %q", subnet.IpCidrRange)})
+			continue
+		}
+		usable := subnetUsableAddresses(cidr)
+		f := output.Finding{Check: "subnet-capacity", Resource: subnet.Name, Status: output.StatusPassed,
+			Message: fmt.Sprintf("%d usable addresses, %d needed for %s", usable, needed, usage)}
+		switch {
+		case usable < needed:
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("subnetwork range %s only has %d usable addresses, %d are needed for %s", cidr, usable, needed, usage)
+		case usable < needed*capacityHeadroomFactor:
+			f.Status = output.StatusWarning
+			f.Message = fmt.Sprintf("%d usable addresses, %d needed for %s, leaving little room to scale", usable, needed, usage)
+		}
+		c.output.AddFinding(f)
+	}
+}
+
+// subnetUsableAddresses returns the number of addresses of an IPv4 subnetwork, minus the 4 GCP reserves
+func subnetUsableAddresses(cidr *net.IPNet) int {
+	ones, bits := cidr.Mask.Size()
+	if bits-ones >= 31 {
+		return math.MaxInt32
+	}
+
+	return 1<<(bits-ones) - 4
+}
+
+// defaultRoute returns the untagged default route of the network with the highest priority, if any.
+// Tagged routes only apply to the instances carrying their tags.
+func (c *Client) defaultRoute(ctx context.Context, network *computev1.Network) (*computev1.Route, error) {
+	var best *computev1.Route
+	err := c.computeService.Routes.List(c.networkProject()).Filter(fmt.Sprintf("network=%q", network.SelfLink)).Pages(ctx, func(page *computev1.RouteList) error {
+		for _, route := range page.Items {
+			if route.DestRange != "0.0.0.0/0" || len(route.Tags) > 0 || c.networkKey(route.Network) != c.networkKey(network.SelfLink) {
+				continue
+			}
+			// lower values take precedence
+			if best == nil || route.Priority < best.Priority {
+				best = route
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the routes of network %s: %w", network.Name, err)
+	}
+
+	return best, nil
+}
+
+// subnetNats returns a lookup of the Cloud NAT gateways translating the primary range of each subnetwork,
+// by subnetwork URL, named "<router>/<nat>"
+func (c *Client) subnetNats(ctx context.Context, network *computev1.Network, subnets []*computev1.Subnetwork) (map[string]string, error) {
+	nats := map[string]string{}
+	err := c.computeService.Routers.List(c.networkProject(), c.region).Pages(ctx, func(page *computev1.RouterList) error {
+		for _, router := range page.Items {
+			if c.networkKey(router.Network) != c.networkKey(network.SelfLink) {
+				continue
+			}
+			for _, nat := range router.Nats {
+				for _, subnet := range subnets {
+					if _, ok := nats[subnet.SelfLink]; !ok && natCovers(nat, subnet) {
+						nats[subnet.SelfLink] = router.Name + "/" + nat.Name
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the Cloud Routers of region %s: %w", c.region, err)
+	}
+
+	return nats, nil
+}
+
+// natCovers returns true if the NAT gateway translates the primary range of the subnetwork, which the nodes use
+func natCovers(nat *computev1.RouterNat, subnet *computev1.Subnetwork) bool {
+	switch nat.SourceSubnetworkIpRangesToNat {
+	case "ALL_SUBNETWORKS_ALL_IP_RANGES", "ALL_SUBNETWORKS_ALL_PRIMARY_IP_RANGES":
+		return true
+	case "LIST_OF_SUBNETWORKS":
+		for _, s := range nat.Subnetworks {
+			if path.Base(s.Name) != subnet.Name {
+				continue
+			}
+			for _, r := range s.SourceIpRangesToNat {
+				if r == "ALL_IP_RANGES" || r == "PRIMARY_IP_RANGE" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// checkSubnetEgress ensures the nodes of the subnetwork, which have no external address, can reach the internet:
+// through Cloud NAT when the default route uses the internet gateway, or through the appliance the default route sends to
+func (c *Client) checkSubnetEgress(subnet *computev1.Subnetwork, defaultRoute *computev1.Route, nat string) {
+	f := output.Finding{Check: "cloud-nat", Resource: subnet.Name, Status: output.StatusPassed}
+	switch {
+	case defaultRoute == nil:
+		f.Status = output.StatusFailed
+		f.Message = "the network has no default route, the nodes cannot reach the internet"
+	case defaultRoute.NextHopGateway == "":
+		f.Message = fmt.Sprintf("default route %s sends egress to %s, which must reach the egress endpoints",
+			defaultRoute.Name, routeNextHop(defaultRoute))
+	case nat == "":
+		f.Status = output.StatusFailed
+		f.Message = fmt.Sprintf("no Cloud NAT gateway translates the subnetwork's range %s, the nodes have no external address and cannot reach the internet",
+			subnet.IpCidrRange)
+	default:
+		f.Message = fmt.Sprintf("egress goes through Cloud NAT gateway %s", nat)
+	}
+	c.output.AddFinding(f)
+}
+
+// routeNextHop returns a description of the next hop of a route
+func routeNextHop(route *computev1.Route) string {
+	switch {
+	case route.NextHopInstance != "":
+		return "instance " + path.Base(route.NextHopInstance)
+	case route.NextHopIlb != "":
+		return "internal load balancer " + path.Base(route.NextHopIlb)
+	case route.NextHopIp != "":
+		return "address " + route.NextHopIp
+	case route.NextHopVpnTunnel != "":
+		return "VPN tunnel " + path.Base(route.NextHopVpnTunnel)
+	case route.NextHopPeering != "":
+		return "peering " + route.NextHopPeering
+	}
+
+	return "gateway " + path.Base(route.NextHopGateway)
+}
+
+// checkPrivateGoogleAccess ensures the nodes of the subnetwork can reach Google APIs without an external address,
+// through Private Google Access or else through Cloud NAT or the appliance the default route sends to
+func (c *Client) checkPrivateGoogleAccess(subnet *computev1.Subnetwork, defaultRoute *computev1.Route, nat string) {
+	f := output.Finding{Check: "private-google-access", Resource: subnet.Name, Status: output.StatusPassed,
+		Message: "Private Google Access is enabled"}
+	switch {
+	case subnet.PrivateIpGoogleAccess:
+	case nat != "":
+		f.Status = output.StatusWarning
+		f.Message = fmt.Sprintf("Private Google Access is disabled, traffic to Google APIs goes through Cloud NAT gateway %s", nat)
+	case defaultRoute != nil && defaultRoute.NextHopGateway == "":
+		f.Status = output.StatusWarning
+		f.Message = fmt.Sprintf("Private Google Access is disabled, traffic to Google APIs goes to %s", routeNextHop(defaultRoute))
+	default:
+		f.Status = output.StatusFailed
+		f.Message = "Private Google Access is disabled and no Cloud NAT gateway translates the subnetwork, the nodes cannot reach Google APIs"
+	}
+	c.output.AddFinding(f)
+}
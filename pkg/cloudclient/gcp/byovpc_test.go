@@ -0,0 +1,143 @@
+package gcp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+const (
+	projectPath = "/projects/my-project"
+	computeURL  = "https://www.googleapis.com/compute/v1/projects/my-project"
+)
+
+func testSubnetwork(name, cidr string, privateGoogleAccess bool) *computev1.Subnetwork {
+	return &computev1.Subnetwork{
+		Name:                  name,
+		IpCidrRange:           cidr,
+		Network:               computeURL + "/global/networks/my-network",
+		Region:                computeURL + "/regions/us-east1",
+		PrivateIpGoogleAccess: privateGoogleAccess,
+		SelfLink:              computeURL + "/regions/us-east1/subnetworks/" + name,
+	}
+}
+
+func TestByoVPCValidator(t *testing.T) {
+	api := fakeapi.New(t).
+		On("GET", projectPath+"/regions/us-east1/subnetworks/control-plane", fakeapi.Response{Body: testSubnetwork("control-plane", "10.0.0.0/28", true)}).
+		On("GET", projectPath+"/regions/us-east1/subnetworks/compute", fakeapi.Response{Body: testSubnetwork("compute", "10.0.1.0/24", false)}).
+		On("GET", projectPath+"/regions/us-east1/subnetworks/missing", googleAPIError(http.StatusNotFound, "notFound", "The resource was not found")).
+		On("GET", projectPath+"/global/networks/my-network", fakeapi.Response{Body: computev1.Network{
+			Name:     "my-network",
+			SelfLink: computeURL + "/global/networks/my-network",
+		}}).
+		On("GET", projectPath+"/global/routes", fakeapi.Response{Body: computev1.RouteList{Items: []*computev1.Route{
+			{Name: "default-internet", DestRange: "0.0.0.0/0", Priority: 1000, Network: computeURL + "/global/networks/my-network",
+				NextHopGateway: computeURL + "/global/gateways/default-internet-gateway"},
+			{Name: "tagged-appliance", DestRange: "0.0.0.0/0", Priority: 100, Network: computeURL + "/global/networks/my-network",
+				NextHopIp: "10.0.2.10", Tags: []string{"proxied"}},
+			{Name: "subnet-route", DestRange: "10.0.1.0/24", Priority: 0, Network: computeURL + "/global/networks/my-network",
+				NextHopNetwork: computeURL + "/global/networks/my-network"},
+		}}}).
+		On("GET", projectPath+"/regions/us-east1/routers", fakeapi.Response{Body: computev1.RouterList{Items: []*computev1.Router{
+			{Name: "other-router", Network: computeURL + "/global/networks/other", Nats: []*computev1.RouterNat{
+				{Name: "all", SourceSubnetworkIpRangesToNat: "ALL_SUBNETWORKS_ALL_IP_RANGES"},
+			}},
+			{Name: "nat-router", Network: computeURL + "/global/networks/my-network", Nats: []*computev1.RouterNat{
+				{Name: "compute-nat", SourceSubnetworkIpRangesToNat: "LIST_OF_SUBNETWORKS", Subnetworks: []*computev1.RouterNatSubnetworkToNat{
+					{Name: computeURL + "/regions/us-east1/subnetworks/compute", SourceIpRangesToNat: []string{"PRIMARY_IP_RANGE"}},
+				}},
+			}},
+		}}})
+
+	cli := Client{
+		projectID:      "my-project",
+		region:         "us-east1",
+		computeService: newFakeComputeService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.ByoVPCValidator(context.TODO(), options.ByoVPCOptions{SubnetIDs: []string{"control-plane", "compute", "missing"}})
+
+	assert.Equal(t, []output.Finding{
+		{Check: "subnet", Resource: "missing", Status: output.StatusFailed, Message: "subnetwork not found in region us-east1"},
+		{Check: "network-mode", Resource: "my-network", Status: output.StatusPassed, Message: "custom mode VPC network"},
+		{Check: "subnet", Resource: "control-plane", Status: output.StatusPassed, Message: "subnetwork 10.0.0.0/28 in network my-network, region us-east1"},
+		{Check: "subnet", Resource: "compute", Status: output.StatusPassed, Message: "subnetwork 10.0.1.0/24 in network my-network, region us-east1"},
+		{Check: "subnet-capacity", Resource: "control-plane", Status: output.StatusWarning,
+			Message: "12 usable addresses, 11 needed for 3 control plane nodes and load balancers, leaving little room to scale"},
+		{Check: "subnet-capacity", Resource: "compute", Status: output.StatusPassed,
+			Message: "252 usable addresses, 17 needed for 9 compute nodes and load balancers"},
+		{Check: "cloud-nat", Resource: "control-plane", Status: output.StatusFailed,
+			Message: "no Cloud NAT gateway translates the subnetwork's range 10.0.0.0/28, the nodes have no external address and cannot reach the internet"},
+		{Check: "private-google-access", Resource: "control-plane", Status: output.StatusPassed, Message: "Private Google Access is enabled"},
+		{Check: "cloud-nat", Resource: "compute", Status: output.StatusPassed, Message: "egress goes through Cloud NAT gateway nat-router/compute-nat"},
+		{Check: "private-google-access", Resource: "compute", Status: output.StatusWarning,
+			Message: "Private Google Access is disabled, traffic to Google APIs goes through Cloud NAT gateway nat-router/compute-nat"},
+	}, out.Findings())
+	assert.False(t, out.IsSuccessful())
+}
+
+func TestByoVPCValidatorLegacyNetwork(t *testing.T) {
+	proxySubnet := testSubnetwork("proxy-only", "10.0.3.0/24", false)
+	proxySubnet.Purpose = "INTERNAL_HTTPS_LOAD_BALANCER"
+	api := fakeapi.New(t).
+		On("GET", projectPath+"/regions/us-east1/subnetworks/proxy-only", fakeapi.Response{Body: proxySubnet}).
+		On("GET", projectPath+"/global/networks/legacy", fakeapi.Response{Body: computev1.Network{
+			Name:      "legacy",
+			IPv4Range: "10.240.0.0/16",
+			SelfLink:  computeURL + "/global/networks/legacy",
+		}}).
+		On("GET", projectPath+"/global/routes", fakeapi.Response{Body: computev1.RouteList{Items: []*computev1.Route{
+			{Name: "to-firewall", DestRange: "0.0.0.0/0", Priority: 900, Network: computeURL + "/global/networks/legacy",
+				NextHopIlb: computeURL + "/regions/us-east1/forwardingRules/firewall"},
+		}}}).
+		On("GET", projectPath+"/regions/us-east1/routers", fakeapi.Response{Body: computev1.RouterList{}})
+
+	cli := Client{
+		projectID:      "my-project",
+		region:         "us-east1",
+		computeService: newFakeComputeService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.ByoVPCValidator(context.TODO(), options.ByoVPCOptions{VpcID: "legacy", SubnetIDs: []string{"proxy-only"}})
+
+	assert.Equal(t, []output.Finding{
+		{Check: "network-mode", Resource: "legacy", Status: output.StatusFailed,
+			Message: "legacy network with range 10.240.0.0/16, OSD requires a VPC network with subnetworks"},
+		{Check: "subnet", Resource: "proxy-only", Status: output.StatusFailed,
+			Message: "subnetwork belongs to network my-network, not legacy"},
+		{Check: "subnet-capacity", Resource: "proxy-only", Status: output.StatusFailed,
+			Message: "OSD on GCP needs a control plane and a compute subnetwork"},
+		{Check: "cloud-nat", Resource: "proxy-only", Status: output.StatusPassed,
+			Message: "default route to-firewall sends egress to internal load balancer firewall, which must reach the egress endpoints"},
+		{Check: "private-google-access", Resource: "proxy-only", Status: output.StatusWarning,
+			Message: "Private Google Access is disabled, traffic to Google APIs goes to internal load balancer firewall"},
+	}, out.Findings())
+}
+
+func TestNatCovers(t *testing.T) {
+	subnet := &computev1.Subnetwork{Name: "compute"}
+	tests := []struct {
+		name   string
+		nat    computev1.RouterNat
+		expect bool
+	}{
+		{name: "all primary ranges", nat: computev1.RouterNat{SourceSubnetworkIpRangesToNat: "ALL_SUBNETWORKS_ALL_PRIMARY_IP_RANGES"}, expect: true},
+		{name: "listed", nat: computev1.RouterNat{SourceSubnetworkIpRangesToNat: "LIST_OF_SUBNETWORKS",
+			Subnetworks: []*computev1.RouterNatSubnetworkToNat{{Name: "compute", SourceIpRangesToNat: []string{"ALL_IP_RANGES"}}}}, expect: true},
+		{name: "secondary ranges only", nat: computev1.RouterNat{SourceSubnetworkIpRangesToNat: "LIST_OF_SUBNETWORKS",
+			Subnetworks: []*computev1.RouterNatSubnetworkToNat{{Name: "compute", SourceIpRangesToNat: []string{"LIST_OF_SECONDARY_IP_RANGES"}}}}, expect: false},
+		{name: "other subnetwork", nat: computev1.RouterNat{SourceSubnetworkIpRangesToNat: "LIST_OF_SUBNETWORKS",
+			Subnetworks: []*computev1.RouterNatSubnetworkToNat{{Name: "control-plane", SourceIpRangesToNat: []string{"ALL_IP_RANGES"}}}}, expect: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expect, natCovers(&test.nat, subnet), test.name)
+	}
+}
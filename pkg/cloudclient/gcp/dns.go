@@ -0,0 +1,355 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
+)
+
+// ownedDomains are the domains of Google APIs and of the OpenShift services the cluster depends on,
+// a private zone or a response policy for any of their names hijacks them
+var ownedDomains = []string{
+	"googleapis.com",
+	"openshift.com",
+	"openshiftapps.com",
+	"redhat.com",
+	"redhat.io",
+	"quay.io",
+}
+
+// privateGoogleAccessHosts are the names Private Google Access expects googleapis.com to be mapped to
+var privateGoogleAccessHosts = []string{"private.googleapis.com", "restricted.googleapis.com"}
+
+// nodeDomains returns the internal names of the project's instances and of the metadata server,
+// which must be resolved by the Cloud DNS internal resolver
+func nodeDomains(projectID string) []string {
+	return []string{
+		"c." + projectID + ".internal",
+		"metadata.google.internal",
+	}
+}
+
+// hijackedNames returns the Google, OpenShift and node names answered by the domain instead of their authoritative servers:
+// the owned domains it covers or lies within, and the node domains it covers
+func (c *Client) hijackedNames(domain string) []string {
+	var hijacked []string
+	for _, owned := range ownedDomains {
+		switch {
+		case helpers.DomainCovers(domain, owned):
+			hijacked = append(hijacked, owned)
+		case helpers.DomainCovers(owned, domain):
+			hijacked = append(hijacked, domain)
+		}
+	}
+
+	return append(hijacked, helpers.CoveredNames(domain, nodeDomains(c.projectID))...)
+}
+
+// networkKey returns the "projects/<project>/global/networks/<name>" part of a network, which may be given
+// by name or by URL, so that networks referenced by Compute Engine and Cloud DNS can be compared
+func (c *Client) networkKey(network string) string {
+	if i := strings.Index(network, "projects/"); i >= 0 {
+		return network[i:]
+	}
+
+	return fmt.Sprintf("projects/%s/global/networks/%s", c.networkProject(), network)
+}
+
+// verifyDns performs verification process for the network's DNS
+// Basic workflow is:
+// - ask Cloud DNS API for the private zones, server policies and response policies bound to the network
+// - fail when they hijack Google APIs, the OpenShift domains or the node names
+// - warn when they forward those names or the egress endpoints to other name servers
+func (c *Client) verifyDns(ctx context.Context, opts options.DnsOptions) *output.Output {
+	network := c.networkKey(opts.VpcID)
+	c.logger.Info(ctx, "Verifying DNS config for network %s", network)
+
+	eps, err := endpoints.Default(PlatformName, map[string]string{"GCP_REGION": c.region})
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	hosts := endpoints.Hosts(eps)
+	c.verifyPrivateZones(ctx, network, hosts)
+	c.verifyDnsPolicies(ctx, network)
+	c.verifyResponsePolicies(ctx, network, hosts)
+
+	return &c.output
+}
+
+// verifyPrivateZones verifies the Cloud DNS private zones visible from the network
+// Basic workflow is:
+// - ask Cloud DNS API for the managed zones of the project, and keep the private ones bound to the network
+// - fail when a zone hijacks Google APIs, the OpenShift domains or the node names, unless it maps googleapis.com to Private Google Access
+// - warn when a forwarding or peering zone sends those names or the egress endpoints to other name servers
+// - warn when a zone shadows egress endpoints, which then only resolve if the zone has records for them
+func (c *Client) verifyPrivateZones(ctx context.Context, network string, hosts []string) {
+	var zones []*dnsv1beta2.ManagedZone
+	err := c.dnsService.ManagedZones.List(c.networkProject()).Pages(ctx, func(page *dnsv1beta2.ManagedZonesListResponse) error {
+		for _, zone := range page.ManagedZones {
+			if zone.Visibility == "private" && zone.PrivateVisibilityConfig != nil && c.boundTo(network, zoneNetworks(zone)) {
+				zones = append(zones, zone)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-private-zone", network, "list the managed zones", err)
+		return
+	}
+
+	flagged := false
+	for _, zone := range zones {
+		name := helpers.NormalizeDomain(zone.DnsName)
+		hijacked := c.hijackedNames(name)
+		shadowed := helpers.WithoutNames(helpers.CoveredNames(name, hosts), hijacked)
+		if len(hijacked) == 0 && len(shadowed) == 0 {
+			continue
+		}
+
+		switch {
+		case zone.ForwardingConfig != nil:
+			var targets []string
+			for _, t := range zone.ForwardingConfig.TargetNameServers {
+				targets = append(targets, t.Ipv4Address)
+			}
+			c.addForwardedFinding(zone.Name, name, "forwarded to "+strings.Join(targets, ", "), append(hijacked, shadowed...))
+		case zone.PeeringConfig != nil && zone.PeeringConfig.TargetNetwork != nil:
+			c.addForwardedFinding(zone.Name, name, "resolved by network "+c.networkKey(zone.PeeringConfig.TargetNetwork.NetworkUrl), append(hijacked, shadowed...))
+		case len(hijacked) > 0:
+			if len(hijacked) == 1 && hijacked[0] == "googleapis.com" {
+				if target := c.googleAPIsZoneTarget(ctx, zone, network); target != "" {
+					c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone.Name, Status: output.StatusPassed,
+						Message: fmt.Sprintf("private zone %s maps Google APIs to %s", name, target)})
+					break
+				}
+			}
+			c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone.Name, Status: output.StatusFailed,
+				Message: fmt.Sprintf("private zone %s hijacks %s, names missing from the zone do not resolve in the network", name, strings.Join(hijacked, ", "))})
+		default:
+			c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone.Name, Status: output.StatusWarning,
+				Message: fmt.Sprintf("private zone %s shadows the egress endpoints %s, they only resolve if the zone has records for them", name, strings.Join(shadowed, ", "))})
+		}
+		flagged = true
+	}
+
+	if !flagged {
+		c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: network, Status: output.StatusPassed,
+			Message: fmt.Sprintf("none of the %d private zones bound to the network shadows Google APIs, the OpenShift domains or the egress endpoints", len(zones))})
+	}
+}
+
+func zoneNetworks(zone *dnsv1beta2.ManagedZone) []string {
+	var networks []string
+	for _, n := range zone.PrivateVisibilityConfig.Networks {
+		networks = append(networks, n.NetworkUrl)
+	}
+
+	return networks
+}
+
+// boundTo returns true if the network is one of the given networks
+func (c *Client) boundTo(network string, networks []string) bool {
+	for _, n := range networks {
+		if c.networkKey(n) == network {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addForwardedFinding warns that the queries for the domain, which covers names the cluster depends on, are sent elsewhere
+func (c *Client) addForwardedFinding(zone, domain, destination string, names []string) {
+	if domain == "" {
+		c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone, Status: output.StatusWarning,
+			Message: fmt.Sprintf("all DNS queries are %s, they must resolve public names, Google APIs and the node names", destination)})
+		return
+	}
+	c.output.AddFinding(output.Finding{Check: "dns-private-zone", Resource: zone, Status: output.StatusWarning,
+		Message: fmt.Sprintf("queries for %s are %s, they must resolve %s", domain, destination, strings.Join(names, ", "))})
+}
+
+// googleAPIsZoneTarget returns what the googleapis.com zone maps Google APIs to, or "" if it doesn't follow a known layout:
+// - Private Google Access: a wildcard CNAME to private.googleapis.com or restricted.googleapis.com, which has A records in the zone
+// - Private Service Connect: a wildcard record resolving to the address of a PSC endpoint of the network
+func (c *Client) googleAPIsZoneTarget(ctx context.Context, zone *dnsv1beta2.ManagedZone, network string) string {
+	var rrsets []*dnsv1beta2.ResourceRecordSet
+	err := c.dnsService.ResourceRecordSets.List(c.networkProject(), zone.Name).Pages(ctx, func(page *dnsv1beta2.ResourceRecordSetsListResponse) error {
+		rrsets = append(rrsets, page.Rrsets...)
+		return nil
+	})
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-private-zone", zone.Name, "list the records", err)
+		return ""
+	}
+	if privateGoogleAccessTarget(rrsets) != "" {
+		return "Private Google Access"
+	}
+	if len(googleAPIsAddresses(rrsets)) == 0 {
+		return ""
+	}
+	endpoints, err := c.pscEndpoints(ctx, network)
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-private-zone", zone.Name, "list the PSC endpoints", err)
+		return ""
+	}
+	if psc := pscTarget(rrsets, endpoints); psc != nil {
+		return "Private Service Connect endpoint " + psc.name
+	}
+
+	return ""
+}
+
+// privateGoogleAccessTarget returns the Private Google Access name the records map *.googleapis.com to, if any
+func privateGoogleAccessTarget(rrsets []*dnsv1beta2.ResourceRecordSet) string {
+	addresses := map[string]bool{}
+	var target string
+	for _, rrset := range rrsets {
+		name := helpers.NormalizeDomain(rrset.Name)
+		switch {
+		case rrset.Type == "A" && len(rrset.Rrdatas) > 0:
+			addresses[name] = true
+		case rrset.Type == "CNAME" && name == "*.googleapis.com" && len(rrset.Rrdatas) == 1:
+			target = helpers.NormalizeDomain(rrset.Rrdatas[0])
+		}
+	}
+	for _, host := range privateGoogleAccessHosts {
+		if target == host && addresses[host] {
+			return host
+		}
+	}
+
+	return ""
+}
+
+// verifyDnsPolicies verifies the Cloud DNS server policies applied to the network
+// Basic workflow is:
+// - ask Cloud DNS API for the server policies of the project, and keep the ones applied to the network
+// - warn when alternative name servers replace the Cloud DNS resolver
+// - report inbound forwarding, which lets other networks query the network's private zones
+func (c *Client) verifyDnsPolicies(ctx context.Context, network string) {
+	var policies []*dnsv1beta2.Policy
+	err := c.dnsService.Policies.List(c.networkProject()).Pages(ctx, func(page *dnsv1beta2.PoliciesListResponse) error {
+		for _, policy := range page.Policies {
+			var networks []string
+			for _, n := range policy.Networks {
+				networks = append(networks, n.NetworkUrl)
+			}
+			if c.boundTo(network, networks) {
+				policies = append(policies, policy)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-policy", network, "list the DNS policies", err)
+		return
+	}
+	if len(policies) == 0 {
+		c.output.AddFinding(output.Finding{Check: "dns-policy", Resource: network, Status: output.StatusPassed,
+			Message: "no DNS server policy applies to the network, instances use the Cloud DNS resolver"})
+		return
+	}
+
+	// a network has at most one server policy
+	for _, policy := range policies {
+		if policy.AlternativeNameServerConfig != nil && len(policy.AlternativeNameServerConfig.TargetNameServers) > 0 {
+			var targets []string
+			for _, t := range policy.AlternativeNameServerConfig.TargetNameServers {
+				targets = append(targets, t.Ipv4Address)
+			}
+			c.output.AddFinding(output.Finding{Check: "dns-policy", Resource: policy.Name, Status: output.StatusWarning,
+				Message: fmt.Sprintf("all DNS queries go to the alternative name servers %s instead of Cloud DNS, they must resolve public names, "+
+					"Google APIs and the node names, and the network's private zones are ignored", strings.Join(targets, ", "))})
+			continue
+		}
+		message := "the policy keeps the Cloud DNS resolver"
+		if policy.EnableInboundForwarding {
+			message += ", inbound forwarding lets other networks query the network's private zones"
+		}
+		c.output.AddFinding(output.Finding{Check: "dns-policy", Resource: policy.Name, Status: output.StatusPassed, Message: message})
+	}
+}
+
+// verifyResponsePolicies verifies the Cloud DNS response policies applied to the network
+// Basic workflow is:
+// - ask Cloud DNS API for the response policies of the project, and keep the ones applied to the network
+// - ask Cloud DNS API for the rules of each of them
+// - fail when a rule overrides Google APIs, the OpenShift domains or the node names, unless it maps googleapis.com to Private Google Access
+// - warn when a rule overrides egress endpoints
+func (c *Client) verifyResponsePolicies(ctx context.Context, network string, hosts []string) {
+	var policies []*dnsv1beta2.ResponsePolicy
+	err := c.dnsService.ResponsePolicies.List(c.networkProject()).Pages(ctx, func(page *dnsv1beta2.ResponsePoliciesListResponse) error {
+		for _, policy := range page.ResponsePolicies {
+			var networks []string
+			for _, n := range policy.Networks {
+				networks = append(networks, n.NetworkUrl)
+			}
+			if c.boundTo(network, networks) {
+				policies = append(policies, policy)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.addUncheckedFinding(ctx, "dns-response-policy", network, "list the response policies", err)
+		return
+	}
+
+	flagged := false
+	for _, policy := range policies {
+		var rules []*dnsv1beta2.ResponsePolicyRule
+		err := c.dnsService.ResponsePolicyRules.List(c.networkProject(), policy.ResponsePolicyName).Pages(ctx, func(page *dnsv1beta2.ResponsePolicyRulesListResponse) error {
+			rules = append(rules, page.ResponsePolicyRules...)
+			return nil
+		})
+		if err != nil {
+			c.addUncheckedFinding(ctx, "dns-response-policy", policy.ResponsePolicyName, "list the response policy rules", err)
+			flagged = true
+			continue
+		}
+
+		// Private Google Access takes a wildcard rule for googleapis.com and a rule for the name it points to
+		var localData []*dnsv1beta2.ResourceRecordSet
+		for _, rule := range rules {
+			if rule.LocalData != nil {
+				localData = append(localData, rule.LocalData.LocalDatas...)
+			}
+		}
+		pga := privateGoogleAccessTarget(localData)
+
+		for _, rule := range rules {
+			// a bypass rule restores the normal resolution of names a broader rule overrides
+			if rule.Behavior == "bypassResponsePolicy" {
+				continue
+			}
+			name := helpers.NormalizeDomain(strings.TrimPrefix(rule.DnsName, "*."))
+			resource := policy.ResponsePolicyName + "/" + rule.RuleName
+			if hijacked := c.hijackedNames(name); len(hijacked) > 0 {
+				if pga != "" && (name == "googleapis.com" || name == pga) {
+					continue
+				}
+				c.output.AddFinding(output.Finding{Check: "dns-response-policy", Resource: resource, Status: output.StatusFailed,
+					Message: fmt.Sprintf("rule for %s overrides the answers for %s", rule.DnsName, strings.Join(hijacked, ", "))})
+				flagged = true
+				continue
+			}
+			if overridden := helpers.CoveredNames(name, hosts); len(overridden) > 0 {
+				c.output.AddFinding(output.Finding{Check: "dns-response-policy", Resource: resource, Status: output.StatusWarning,
+					Message: fmt.Sprintf("rule for %s overrides the egress endpoints %s, they only resolve if its local data is correct", rule.DnsName, strings.Join(overridden, ", "))})
+				flagged = true
+			}
+		}
+	}
+
+	if !flagged {
+		c.output.AddFinding(output.Finding{Check: "dns-response-policy", Resource: network, Status: output.StatusPassed,
+			Message: fmt.Sprintf("none of the %d response policies applied to the network overrides names the cluster depends on", len(policies))})
+	}
+}
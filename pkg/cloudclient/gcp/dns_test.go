@@ -0,0 +1,179 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+	computev1 "google.golang.org/api/compute/v1"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
+)
+
+const (
+	dnsPath    = "/dns/v1beta2/projects/my-project"
+	networkURL = "https://www.googleapis.com/compute/v1/projects/my-project/global/networks/my-network"
+)
+
+func privateZone(name, dnsName string, networks ...string) *dnsv1beta2.ManagedZone {
+	config := &dnsv1beta2.ManagedZonePrivateVisibilityConfig{}
+	for _, n := range networks {
+		config.Networks = append(config.Networks, &dnsv1beta2.ManagedZonePrivateVisibilityConfigNetwork{NetworkUrl: n})
+	}
+	return &dnsv1beta2.ManagedZone{Name: name, DnsName: dnsName, Visibility: "private", PrivateVisibilityConfig: config}
+}
+
+func localData(name, rrtype string, rrdatas ...string) *dnsv1beta2.ResponsePolicyRuleLocalData {
+	return &dnsv1beta2.ResponsePolicyRuleLocalData{LocalDatas: []*dnsv1beta2.ResourceRecordSet{{Name: name, Type: rrtype, Rrdatas: rrdatas}}}
+}
+
+func TestVerifyDns(t *testing.T) {
+	forwarding := privateZone("forward-internal", "internal.", networkURL)
+	forwarding.ForwardingConfig = &dnsv1beta2.ManagedZoneForwardingConfig{
+		TargetNameServers: []*dnsv1beta2.ManagedZoneForwardingConfigNameServerTarget{{Ipv4Address: "10.0.0.53"}},
+	}
+	api := fakeapi.New(t).
+		On("GET", dnsPath+"/managedZones", fakeapi.Response{Body: dnsv1beta2.ManagedZonesListResponse{ManagedZones: []*dnsv1beta2.ManagedZone{
+			privateZone("corp", "corp.example.com.", networkURL),
+			privateZone("google-apis", "googleapis.com.", "projects/my-project/global/networks/my-network"),
+			privateZone("quay", "quay.io.", networkURL),
+			privateZone("other-network", "redhat.com.", "https://www.googleapis.com/compute/v1/projects/my-project/global/networks/other"),
+			{Name: "public", DnsName: "example.com.", Visibility: "public"},
+			forwarding,
+		}}}).
+		On("GET", dnsPath+"/managedZones/google-apis/rrsets", fakeapi.Response{Body: dnsv1beta2.ResourceRecordSetsListResponse{Rrsets: []*dnsv1beta2.ResourceRecordSet{
+			{Name: "googleapis.com.", Type: "SOA", Rrdatas: []string{"ns-gcp-private.googledomains.com. cloud-dns-hostmaster.google.com. 1 21600 3600 259200 300"}},
+			{Name: "*.googleapis.com.", Type: "CNAME", Rrdatas: []string{"private.googleapis.com."}},
+			{Name: "private.googleapis.com.", Type: "A", Rrdatas: []string{"199.36.153.8", "199.36.153.9", "199.36.153.10", "199.36.153.11"}},
+		}}}).
+		On("GET", dnsPath+"/policies", fakeapi.Response{Body: dnsv1beta2.PoliciesListResponse{Policies: []*dnsv1beta2.Policy{{
+			Name:     "on-prem",
+			Networks: []*dnsv1beta2.PolicyNetwork{{NetworkUrl: networkURL}},
+			AlternativeNameServerConfig: &dnsv1beta2.PolicyAlternativeNameServerConfig{
+				TargetNameServers: []*dnsv1beta2.PolicyAlternativeNameServerConfigTargetNameServer{{Ipv4Address: "192.168.0.53"}},
+			},
+		}}}}).
+		On("GET", dnsPath+"/responsePolicies", fakeapi.Response{Body: dnsv1beta2.ResponsePoliciesListResponse{ResponsePolicies: []*dnsv1beta2.ResponsePolicy{
+			{ResponsePolicyName: "egress", Networks: []*dnsv1beta2.ResponsePolicyNetwork{{NetworkUrl: networkURL}}},
+			{ResponsePolicyName: "unbound"},
+		}}}).
+		On("GET", dnsPath+"/responsePolicies/egress/rules", fakeapi.Response{Body: dnsv1beta2.ResponsePolicyRulesListResponse{ResponsePolicyRules: []*dnsv1beta2.ResponsePolicyRule{
+			{RuleName: "google-apis", DnsName: "*.googleapis.com.", LocalData: localData("*.googleapis.com.", "CNAME", "restricted.googleapis.com.")},
+			{RuleName: "restricted", DnsName: "restricted.googleapis.com.", LocalData: localData("restricted.googleapis.com.", "A", "199.36.153.4")},
+			{RuleName: "ocm", DnsName: "api.openshift.com.", LocalData: localData("api.openshift.com.", "A", "10.0.0.10")},
+			{RuleName: "quay-bypass", DnsName: "quay.io.", Behavior: "bypassResponsePolicy"},
+			{RuleName: "pagerduty", DnsName: "events.pagerduty.com.", LocalData: localData("events.pagerduty.com.", "A", "10.0.0.11")},
+		}}})
+
+	cli := Client{
+		projectID:  "my-project",
+		dnsService: newFakeDnsService(t, api),
+		logger:     &ocmlog.StdLogger{},
+	}
+	out := cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: "my-network"})
+
+	assert.Equal(t, []output.Finding{
+		{Check: "dns-private-zone", Resource: "google-apis", Status: output.StatusPassed,
+			Message: "private zone googleapis.com maps Google APIs to Private Google Access"},
+		{Check: "dns-private-zone", Resource: "quay", Status: output.StatusFailed,
+			Message: "private zone quay.io hijacks quay.io, names missing from the zone do not resolve in the network"},
+		{Check: "dns-private-zone", Resource: "forward-internal", Status: output.StatusWarning,
+			Message: "queries for internal are forwarded to 10.0.0.53, they must resolve c.my-project.internal, metadata.google.internal"},
+		{Check: "dns-policy", Resource: "on-prem", Status: output.StatusWarning,
+			Message: "all DNS queries go to the alternative name servers 192.168.0.53 instead of Cloud DNS, they must resolve public names, " +
+				"Google APIs and the node names, and the network's private zones are ignored"},
+		{Check: "dns-response-policy", Resource: "egress/ocm", Status: output.StatusFailed,
+			Message: "rule for api.openshift.com. overrides the answers for api.openshift.com"},
+		{Check: "dns-response-policy", Resource: "egress/pagerduty", Status: output.StatusWarning,
+			Message: "rule for events.pagerduty.com. overrides the egress endpoints events.pagerduty.com, they only resolve if its local data is correct"},
+	}, out.Findings())
+	assert.False(t, out.IsSuccessful())
+	// the rules of a response policy not applied to the network are not read
+	assert.Empty(t, api.Received("GET", dnsPath+"/responsePolicies/unbound/rules"))
+}
+
+func TestVerifyDnsDefaultConfiguration(t *testing.T) {
+	api := fakeapi.New(t).
+		On("GET", dnsPath+"/managedZones", fakeapi.Response{Body: dnsv1beta2.ManagedZonesListResponse{ManagedZones: []*dnsv1beta2.ManagedZone{
+			privateZone("cluster", "mycluster.example.com.", networkURL),
+		}}}).
+		On("GET", dnsPath+"/policies", fakeapi.Response{Body: dnsv1beta2.PoliciesListResponse{Policies: []*dnsv1beta2.Policy{{
+			Name:                    "inbound",
+			Networks:                []*dnsv1beta2.PolicyNetwork{{NetworkUrl: networkURL}},
+			EnableInboundForwarding: true,
+		}}}}).
+		On("GET", dnsPath+"/responsePolicies", googleAPIError(http.StatusForbidden, "forbidden", "Forbidden"))
+
+	cli := Client{
+		projectID:  "my-project",
+		dnsService: newFakeDnsService(t, api),
+		logger:     &ocmlog.StdLogger{},
+	}
+	out := cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: networkURL})
+
+	assert.Equal(t, []output.Finding{
+		{Check: "dns-private-zone", Resource: "projects/my-project/global/networks/my-network", Status: output.StatusPassed,
+			Message: "none of the 1 private zones bound to the network shadows Google APIs, the OpenShift domains or the egress endpoints"},
+		{Check: "dns-policy", Resource: "inbound", Status: output.StatusPassed,
+			Message: "the policy keeps the Cloud DNS resolver, inbound forwarding lets other networks query the network's private zones"},
+		{Check: "dns-response-policy", Resource: "projects/my-project/global/networks/my-network", Status: output.StatusUnknown,
+			Message: "unable to list the response policies: access denied (forbidden)"},
+	}, out.Findings())
+	_, _, errs := out.Parse()
+	assert.Empty(t, errs, "the DNS checks are beyond the requirements")
+}
+
+func TestVerifyDnsPscZone(t *testing.T) {
+	api := fakeapi.New(t).
+		On("GET", dnsPath+"/managedZones", fakeapi.Response{Body: dnsv1beta2.ManagedZonesListResponse{ManagedZones: []*dnsv1beta2.ManagedZone{
+			privateZone("google-apis", "googleapis.com.", networkURL),
+		}}}).
+		On("GET", dnsPath+"/managedZones/google-apis/rrsets", fakeapi.Response{Body: dnsv1beta2.ResourceRecordSetsListResponse{Rrsets: []*dnsv1beta2.ResourceRecordSet{
+			{Name: "*.googleapis.com.", Type: "CNAME", Rrdatas: []string{"googleapis.com."}},
+			{Name: "googleapis.com.", Type: "A", Rrdatas: []string{"10.10.0.5"}},
+		}}}).
+		On("GET", projectPath+"/global/forwardingRules", fakeapi.Response{Body: computev1.ForwardingRuleList{Items: []*computev1.ForwardingRule{
+			{Name: "allapis", IPAddress: "10.10.0.5", Target: "all-apis", Network: networkURL},
+		}}}).
+		On("GET", dnsPath+"/policies", fakeapi.Response{Body: dnsv1beta2.PoliciesListResponse{}}).
+		On("GET", dnsPath+"/responsePolicies", fakeapi.Response{Body: dnsv1beta2.ResponsePoliciesListResponse{}})
+
+	cli := Client{
+		projectID:      "my-project",
+		computeService: newFakeComputeService(t, api),
+		dnsService:     newFakeDnsService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.VerifyDns(context.TODO(), options.DnsOptions{VpcID: "my-network"})
+
+	assert.Contains(t, out.Findings(), output.Finding{Check: "dns-private-zone", Resource: "google-apis", Status: output.StatusPassed,
+		Message: "private zone googleapis.com maps Google APIs to Private Service Connect endpoint allapis"})
+	assert.True(t, out.IsSuccessful())
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		code        int
+		reason      string
+		expectClass errorClass
+	}{
+		{code: http.StatusNotFound, reason: "notFound", expectClass: errorClassNotFound},
+		{code: http.StatusForbidden, reason: "forbidden", expectClass: errorClassAccessDenied},
+		{code: http.StatusTooManyRequests, reason: "rateLimitExceeded", expectClass: errorClassThrottled},
+		{code: http.StatusInternalServerError, reason: "backendError", expectClass: errorClassUnknown},
+	}
+	for _, test := range tests {
+		api := fakeapi.New(t).On("GET", dnsPath+"/policies", googleAPIError(test.code, test.reason, "error"))
+		_, err := newFakeDnsService(t, api).Policies.List("my-project").Do()
+		class, reason := classifyError(fmt.Errorf("unable to list: %w", err))
+		assert.Equal(t, test.expectClass, class, test.reason)
+		assert.Equal(t, test.reason, reason)
+	}
+	class, _ := classifyError(fmt.Errorf("dial tcp: i/o timeout"))
+	assert.Equal(t, errorClassUnknown, class)
+}
@@ -0,0 +1,63 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// errorClass tells apart the Google API errors a user can act on: a wrong name, a missing permission or too many calls
+type errorClass string
+
+const (
+	errorClassNotFound     errorClass = "not found"
+	errorClassAccessDenied errorClass = "access denied"
+	errorClassThrottled    errorClass = "throttled"
+	errorClassUnknown      errorClass = "unclassified error"
+)
+
+// classifyError returns the class and the reason of an error returned by a Google API
+func classifyError(err error) (errorClass, string) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return errorClassUnknown, ""
+	}
+	// e.g. notFound, forbidden, rateLimitExceeded
+	reason := http.StatusText(apiErr.Code)
+	if len(apiErr.Errors) > 0 && apiErr.Errors[0].Reason != "" {
+		reason = apiErr.Errors[0].Reason
+	}
+
+	switch apiErr.Code {
+	case http.StatusNotFound:
+		return errorClassNotFound, reason
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errorClassAccessDenied, reason
+	case http.StatusTooManyRequests:
+		return errorClassThrottled, reason
+	}
+
+	return errorClassUnknown, reason
+}
+
+// addUnknownFinding reports a requirement that could not be verified because of a Google API error,
+// and returns the class of the error
+func (c *Client) addUnknownFinding(check, resource, action string, err error) errorClass {
+	class, reason := classifyError(err)
+	c.output.AddUnknownFinding(check, resource, action, string(class), reason)
+	c.output.AddError(fmt.Errorf("unable to %s of %s: %w", action, resource, err))
+
+	return class
+}
+
+// addUncheckedFinding reports a check beyond the requirements, e.g. the DNS configuration of the network, that could not be
+// run because of a Google API error, usually a missing permission. Unlike addUnknownFinding, the error is only logged and
+// doesn't fail the verification.
+func (c *Client) addUncheckedFinding(ctx context.Context, check, resource, action string, err error) {
+	class, reason := classifyError(err)
+	c.output.AddUnknownFinding(check, resource, action, string(class), reason)
+	c.logger.Debug(ctx, "Unable to %s of %s: %s", action, resource, err)
+}
@@ -0,0 +1,45 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	computev1 "google.golang.org/api/compute/v1"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
+	"google.golang.org/api/option"
+)
+
+// fakeOptions returns the options pointing a Google API client at the fake API
+func fakeOptions(api *fakeapi.Server) []option.ClientOption {
+	return []option.ClientOption{option.WithEndpoint(api.Start().URL + "/"), option.WithoutAuthentication()}
+}
+
+// newFakeComputeService returns a Compute API client talking to the fake API
+func newFakeComputeService(t *testing.T, api *fakeapi.Server) *computev1.Service {
+	service, err := computev1.NewService(context.TODO(), fakeOptions(api)...)
+	if err != nil {
+		t.Fatalf("unable to create the fake compute service: %v", err)
+	}
+	return service
+}
+
+// newFakeDnsService returns a Cloud DNS API client talking to the fake API
+func newFakeDnsService(t *testing.T, api *fakeapi.Server) *dnsv1beta2.Service {
+	service, err := dnsv1beta2.NewService(context.TODO(), fakeOptions(api)...)
+	if err != nil {
+		t.Fatalf("unable to create the fake DNS service: %v", err)
+	}
+	return service
+}
+
+// googleAPIError is the body of an error returned by Google APIs
+func googleAPIError(code int, reason, message string) fakeapi.Response {
+	return fakeapi.Response{Status: code, Body: map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+			"errors":  []map[string]string{{"reason": reason, "message": message}},
+		},
+	}}
+}
@@ -0,0 +1,322 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// lookupIP resolves endpoint hosts, it can be replaced in tests
+var lookupIP = net.LookupIP
+
+// impliedEgressRule is the rule every VPC network ends with, allowing all egress
+const impliedEgressRule = "the implied allow egress rule"
+
+// beforeClassicFirewall is the enforcement order evaluating the network firewall policies before the VPC firewall rules
+const beforeClassicFirewall = "BEFORE_CLASSIC_FIREWALL"
+
+// firewallDecision is the outcome of evaluating a flow against the firewall, and the rule deciding it
+type firewallDecision struct {
+	allowed bool
+	rule    string
+}
+
+// firewallTarget is an instance of the cluster, as seen by the rules selecting their targets
+type firewallTarget struct {
+	network        string
+	tags           []string
+	serviceAccount string
+}
+
+// verifyFirewall evaluates the firewall of the network against the egress endpoints
+// Basic workflow is:
+// - ask Compute API for the effective firewall of the network: its firewall rules and the firewall policies applied to it
+// - ask Compute API whether the network firewall policies are enforced before or after the VPC firewall rules
+// - resolve the IPv4 addresses of every endpoint host
+// - evaluate the hierarchical firewall policies, then the VPC firewall rules and the network firewall policies in the network's order for each host and port
+// - report the rule denying each flow, and the rules allowing the others
+func (c *Client) verifyFirewall(ctx context.Context, opts options.FirewallOptions) *output.Output {
+	eps := opts.Endpoints
+	if len(eps) == 0 {
+		var err error
+		eps, err = endpoints.Default(PlatformName, map[string]string{"GCP_REGION": c.region})
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+	}
+
+	network := path.Base(opts.VpcID)
+	c.logger.Info(ctx, "Evaluating the firewall of network %s against %d endpoints", network, len(eps))
+	effective, err := c.computeService.Networks.GetEffectiveFirewalls(c.networkProject(), network).Context(ctx).Do()
+	if err != nil {
+		return c.output.AddError(fmt.Errorf("unable to get the effective firewall of network %s: %w", network, err)) // fatal
+	}
+	order, err := c.firewallPolicyEnforcementOrder(ctx, network)
+	if err != nil {
+		return c.output.AddError(fmt.Errorf("unable to get the firewall policy enforcement order of network %s: %w", network, err)) // fatal
+	}
+	tags := opts.NetworkTags
+	if len(tags) == 0 {
+		// the probe instance carries the network tags derived from the cloud tags, rules may target them
+		tags = networkTags(c.tags)
+	}
+	target := firewallTarget{network: c.networkKey(opts.VpcID), tags: tags, serviceAccount: opts.ServiceAccount}
+	c.logger.Debug(ctx, "Network firewall policies of %s are enforced %s", network, order)
+
+	addresses := endpoints.ResolveIPv4(ctx, c.logger, &c.output, "firewall-egress", eps, lookupIP)
+	allowedBy := map[string][]string{}
+	for _, e := range eps {
+		for _, port := range e.Ports {
+			var decision firewallDecision
+			// report the first denied address only, the others are usually denied by the same rule
+			for _, ip := range addresses[e.Host] {
+				decision = c.evaluateFirewall(effective, order, target, ip, port)
+				if !decision.allowed {
+					c.output.AddFinding(output.Finding{Check: "firewall-egress", Resource: network, Status: output.StatusFailed,
+						Message: fmt.Sprintf("egress to %s (%s) on port %d is denied by %s", e.Host, ip, port, decision.rule)})
+					break
+				}
+			}
+			if decision.allowed {
+				allowedBy[decision.rule] = append(allowedBy[decision.rule], fmt.Sprintf("%s:%d", e.Host, port))
+			}
+		}
+	}
+
+	rules := make([]string, 0, len(allowedBy))
+	for rule := range allowedBy {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		c.output.AddFinding(output.Finding{Check: "firewall-egress", Resource: network, Status: output.StatusPassed,
+			Message: fmt.Sprintf("%s allows egress to %s", rule, strings.Join(allowedBy[rule], ", "))})
+	}
+
+	return &c.output
+}
+
+// firewallPolicyEnforcementOrder returns the networkFirewallPolicyEnforcementOrder of the network, AFTER_CLASSIC_FIREWALL
+// by default. The field is read from the raw network resource, the Compute API client in use doesn't know about it yet.
+func (c *Client) firewallPolicyEnforcementOrder(ctx context.Context, network string) (string, error) {
+	urls := googleapi.ResolveRelative(c.computeService.BasePath,
+		fmt.Sprintf("projects/%s/global/networks/%s", url.PathEscape(c.networkProject()), url.PathEscape(network)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urls+"?fields=networkFirewallPolicyEnforcementOrder", nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return "", err
+	}
+	var fields struct {
+		NetworkFirewallPolicyEnforcementOrder string `json:"networkFirewallPolicyEnforcementOrder"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&fields); err != nil {
+		return "", err
+	}
+	if fields.NetworkFirewallPolicyEnforcementOrder == "" {
+		return "AFTER_CLASSIC_FIREWALL", nil
+	}
+
+	return fields.NetworkFirewallPolicyEnforcementOrder, nil
+}
+
+// evaluateFirewall returns the decision for TCP egress from the target to the address and port.
+// GCP evaluates the hierarchical firewall policies from the organization down to the folders, then the VPC firewall
+// rules and the network firewall policies, in the given enforcement order, and finally the implied rule allowing egress.
+// A policy rule allows or denies the flow, or hands it to the next level when its action is goto_next.
+func (c *Client) evaluateFirewall(effective *computev1.NetworksGetEffectiveFirewallsResponse, order string, target firewallTarget, ip net.IP, port int) firewallDecision {
+	var hierarchical, network []*computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy
+	for _, policy := range effective.FirewallPolicys {
+		if policy.Type == "NETWORK" {
+			network = append(network, policy)
+		} else {
+			hierarchical = append(hierarchical, policy)
+		}
+	}
+
+	for _, policy := range hierarchical {
+		if d, ok := c.evaluateFirewallPolicy(policy, target, ip, port); ok {
+			return d
+		}
+	}
+	if order == beforeClassicFirewall {
+		for _, policy := range network {
+			if d, ok := c.evaluateFirewallPolicy(policy, target, ip, port); ok {
+				return d
+			}
+		}
+	}
+	if d, ok := c.evaluateFirewallRules(effective.Firewalls, target, ip, port); ok {
+		return d
+	}
+	if order != beforeClassicFirewall {
+		for _, policy := range network {
+			if d, ok := c.evaluateFirewallPolicy(policy, target, ip, port); ok {
+				return d
+			}
+		}
+	}
+
+	return firewallDecision{allowed: true, rule: impliedEgressRule}
+}
+
+// evaluateFirewallPolicy returns the decision of the first policy rule (by priority) matching the flow,
+// ok is false when no rule matches or the matching rule goes to the next level
+func (c *Client) evaluateFirewallPolicy(policy *computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy,
+	target firewallTarget, ip net.IP, port int) (firewallDecision, bool) {
+	rules := make([]*computev1.FirewallPolicyRule, 0, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		if rule.Direction == "EGRESS" && !rule.Disabled && c.policyRuleApplies(rule, target) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+
+	name := policy.DisplayName
+	if name == "" {
+		name = policy.ShortName
+	}
+	if name == "" {
+		name = policy.Name
+	}
+	for _, rule := range rules {
+		if rule.Match == nil || (len(rule.Match.DestIpRanges) > 0 && !rangesContain(rule.Match.DestIpRanges, ip)) {
+			continue
+		}
+		matched := false
+		for _, l4 := range rule.Match.Layer4Configs {
+			if protocolPortMatches(l4.IpProtocol, l4.Ports, port) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if rule.Action == "goto_next" {
+			return firewallDecision{}, false
+		}
+		return firewallDecision{allowed: rule.Action == "allow",
+			rule: fmt.Sprintf("rule %d of firewall policy %s", rule.Priority, name)}, true
+	}
+
+	return firewallDecision{}, false
+}
+
+// policyRuleApplies returns true if the policy rule targets the network and the target's service account
+func (c *Client) policyRuleApplies(rule *computev1.FirewallPolicyRule, target firewallTarget) bool {
+	if len(rule.TargetResources) > 0 && !c.boundTo(target.network, rule.TargetResources) {
+		return false
+	}
+
+	return len(rule.TargetServiceAccounts) == 0 || helpers.Contains(rule.TargetServiceAccounts, target.serviceAccount)
+}
+
+// evaluateFirewallRules returns the decision of the first VPC firewall rule (by priority) matching the flow,
+// a deny rule taking precedence over an allow rule of the same priority. ok is false when no rule matches.
+func (c *Client) evaluateFirewallRules(firewalls []*computev1.Firewall, target firewallTarget, ip net.IP, port int) (firewallDecision, bool) {
+	var matching []*computev1.Firewall
+	for _, fw := range firewalls {
+		if fw.Direction != "EGRESS" || fw.Disabled || c.networkKey(fw.Network) != target.network || !firewallRuleApplies(fw, target) {
+			continue
+		}
+		// egress rules without destination ranges apply to every destination
+		if len(fw.DestinationRanges) > 0 && !rangesContain(fw.DestinationRanges, ip) {
+			continue
+		}
+		if firewallRuleMatchesPort(fw, port) {
+			matching = append(matching, fw)
+		}
+	}
+	if len(matching) == 0 {
+		return firewallDecision{}, false
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].Priority != matching[j].Priority {
+			return matching[i].Priority < matching[j].Priority
+		}
+		return len(matching[i].Denied) > 0 && len(matching[j].Denied) == 0
+	})
+
+	fw := matching[0]
+	return firewallDecision{allowed: len(fw.Denied) == 0,
+		rule: fmt.Sprintf("firewall rule %s (priority %d)", fw.Name, fw.Priority)}, true
+}
+
+// firewallRuleApplies returns true if the rule targets every instance of the network, or the target's tags or service account
+func firewallRuleApplies(fw *computev1.Firewall, target firewallTarget) bool {
+	switch {
+	case len(fw.TargetTags) > 0:
+		for _, tag := range fw.TargetTags {
+			if helpers.Contains(target.tags, tag) {
+				return true
+			}
+		}
+		return false
+	case len(fw.TargetServiceAccounts) > 0:
+		return helpers.Contains(fw.TargetServiceAccounts, target.serviceAccount)
+	}
+
+	return true
+}
+
+// firewallRuleMatchesPort returns true if the allowed or denied protocols of the rule include TCP traffic on the port
+func firewallRuleMatchesPort(fw *computev1.Firewall, port int) bool {
+	for _, a := range fw.Allowed {
+		if protocolPortMatches(a.IPProtocol, a.Ports, port) {
+			return true
+		}
+	}
+	for _, d := range fw.Denied {
+		if protocolPortMatches(d.IPProtocol, d.Ports, port) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// protocolPortMatches returns true if the protocol is TCP or all protocols, and the ports, e.g. "443" or "8000-9000",
+// include the port. No ports means every port.
+func protocolPortMatches(protocol string, ports []string, port int) bool {
+	switch strings.ToLower(protocol) {
+	case "tcp", "6", "all":
+	default:
+		return false
+	}
+	if len(ports) == 0 {
+		return true
+	}
+
+	return helpers.PortRangesContain(ports, port)
+}
+
+// rangesContain returns true if any of the CIDR ranges contains the address
+func rangesContain(ranges []string, ip net.IP) bool {
+	for _, r := range ranges {
+		if _, cidr, err := net.ParseCIDR(r); err == nil && cidr.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,166 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+func TestVerifyFirewall(t *testing.T) {
+	defer func(orig func(string) ([]net.IP, error)) { lookupIP = orig }(lookupIP)
+	lookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "quay.io":
+			return []net.IP{net.ParseIP("203.0.113.10")}, nil
+		case "api.openshift.com":
+			return []net.IP{net.ParseIP("198.51.100.20")}, nil
+		case "inputs1.osdsecuritylogs.splunkcloud.com":
+			return []net.IP{net.ParseIP("198.51.100.30")}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+
+	network := computeURL + "/global/networks/my-network"
+	api := fakeapi.New(t).
+		On("GET", projectPath+"/global/networks/my-network", fakeapi.Response{Body: map[string]string{}}).
+		On("GET", projectPath+"/global/networks/my-network/getEffectiveFirewalls", fakeapi.Response{Body: computev1.NetworksGetEffectiveFirewallsResponse{
+			FirewallPolicys: []*computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy{{
+				Name: "123456", DisplayName: "org-policy", Type: "HIERARCHY",
+				Rules: []*computev1.FirewallPolicyRule{
+					{Priority: 2000, Direction: "EGRESS", Action: "goto_next", Match: &computev1.FirewallPolicyRuleMatcher{
+						DestIpRanges: []string{"0.0.0.0/0"}, Layer4Configs: []*computev1.FirewallPolicyRuleMatcherLayer4Config{{IpProtocol: "all"}}}},
+					{Priority: 1000, Direction: "EGRESS", Action: "deny", Match: &computev1.FirewallPolicyRuleMatcher{
+						DestIpRanges: []string{"198.51.100.30/32"}, Layer4Configs: []*computev1.FirewallPolicyRuleMatcherLayer4Config{{IpProtocol: "tcp"}}}},
+					{Priority: 500, Direction: "INGRESS", Action: "allow", Match: &computev1.FirewallPolicyRuleMatcher{
+						SrcIpRanges: []string{"0.0.0.0/0"}, Layer4Configs: []*computev1.FirewallPolicyRuleMatcherLayer4Config{{IpProtocol: "all"}}}},
+				},
+			}},
+			Firewalls: []*computev1.Firewall{
+				{Name: "deny-all-egress", Network: network, Direction: "EGRESS", Priority: 65534,
+					Denied: []*computev1.FirewallDenied{{IPProtocol: "all"}}, DestinationRanges: []string{"0.0.0.0/0"}},
+				{Name: "allow-quay", Network: network, Direction: "EGRESS", Priority: 1000, TargetTags: []string{"worker"},
+					Allowed: []*computev1.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"443"}}}, DestinationRanges: []string{"203.0.113.0/24"}},
+				{Name: "deny-bastion", Network: network, Direction: "EGRESS", Priority: 900, TargetTags: []string{"bastion"},
+					Denied: []*computev1.FirewallDenied{{IPProtocol: "tcp"}}},
+				{Name: "allow-other-account", Network: network, Direction: "EGRESS", Priority: 1000,
+					TargetServiceAccounts: []string{"other@my-project.iam.gserviceaccount.com"},
+					Allowed:               []*computev1.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"80", "443"}}}},
+			},
+		}})
+
+	cli := Client{
+		projectID:      "my-project",
+		computeService: newFakeComputeService(t, api),
+		httpClient:     http.DefaultClient,
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.VerifyFirewall(context.TODO(), options.FirewallOptions{
+		VpcID:          "my-network",
+		NetworkTags:    []string{"worker"},
+		ServiceAccount: "worker@my-project.iam.gserviceaccount.com",
+		Endpoints: []endpoints.Endpoint{
+			{Host: "quay.io", Ports: []int{443}},
+			{Host: "api.openshift.com", Ports: []int{443}},
+			{Host: "inputs1.osdsecuritylogs.splunkcloud.com", Ports: []int{9997}},
+			{Host: "unresolvable.example.com", Ports: []int{443}},
+		},
+	})
+
+	assert.Equal(t, []output.Finding{
+		{Check: "firewall-egress", Resource: "unresolvable.example.com", Status: output.StatusWarning,
+			Message: "host could not be resolved to an IPv4 address and was not evaluated"},
+		{Check: "firewall-egress", Resource: "my-network", Status: output.StatusFailed,
+			Message: "egress to api.openshift.com (198.51.100.20) on port 443 is denied by firewall rule deny-all-egress (priority 65534)"},
+		{Check: "firewall-egress", Resource: "my-network", Status: output.StatusFailed,
+			Message: "egress to inputs1.osdsecuritylogs.splunkcloud.com (198.51.100.30) on port 9997 is denied by rule 1000 of firewall policy org-policy"},
+		{Check: "firewall-egress", Resource: "my-network", Status: output.StatusPassed,
+			Message: "firewall rule allow-quay (priority 1000) allows egress to quay.io:443"},
+	}, out.Findings())
+	assert.False(t, out.IsSuccessful())
+}
+
+func TestVerifyFirewallDefaultNetworkTags(t *testing.T) {
+	defer func(orig func(string) ([]net.IP, error)) { lookupIP = orig }(lookupIP)
+	lookupIP = func(host string) ([]net.IP, error) { return []net.IP{net.ParseIP("203.0.113.10")}, nil }
+
+	network := computeURL + "/global/networks/my-network"
+	api := fakeapi.New(t).
+		On("GET", projectPath+"/global/networks/my-network", fakeapi.Response{Body: map[string]string{}}).
+		On("GET", projectPath+"/global/networks/my-network/getEffectiveFirewalls", fakeapi.Response{Body: computev1.NetworksGetEffectiveFirewallsResponse{
+			Firewalls: []*computev1.Firewall{
+				{Name: "deny-all-egress", Network: network, Direction: "EGRESS", Priority: 65534,
+					Denied: []*computev1.FirewallDenied{{IPProtocol: "all"}}},
+				{Name: "allow-verifier", Network: network, Direction: "EGRESS", Priority: 1000, TargetTags: []string{"osd-network-verifier"},
+					Allowed: []*computev1.FirewallAllowed{{IPProtocol: "tcp"}}},
+			},
+		}})
+
+	cli := Client{
+		projectID:      "my-project",
+		tags:           map[string]string{"osd-network-verifier": "owned"},
+		computeService: newFakeComputeService(t, api),
+		httpClient:     http.DefaultClient,
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.VerifyFirewall(context.TODO(), options.FirewallOptions{
+		VpcID:     "my-network",
+		Endpoints: []endpoints.Endpoint{{Host: "quay.io", Ports: []int{443}}},
+	})
+
+	// without network tags, the rules targeting the tags of the probe apply
+	assert.Equal(t, []output.Finding{
+		{Check: "firewall-egress", Resource: "my-network", Status: output.StatusPassed,
+			Message: "firewall rule allow-verifier (priority 1000) allows egress to quay.io:443"},
+	}, out.Findings())
+}
+
+func TestEvaluateFirewallRules(t *testing.T) {
+	network := computeURL + "/global/networks/my-network"
+	cli := Client{projectID: "my-project"}
+	target := firewallTarget{network: cli.networkKey("my-network")}
+	ip := net.ParseIP("203.0.113.10")
+	allowAll := []*computev1.Firewall{
+		{Name: "allow-all", Network: network, Direction: "EGRESS", Priority: 1000, Allowed: []*computev1.FirewallAllowed{{IPProtocol: "all"}}},
+	}
+	denyPolicy := []*computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy{{
+		Name: "network-policy", Type: "NETWORK",
+		Rules: []*computev1.FirewallPolicyRule{{Priority: 100, Direction: "EGRESS", Action: "deny", Match: &computev1.FirewallPolicyRuleMatcher{
+			DestIpRanges: []string{"0.0.0.0/0"}, Layer4Configs: []*computev1.FirewallPolicyRuleMatcherLayer4Config{{IpProtocol: "tcp"}}}}},
+	}}
+	tests := []struct {
+		name      string
+		firewalls []*computev1.Firewall
+		policies  []*computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy
+		order     string
+		expect    firewallDecision
+	}{
+		{name: "no rule", expect: firewallDecision{allowed: true, rule: impliedEgressRule}},
+		{name: "deny wins at the same priority", firewalls: []*computev1.Firewall{
+			{Name: "allow", Network: network, Direction: "EGRESS", Priority: 1000, Allowed: []*computev1.FirewallAllowed{{IPProtocol: "tcp"}}},
+			{Name: "deny", Network: network, Direction: "EGRESS", Priority: 1000, Denied: []*computev1.FirewallDenied{{IPProtocol: "6", Ports: []string{"400-500"}}}},
+		}, expect: firewallDecision{allowed: false, rule: "firewall rule deny (priority 1000)"}},
+		{name: "disabled and udp rules are skipped", firewalls: []*computev1.Firewall{
+			{Name: "disabled", Network: network, Direction: "EGRESS", Priority: 10, Disabled: true, Denied: []*computev1.FirewallDenied{{IPProtocol: "all"}}},
+			{Name: "udp", Network: network, Direction: "EGRESS", Priority: 20, Denied: []*computev1.FirewallDenied{{IPProtocol: "udp"}}},
+			{Name: "other-ports", Network: network, Direction: "EGRESS", Priority: 30, Denied: []*computev1.FirewallDenied{{IPProtocol: "tcp", Ports: []string{"80", "8000-8443"}}}},
+		}, expect: firewallDecision{allowed: true, rule: impliedEgressRule}},
+		{name: "network firewall policy after the VPC rules", firewalls: allowAll, policies: denyPolicy, order: "AFTER_CLASSIC_FIREWALL",
+			expect: firewallDecision{allowed: true, rule: "firewall rule allow-all (priority 1000)"}},
+		{name: "network firewall policy before the VPC rules", firewalls: allowAll, policies: denyPolicy, order: beforeClassicFirewall,
+			expect: firewallDecision{allowed: false, rule: "rule 100 of firewall policy network-policy"}},
+	}
+	for _, test := range tests {
+		effective := &computev1.NetworksGetEffectiveFirewallsResponse{Firewalls: test.firewalls, FirewallPolicys: test.policies}
+		assert.Equal(t, test.expect, cli.evaluateFirewall(effective, test.order, target, ip, 443), test.name)
+	}
+}
@@ -2,59 +2,80 @@ package gcp
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"net/http"
 
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/options"
 	"github.com/openshift/osd-network-verifier/pkg/output"
 	"golang.org/x/oauth2/google"
 	computev1 "google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
 )
 
 // ClientIdentifier is what kind of cloud this implement supports
 const ClientIdentifier string = "GCP"
 
+// PlatformName is the name the GCP provider is registered under, and the endpoint profile it verifies
+const PlatformName string = "gcp"
+
 // Client represents a GCP Client
 type Client struct {
 	projectID      string
+	hostProjectID  string
 	region         string
+	zone           string
 	instanceType   string
 	computeService *computev1.Service
-	tags           map[string]string
-	logger         ocmlog.Logger
-	output         output.Output
-}
-
-func (c *Client) ByoVPCValidator(ctx context.Context) error {
-	c.logger.Info(ctx, "interface executed: %s", ClientIdentifier)
-	return nil
+	// httpClient is the authenticated client of computeService, for the fields it doesn't know about
+	httpClient *http.Client
+	dnsService *dnsv1beta2.Service
+	tags       map[string]string
+	logger     ocmlog.Logger
+	output     output.Output
 }
 
-func (c *Client) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID string, kmsKeyID string, timeout time.Duration) *output.Output {
-	return &c.output
+func (c *Client) ByoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.byoVPCValidator(ctx, opts)
 }
 
-func (c *Client) VerifyDns(ctx context.Context, vpcID string) *output.Output {
-	return &c.output
+func (c *Client) ValidateEgress(ctx context.Context, opts options.EgressOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	if opts.Mode != options.EgressModeInstance {
+		return c.output.AddError(fmt.Errorf("egress mode %q is not supported on GCP", opts.Mode))
+	}
+	return c.validateEgress(ctx, opts)
 }
 
-func NewClient(ctx context.Context, logger ocmlog.Logger, credentials *google.Credentials, region, instanceType string, tags map[string]string) (*Client, error) {
-	// initialize actual client
-	return newClient(ctx, logger, credentials, region, instanceType, tags)
+func (c *Client) VerifyDns(ctx context.Context, opts options.DnsOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
+	}
+	return c.verifyDns(ctx, opts)
 }
 
-func newClient(ctx context.Context, logger ocmlog.Logger, credentials *google.Credentials, region, instanceType string, tags map[string]string) (*Client, error) {
-	computeService, err := computev1.NewService(ctx, option.WithCredentials(credentials))
-	if err != nil {
-		return nil, err
+// VerifyFirewall evaluates the firewall rules and firewall policies of the network against the egress endpoints
+// without launching an instance
+func (c *Client) VerifyFirewall(ctx context.Context, opts options.FirewallOptions) *output.Output {
+	opts.SetDefaults()
+	if err := opts.Validate(); err != nil {
+		return c.output.AddError(err)
 	}
+	return c.verifyFirewall(ctx, opts)
+}
 
-	return &Client{
-		projectID:      credentials.ProjectID,
-		region:         region,
-		instanceType:   instanceType,
-		computeService: computeService,
-		tags:           tags,
-		logger:         logger,
-	}, nil
+// NewClient creates a new CloudClient for use with GCP.
+// The probe instances are created in the zone, or in the first zone of the region if zone is empty.
+// The network resources belong to the Shared VPC host project, or to the project of the credentials if hostProject is empty.
+func NewClient(ctx context.Context, logger ocmlog.Logger, credentials *google.Credentials, hostProject, region, zone, instanceType string, tags map[string]string) (*Client, error) {
+	// initialize actual client
+	return newClient(ctx, logger, credentials, hostProject, region, zone, instanceType, tags)
 }
@@ -2,51 +2,273 @@ package gcp
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/validatortest"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/oauth2/google"
+	computev1 "google.golang.org/api/compute/v1"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
 )
 
-func TestByoVPCValidator(t *testing.T) {
-	ctx := context.TODO()
-	logger := &ocmlog.StdLogger{}
-	client := &Client{logger: logger}
-	err := client.ByoVPCValidator(ctx)
-	if err != nil {
-		t.Errorf("unexpected error: %v", err)
+func TestValidateEgress(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval, serialPortPollInterval = interval, interval }(operationPollInterval)
+	operationPollInterval, serialPortPollInterval = time.Millisecond, time.Millisecond
+
+	const zonePath = "/projects/my-project/zones/us-east1-b"
+	api := fakeapi.New(t).
+		On("GET", "/projects/my-project/regions/us-east1", fakeapi.Response{Body: computev1.Region{Zones: []string{
+			"https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-c",
+			"https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-b",
+		}}}).
+		On("POST", zonePath+"/instances", fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "RUNNING"}}).
+		On("GET", zonePath+"/operations/op-insert",
+			fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "RUNNING"}},
+			fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "DONE"}}).
+		On("GET", zonePath+"/instances/*/serialPort",
+			fakeapi.Response{Body: computev1.SerialPortOutput{Contents: "booting\nUSERDATA BEGIN\n", Next: 22}},
+			fakeapi.Response{Body: computev1.SerialPortOutput{
+				Contents: "Using the gcp endpoint profile\nNot all endpoints were reachable:\n" +
+					"Unable to reach quay.io:443 within specified timeout after 3 retries\n" +
+					"Unable to reach iam.googleapis.com:443 within specified timeout after 3 retries\nUSERDATA END\n",
+				Next: 249,
+			}}).
+		On("DELETE", zonePath+"/instances/*", fakeapi.Response{Body: computev1.Operation{Name: "op-delete"}}).
+		On("GET", projectPath+"/regions/us-east1/subnetworks/my-subnet", fakeapi.Response{Body: testSubnetwork("my-subnet", "10.0.0.0/24", true)}).
+		On("GET", projectPath+"/global/forwardingRules", fakeapi.Response{Body: computev1.ForwardingRuleList{Items: []*computev1.ForwardingRule{
+			{Name: "restrictedapis", IPAddress: "10.10.0.5", Target: "vpc-sc", Network: computeURL + "/global/networks/my-network"},
+		}}}).
+		On("GET", dnsPath+"/managedZones", fakeapi.Response{Body: dnsv1beta2.ManagedZonesListResponse{ManagedZones: []*dnsv1beta2.ManagedZone{
+			privateZone("google-apis", "googleapis.com.", "projects/my-project/global/networks/my-network"),
+		}}}).
+		On("GET", dnsPath+"/managedZones/google-apis/rrsets", fakeapi.Response{Body: dnsv1beta2.ResourceRecordSetsListResponse{Rrsets: []*dnsv1beta2.ResourceRecordSet{
+			{Name: "*.googleapis.com.", Type: "A", Rrdatas: []string{"10.10.0.5"}},
+		}}})
+
+	cli := Client{
+		projectID:      "my-project",
+		region:         "us-east1",
+		instanceType:   "e2-micro",
+		tags:           map[string]string{"osd-network-verifier": "owned", "Name": "osd-network-verifier"},
+		computeService: newFakeComputeService(t, api),
+		dnsService:     newFakeDnsService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: "my-subnet", Timeout: time.Second})
+
+	failures, exceptions, errs := out.Parse()
+	if assert.Len(t, failures, 2) {
+		assert.Contains(t, failures[0].Error(), "quay.io:443")
+	}
+	assert.Empty(t, exceptions)
+	assert.Empty(t, errs)
+	assert.Equal(t, []output.Finding{
+		{Check: "google-apis-path", Resource: "my-subnet", Status: output.StatusPassed,
+			Message: "Google API traffic goes through Private Service Connect endpoint restrictedapis (10.10.0.5, bundle vpc-sc), " +
+				"only the APIs supported by VPC Service Controls are reachable"},
+		{Check: "google-apis-path", Resource: "my-subnet", Status: output.StatusWarning,
+			Message: "Google API endpoints iam.googleapis.com:443 are unreachable through Private Service Connect endpoint restrictedapis (10.10.0.5, bundle vpc-sc), " +
+				"their services may not be supported by VPC Service Controls or the perimeter may deny them"},
+	}, out.Findings())
+
+	inserts := api.Received("POST", zonePath+"/instances")
+	if assert.Len(t, inserts, 1) {
+		var instance computev1.Instance
+		assert.NoError(t, json.Unmarshal(inserts[0].Body, &instance))
+		assert.Equal(t, "projects/my-project/regions/us-east1/subnetworks/my-subnet", instance.NetworkInterfaces[0].Subnetwork)
+		assert.Empty(t, instance.NetworkInterfaces[0].AccessConfigs, "the probe must not have an external address")
+		assert.Equal(t, "zones/us-east1-b/machineTypes/e2-micro", instance.MachineType)
+		assert.Equal(t, defaultImage, instance.Disks[0].InitializeParams.SourceImage)
+		assert.Contains(t, *instance.Metadata.Items[0].Value, "--timeout=1s")
+		expectLabels := map[string]string{"osd-network-verifier": "owned", "name": "osd-network-verifier"}
+		assert.Equal(t, expectLabels, instance.Labels)
+		assert.Equal(t, expectLabels, instance.Disks[0].InitializeParams.Labels)
+		assert.Equal(t, []string{"name", "osd-network-verifier"}, instance.Tags.Items)
+	}
+	// the serial port output is read incrementally
+	reads := api.Received("GET", zonePath+"/instances/*/serialPort")
+	if assert.Len(t, reads, 2) {
+		assert.Equal(t, []string{"22"}, reads[1].Query["start"])
 	}
+	assert.Len(t, api.Received("DELETE", zonePath+"/instances/*"), 1)
 }
 
-func TestValidateEgress(t *testing.T) {
-	ctx := context.TODO()
-	subnetID := "subnet-id"
-	cloudImageID := "image-id"
-	cli := Client{}
-	timeout := 1 * time.Second
-	if !cli.ValidateEgress(ctx, subnetID, cloudImageID, "", timeout).IsSuccessful() {
-		t.Errorf("validation should have been successful")
+func TestValidateEgressInstanceErrors(t *testing.T) {
+	defer func(interval, timeout time.Duration) {
+		operationPollInterval, operationPollTimeout = interval, timeout
+	}(operationPollInterval, operationPollTimeout)
+	operationPollInterval, operationPollTimeout = time.Millisecond, 10*time.Millisecond
+
+	const zonePath = "/projects/my-project/zones/us-east1-b"
+	tests := []struct {
+		name         string
+		insert       fakeapi.Response
+		operation    fakeapi.Response
+		delete       fakeapi.Response
+		expectError  string
+		expectDelete bool
+	}{
+		{
+			name:        "insert denied",
+			insert:      googleAPIError(403, "forbidden", "Required 'compute.instances.create' permission"),
+			expectError: "compute.instances.create",
+		},
+		{
+			name: "operation failed",
+			insert: fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "DONE", OperationType: "insert",
+				TargetLink: "https://www.googleapis.com/compute/v1" + zonePath + "/instances/probe",
+				Error: &computev1.OperationError{Errors: []*computev1.OperationErrorErrors{{
+					Code: "ZONE_RESOURCE_POOL_EXHAUSTED", Message: "The zone does not have enough resources",
+				}}},
+			}},
+			// the instance was never created
+			delete:       googleAPIError(404, "notFound", "The resource 'probe' was not found"),
+			expectError:  "operation insert on probe failed: ZONE_RESOURCE_POOL_EXHAUSTED",
+			expectDelete: true,
+		},
+		{
+			name:         "operation never completes",
+			insert:       fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "RUNNING"}},
+			operation:    fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "RUNNING"}},
+			delete:       fakeapi.Response{Body: computev1.Operation{Name: "op-delete"}},
+			expectError:  "timed out",
+			expectDelete: true,
+		},
+	}
+	for _, test := range tests {
+		api := fakeapi.New(t).
+			On("GET", "/projects/my-project/regions/us-east1", fakeapi.Response{Body: computev1.Region{Zones: []string{"us-east1-b"}}}).
+			On("POST", zonePath+"/instances", test.insert).
+			On("GET", zonePath+"/operations/op-insert", test.operation).
+			On("DELETE", zonePath+"/instances/*", test.delete)
+
+		cli := Client{
+			projectID:      "my-project",
+			region:         "us-east1",
+			instanceType:   "e2-micro",
+			computeService: newFakeComputeService(t, api),
+			logger:         &ocmlog.StdLogger{},
+		}
+		out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: "my-subnet"})
+
+		_, _, errs := out.Parse()
+		if assert.Len(t, errs, 1, test.name) {
+			assert.Contains(t, errs[0].Error(), test.expectError, test.name)
+		}
+		// the instance is deleted as soon as its creation was accepted
+		deletes := api.Received("DELETE", zonePath+"/instances/*")
+		if test.expectDelete {
+			assert.Len(t, deletes, 1, test.name)
+		} else {
+			assert.Empty(t, deletes, test.name)
+		}
 	}
 }
 
+func TestValidateEgressValidatorOutput(t *testing.T) {
+	defer func(interval time.Duration) { serialPortPollInterval = interval }(serialPortPollInterval)
+	serialPortPollInterval = time.Millisecond
+	result := validatortest.Run(t, PlatformName)
+
+	const zonePath = "/projects/my-project/zones/us-east1-b"
+	api := fakeapi.New(t).
+		On("POST", zonePath+"/instances", fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "DONE"}}).
+		On("GET", zonePath+"/instances/*/serialPort", fakeapi.Response{Body: computev1.SerialPortOutput{Contents: result.Output}}).
+		On("DELETE", zonePath+"/instances/*", fakeapi.Response{Body: computev1.Operation{Name: "op-delete"}}).
+		On("GET", projectPath+"/regions/us-east1/subnetworks/my-subnet", fakeapi.Response{Body: testSubnetwork("my-subnet", "10.0.0.0/24", true)}).
+		On("GET", projectPath+"/global/forwardingRules", fakeapi.Response{Body: computev1.ForwardingRuleList{}}).
+		On("GET", dnsPath+"/managedZones", fakeapi.Response{Body: dnsv1beta2.ManagedZonesListResponse{}})
+
+	cli := Client{
+		projectID:      "my-project",
+		region:         "us-east1",
+		zone:           "us-east1-b",
+		instanceType:   "e2-micro",
+		computeService: newFakeComputeService(t, api),
+		dnsService:     newFakeDnsService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: "my-subnet", Timeout: time.Second})
+
+	failures, exceptions, errs := out.Parse()
+	if assert.Len(t, failures, 1) {
+		assert.Contains(t, failures[0].Error(), result.Unreachable)
+	}
+	assert.Empty(t, exceptions)
+	assert.Empty(t, errs)
+
+	// the startup script runs the pinned image with the GCP profile
+	inserts := api.Received("POST", zonePath+"/instances")
+	if assert.Len(t, inserts, 1) {
+		var instance computev1.Instance
+		assert.NoError(t, json.Unmarshal(inserts[0].Body, &instance))
+		assert.Contains(t, *instance.Metadata.Items[0].Value, `docker run --env "PLATFORM=gcp"`)
+		assert.Contains(t, *instance.Metadata.Items[0].Value, helpers.NetworkValidatorImage)
+	}
+}
+
+func TestValidateEgressValidatorWithoutProfiles(t *testing.T) {
+	defer func(interval time.Duration) { serialPortPollInterval = interval }(serialPortPollInterval)
+	serialPortPollInterval = time.Millisecond
+
+	const zonePath = "/projects/my-project/zones/us-east1-b"
+	api := fakeapi.New(t).
+		On("POST", zonePath+"/instances", fakeapi.Response{Body: computev1.Operation{Name: "op-insert", Status: "DONE"}}).
+		On("GET", zonePath+"/instances/*/serialPort", fakeapi.Response{Body: computev1.SerialPortOutput{
+			Contents: "USERDATA BEGIN\nValidating quay.io:443\nSuccess!\nUSERDATA END\n",
+		}}).
+		On("DELETE", zonePath+"/instances/*", fakeapi.Response{Body: computev1.Operation{Name: "op-delete"}})
+
+	cli := Client{
+		projectID:      "my-project",
+		region:         "us-east1",
+		zone:           "us-east1-b",
+		instanceType:   "e2-micro",
+		computeService: newFakeComputeService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	out := cli.ValidateEgress(context.TODO(), options.EgressOptions{SubnetID: "my-subnet"})
+
+	// an image predating the endpoint profiles verified the AWS endpoints
+	_, _, errs := out.Parse()
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "doesn't support endpoint profiles")
+	}
+	assert.Len(t, api.Received("DELETE", zonePath+"/instances/*"), 1)
+}
+
 func TestNewClient(t *testing.T) {
 	ctx := context.TODO()
 	logger := &ocmlog.StdLogger{}
 	credentials := &google.Credentials{ProjectID: "my-sample-project-191923"}
 	region := "superstable-region1-z"
+	zone := "superstable-region1-z-b"
+	hostProject := "my-host-project-191923"
 	instanceType := "test-instance"
 	tags := map[string]string{"osd-network-verifier": "owned"}
-	client, err := NewClient(ctx, logger, credentials, region, instanceType, tags)
+	client, err := NewClient(ctx, logger, credentials, hostProject, region, zone, instanceType, tags)
 	if err != nil {
 		t.Errorf("unexpected error creating client: %v", err)
 	}
 	if client.projectID != credentials.ProjectID {
 		t.Errorf("unexpected project ID: %v", client.projectID)
 	}
+	if client.networkProject() != hostProject {
+		t.Errorf("unexpected host project: %v", client.networkProject())
+	}
 	if client.region != region {
 		t.Errorf("unexpected region: %v", client.region)
 	}
+	if client.zone != zone {
+		t.Errorf("unexpected zone: %v", client.zone)
+	}
 	if client.tags["osd-network-verifier"] != "owned" {
 		t.Errorf("unexpected tags: %v", client.tags)
 	}
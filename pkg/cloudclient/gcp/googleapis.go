@@ -0,0 +1,193 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	computev1 "google.golang.org/api/compute/v1"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
+)
+
+const (
+	// restrictedGoogleAPIs is the Private Google Access name reaching the APIs supported by VPC Service Controls only
+	restrictedGoogleAPIs = "restricted.googleapis.com"
+	// vpcScBundle is the bundle of the PSC endpoints reaching the APIs supported by VPC Service Controls only
+	vpcScBundle = "vpc-sc"
+)
+
+// privateGoogleAccessRanges are the ranges private.googleapis.com and restricted.googleapis.com resolve to
+var privateGoogleAccessRanges = map[string]string{
+	"private.googleapis.com": "199.36.153.8/30",
+	restrictedGoogleAPIs:     "199.36.153.4/30",
+}
+
+// pscEndpoint is a Private Service Connect endpoint for Google APIs: a global forwarding rule targeting an API bundle
+type pscEndpoint struct {
+	name    string
+	address string
+	// bundle is all-apis, or vpc-sc for the APIs supported by VPC Service Controls
+	bundle string
+}
+
+// googleAPIsPath is how the instances of a subnetwork reach Google APIs
+type googleAPIsPath struct {
+	description string
+	// restricted is true when only the APIs supported by VPC Service Controls are reachable
+	restricted bool
+}
+
+// pscEndpoints returns the Private Service Connect endpoints for Google APIs of the network
+func (c *Client) pscEndpoints(ctx context.Context, network string) ([]pscEndpoint, error) {
+	var endpoints []pscEndpoint
+	err := c.computeService.GlobalForwardingRules.List(c.networkProject()).Pages(ctx, func(page *computev1.ForwardingRuleList) error {
+		for _, rule := range page.Items {
+			if (rule.Target == "all-apis" || rule.Target == vpcScBundle) && c.networkKey(rule.Network) == network {
+				endpoints = append(endpoints, pscEndpoint{name: rule.Name, address: rule.IPAddress, bundle: rule.Target})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the global forwarding rules: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// googleAPIsAddresses follows the *.googleapis.com record of the zone, a wildcard A record or a CNAME to a name of the
+// zone, and returns the addresses Google APIs resolve to
+func googleAPIsAddresses(rrsets []*dnsv1beta2.ResourceRecordSet) []string {
+	records := map[string]*dnsv1beta2.ResourceRecordSet{}
+	for _, rrset := range rrsets {
+		if rrset.Type == "A" || rrset.Type == "CNAME" {
+			records[helpers.NormalizeDomain(rrset.Name)] = rrset
+		}
+	}
+	name := "*.googleapis.com"
+	if r, ok := records[name]; ok && r.Type == "CNAME" && len(r.Rrdatas) == 1 {
+		name = helpers.NormalizeDomain(r.Rrdatas[0])
+	}
+	if r, ok := records[name]; ok && r.Type == "A" {
+		return r.Rrdatas
+	}
+
+	return nil
+}
+
+// pscTarget returns the PSC endpoint the records map Google APIs to, if any
+func pscTarget(rrsets []*dnsv1beta2.ResourceRecordSet, endpoints []pscEndpoint) *pscEndpoint {
+	addresses := googleAPIsAddresses(rrsets)
+	for i, e := range endpoints {
+		if helpers.Contains(addresses, e.address) {
+			return &endpoints[i]
+		}
+	}
+
+	return nil
+}
+
+// googleAPIsZoneRecords returns the records of the private googleapis.com zone bound to the network, if any
+func (c *Client) googleAPIsZoneRecords(ctx context.Context, network string) ([]*dnsv1beta2.ResourceRecordSet, error) {
+	var zone *dnsv1beta2.ManagedZone
+	err := c.dnsService.ManagedZones.List(c.networkProject()).Pages(ctx, func(page *dnsv1beta2.ManagedZonesListResponse) error {
+		for _, z := range page.ManagedZones {
+			if z.Visibility == "private" && z.PrivateVisibilityConfig != nil && z.ForwardingConfig == nil && z.PeeringConfig == nil &&
+				helpers.NormalizeDomain(z.DnsName) == "googleapis.com" && c.boundTo(network, zoneNetworks(z)) {
+				zone = z
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the managed zones: %w", err)
+	}
+	if zone == nil {
+		return nil, nil
+	}
+
+	var rrsets []*dnsv1beta2.ResourceRecordSet
+	err = c.dnsService.ResourceRecordSets.List(c.networkProject(), zone.Name).Pages(ctx, func(page *dnsv1beta2.ResourceRecordSetsListResponse) error {
+		rrsets = append(rrsets, page.Rrsets...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the records of zone %s: %w", zone.Name, err)
+	}
+
+	return rrsets, nil
+}
+
+// verifyGoogleAPIsPath reports how the subnetwork reaches Google APIs, and explains the unreachable Google API endpoints
+// Basic workflow is:
+// - ask Compute API for the subnetwork and the PSC endpoints for Google APIs of its network
+// - ask Cloud DNS API for the records of the private googleapis.com zone bound to the network, if any
+// - pick the PSC endpoint the records map Google APIs to, or the Private Google Access name, or PGA on the default names, or the public internet
+// - warn about the unreachable Google API endpoints, naming the path they were reached through
+func (c *Client) verifyGoogleAPIsPath(ctx context.Context, subnetID string, unreachable []string) {
+	subnet, err := c.computeService.Subnetworks.Get(c.networkProject(), c.region, path.Base(subnetID)).Context(ctx).Do()
+	if err != nil {
+		c.addUnknownFinding("google-apis-path", subnetID, "get the subnetwork", err)
+		return
+	}
+	network := c.networkKey(subnet.Network)
+	endpoints, err := c.pscEndpoints(ctx, network)
+	if err != nil {
+		c.addUnknownFinding("google-apis-path", subnet.Name, "list the PSC endpoints", err)
+	}
+	rrsets, err := c.googleAPIsZoneRecords(ctx, network)
+	if err != nil {
+		c.addUnknownFinding("google-apis-path", subnet.Name, "read the googleapis.com zone", err)
+	}
+
+	f := output.Finding{Check: "google-apis-path", Resource: subnet.Name, Status: output.StatusPassed}
+	var p googleAPIsPath
+	if psc := pscTarget(rrsets, endpoints); psc != nil {
+		p = googleAPIsPath{description: fmt.Sprintf("Private Service Connect endpoint %s (%s, bundle %s)", psc.name, psc.address, psc.bundle),
+			restricted: psc.bundle == vpcScBundle}
+	} else if target := privateGoogleAccessTarget(rrsets); target != "" {
+		p = googleAPIsPath{description: fmt.Sprintf("Private Google Access on %s (%s)", target, privateGoogleAccessRanges[target]),
+			restricted: target == restrictedGoogleAPIs}
+		if !subnet.PrivateIpGoogleAccess {
+			f.Status = output.StatusFailed
+			f.Message = fmt.Sprintf("the googleapis.com zone maps Google APIs to %s, which is unreachable since Private Google Access is disabled on the subnetwork", target)
+		}
+	} else if subnet.PrivateIpGoogleAccess {
+		p = googleAPIsPath{description: "Private Google Access on the default Google API names"}
+	} else {
+		p = googleAPIsPath{description: "the public internet"}
+	}
+	if f.Message == "" {
+		f.Message = "Google API traffic goes through " + p.description
+		if p.restricted {
+			f.Message += ", only the APIs supported by VPC Service Controls are reachable"
+		}
+		if len(endpoints) > 0 && !strings.HasPrefix(p.description, "Private Service Connect") {
+			var names []string
+			for _, e := range endpoints {
+				names = append(names, e.name)
+			}
+			f.Message += fmt.Sprintf(", the PSC endpoints %s are only used by their p.googleapis.com names", strings.Join(names, ", "))
+		}
+	}
+	c.output.AddFinding(f)
+
+	var failed []string
+	for _, u := range unreachable {
+		endpoint := strings.TrimPrefix(u, "Unable to reach ")
+		if host := strings.SplitN(endpoint, ":", 2)[0]; helpers.DomainCovers("googleapis.com", host) {
+			failed = append(failed, endpoint)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+	message := fmt.Sprintf("Google API endpoints %s are unreachable through %s", strings.Join(failed, ", "), p.description)
+	if p.restricted {
+		message += ", their services may not be supported by VPC Service Controls or the perimeter may deny them"
+	}
+	c.output.AddFinding(output.Finding{Check: "google-apis-path", Resource: subnet.Name, Status: output.StatusWarning, Message: message})
+}
@@ -0,0 +1,120 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+	computev1 "google.golang.org/api/compute/v1"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
+)
+
+func TestVerifyGoogleAPIsPath(t *testing.T) {
+	restrictedRecords := []*dnsv1beta2.ResourceRecordSet{
+		{Name: "*.googleapis.com.", Type: "CNAME", Rrdatas: []string{"restricted.googleapis.com."}},
+		{Name: "restricted.googleapis.com.", Type: "A", Rrdatas: []string{"199.36.153.4", "199.36.153.5", "199.36.153.6", "199.36.153.7"}},
+	}
+	unusedEndpoint := []*computev1.ForwardingRule{
+		{Name: "allapis", IPAddress: "10.10.0.6", Target: "all-apis", Network: computeURL + "/global/networks/my-network"},
+		{Name: "load-balancer", IPAddress: "10.10.0.7", Target: computeURL + "/global/targetHttpProxies/proxy", Network: computeURL + "/global/networks/my-network"},
+	}
+	tests := []struct {
+		name                string
+		privateGoogleAccess bool
+		forwardingRules     []*computev1.ForwardingRule
+		records             []*dnsv1beta2.ResourceRecordSet
+		unreachable         []string
+		expect              []output.Finding
+	}{
+		{
+			name:                "restricted VIP",
+			privateGoogleAccess: true,
+			records:             restrictedRecords,
+			unreachable:         []string{"Unable to reach quay.io:443", "Unable to reach www.googleapis.com:443"},
+			expect: []output.Finding{
+				{Check: "google-apis-path", Resource: "compute", Status: output.StatusPassed,
+					Message: "Google API traffic goes through Private Google Access on restricted.googleapis.com (199.36.153.4/30), " +
+						"only the APIs supported by VPC Service Controls are reachable"},
+				{Check: "google-apis-path", Resource: "compute", Status: output.StatusWarning,
+					Message: "Google API endpoints www.googleapis.com:443 are unreachable through Private Google Access on restricted.googleapis.com (199.36.153.4/30), " +
+						"their services may not be supported by VPC Service Controls or the perimeter may deny them"},
+			},
+		},
+		{
+			name:    "restricted VIP without Private Google Access",
+			records: restrictedRecords,
+			expect: []output.Finding{
+				{Check: "google-apis-path", Resource: "compute", Status: output.StatusFailed,
+					Message: "the googleapis.com zone maps Google APIs to restricted.googleapis.com, which is unreachable since Private Google Access is disabled on the subnetwork"},
+			},
+		},
+		{
+			name:                "default names",
+			privateGoogleAccess: true,
+			forwardingRules:     unusedEndpoint,
+			expect: []output.Finding{
+				{Check: "google-apis-path", Resource: "compute", Status: output.StatusPassed,
+					Message: "Google API traffic goes through Private Google Access on the default Google API names, the PSC endpoints allapis are only used by their p.googleapis.com names"},
+			},
+		},
+		{
+			name:        "public internet",
+			unreachable: []string{"Unable to reach compute.googleapis.com:443"},
+			expect: []output.Finding{
+				{Check: "google-apis-path", Resource: "compute", Status: output.StatusPassed,
+					Message: "Google API traffic goes through the public internet"},
+				{Check: "google-apis-path", Resource: "compute", Status: output.StatusWarning,
+					Message: "Google API endpoints compute.googleapis.com:443 are unreachable through the public internet"},
+			},
+		},
+	}
+	for _, test := range tests {
+		api := fakeapi.New(t).
+			On("GET", projectPath+"/regions/us-east1/subnetworks/compute", fakeapi.Response{Body: testSubnetwork("compute", "10.0.1.0/24", test.privateGoogleAccess)}).
+			On("GET", projectPath+"/global/forwardingRules", fakeapi.Response{Body: computev1.ForwardingRuleList{Items: test.forwardingRules}})
+		zones := dnsv1beta2.ManagedZonesListResponse{}
+		if test.records != nil {
+			zones.ManagedZones = append(zones.ManagedZones, privateZone("google-apis", "googleapis.com.", networkURL))
+			api.On("GET", dnsPath+"/managedZones/google-apis/rrsets", fakeapi.Response{Body: dnsv1beta2.ResourceRecordSetsListResponse{Rrsets: test.records}})
+		}
+		api.On("GET", dnsPath+"/managedZones", fakeapi.Response{Body: zones})
+
+		cli := Client{
+			projectID:      "my-project",
+			region:         "us-east1",
+			computeService: newFakeComputeService(t, api),
+			dnsService:     newFakeDnsService(t, api),
+			logger:         &ocmlog.StdLogger{},
+		}
+		cli.verifyGoogleAPIsPath(context.TODO(), "compute", test.unreachable)
+		assert.Equal(t, test.expect, cli.output.Findings(), test.name)
+	}
+}
+
+func TestGoogleAPIsAddresses(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []*dnsv1beta2.ResourceRecordSet
+		expect  []string
+	}{
+		{name: "wildcard A record", records: []*dnsv1beta2.ResourceRecordSet{
+			{Name: "*.googleapis.com.", Type: "A", Rrdatas: []string{"10.10.0.5"}},
+		}, expect: []string{"10.10.0.5"}},
+		{name: "wildcard CNAME", records: []*dnsv1beta2.ResourceRecordSet{
+			{Name: "*.googleapis.com.", Type: "CNAME", Rrdatas: []string{"googleapis.com."}},
+			{Name: "googleapis.com.", Type: "A", Rrdatas: []string{"10.10.0.5"}},
+		}, expect: []string{"10.10.0.5"}},
+		{name: "CNAME outside the zone", records: []*dnsv1beta2.ResourceRecordSet{
+			{Name: "*.googleapis.com.", Type: "CNAME", Rrdatas: []string{"apis.example.com."}},
+		}},
+		{name: "no wildcard", records: []*dnsv1beta2.ResourceRecordSet{
+			{Name: "storage.googleapis.com.", Type: "A", Rrdatas: []string{"10.10.0.5"}},
+		}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expect, googleAPIsAddresses(test.records), test.name)
+	}
+}
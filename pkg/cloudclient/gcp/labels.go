@@ -0,0 +1,88 @@
+package gcp
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxLabelLength is the maximum length of label keys and values, and of network tags
+const maxLabelLength = 63
+
+// labels translates the cloud tags into GCP labels.
+// Keys and values are lowercased, and the characters other than letters, digits, '_' and '-' are replaced with '_'.
+// Keys must start with a letter, others are prefixed with "x". Keys and values are truncated to 63 characters.
+// When several tags map to the same label, the first tag in key order wins, e.g. "Name" over "name".
+func labels(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(tags))
+	for _, key := range sortedKeys(tags) {
+		label := sanitizeLabel(key, '_')
+		if label == "" || label[0] < 'a' || label[0] > 'z' {
+			label = truncate("x" + label)
+		}
+		if _, ok := labels[label]; !ok {
+			labels[label] = sanitizeLabel(tags[key], '_')
+		}
+	}
+
+	return labels
+}
+
+// networkTags translates the keys of the cloud tags into GCP network tags, so that firewall rules can target the probe.
+// Network tags follow RFC 1035: keys are lowercased, the characters other than letters, digits and '-' are replaced
+// with '-', keys not starting with a letter are prefixed with "x-", and the trailing '-' are trimmed.
+func networkTags(tags map[string]string) []string {
+	var networkTags []string
+	seen := map[string]bool{}
+	for _, key := range sortedKeys(tags) {
+		tag := sanitizeLabel(key, '-')
+		if tag == "" || tag[0] < 'a' || tag[0] > 'z' {
+			tag = truncate("x-" + tag)
+		}
+		tag = strings.TrimRight(tag, "-")
+		if !seen[tag] {
+			seen[tag] = true
+			networkTags = append(networkTags, tag)
+		}
+	}
+
+	return networkTags
+}
+
+// sanitizeLabel lowercases s, replaces the characters other than ASCII letters, digits, '-' and the replacement
+// with the replacement, and truncates the result to 63 characters
+func sanitizeLabel(s string, replacement rune) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == replacement:
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return replacement
+	}, s)
+
+	return truncate(s)
+}
+
+// truncate returns the first 63 characters of s, which holds ASCII characters only
+func truncate(s string) string {
+	if len(s) > maxLabelLength {
+		return s[:maxLabelLength]
+	}
+
+	return s
+}
+
+// sortedKeys returns the keys of the map in lexical order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,44 @@
+package gcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		tags   map[string]string
+		expect map[string]string
+	}{
+		{name: "no tags"},
+		{name: "valid", tags: map[string]string{"red-hat-managed": "true", "cost_center": "1234"},
+			expect: map[string]string{"red-hat-managed": "true", "cost_center": "1234"}},
+		{name: "uppercase and invalid characters", tags: map[string]string{"Cluster.Name": "My Cluster/01", "team@acme": ""},
+			expect: map[string]string{"cluster_name": "my_cluster_01", "team_acme": ""}},
+		{name: "key not starting with a letter", tags: map[string]string{"1st": "a", "_x": "b"},
+			expect: map[string]string{"x1st": "a", "x_x": "b"}},
+		{name: "collisions keep the first key", tags: map[string]string{"Name": "upper", "name": "lower", "NAME": "all"},
+			expect: map[string]string{"name": "all"}},
+		{name: "truncated", tags: map[string]string{strings.Repeat("k", 70): strings.Repeat("V", 70)},
+			expect: map[string]string{strings.Repeat("k", 63): strings.Repeat("v", 63)}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expect, labels(test.tags), test.name)
+	}
+}
+
+func TestNetworkTags(t *testing.T) {
+	tags := map[string]string{
+		"osd-network-verifier":  "owned",
+		"Red_Hat_Managed":       "true",
+		"red-hat-managed":       "false",
+		"9lives":                "",
+		"trailing_":             "",
+		strings.Repeat("t", 70): "",
+	}
+	assert.Equal(t, []string{"x-9lives", "red-hat-managed", "osd-network-verifier", "trailing", strings.Repeat("t", 63)}, networkTags(tags))
+	assert.Nil(t, networkTags(nil))
+}
@@ -0,0 +1,310 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/options"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"golang.org/x/oauth2/google"
+	computev1 "google.golang.org/api/compute/v1"
+	dnsv1beta2 "google.golang.org/api/dns/v1beta2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+var (
+	// Container-Optimized OS ships docker, which runs the network validator image
+	defaultImage               = "projects/cos-cloud/global/images/family/cos-stable"
+	defaultInstanceType        = "e2-micro"
+	userdataEndVerifier string = "USERDATA END"
+
+	// The probe usually completes within a few minutes, the intervals can be shortened in tests
+	operationPollInterval  = 5 * time.Second
+	operationPollTimeout   = 2 * time.Minute
+	serialPortPollInterval = 30 * time.Second
+	serialPortPollTimeout  = 4 * time.Minute
+)
+
+func newClient(ctx context.Context, logger ocmlog.Logger, credentials *google.Credentials, hostProject, region, zone, instanceType string, tags map[string]string) (*Client, error) {
+	httpClient, _, err := htransport.NewClient(ctx, option.WithCredentials(credentials))
+	if err != nil {
+		return nil, err
+	}
+	computeService, err := computev1.NewService(ctx, option.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+	// response policies are only available in the v1beta2 Cloud DNS API
+	dnsService, err := dnsv1beta2.NewService(ctx, option.WithCredentials(credentials))
+	if err != nil {
+		return nil, err
+	}
+	if instanceType == "" {
+		instanceType = defaultInstanceType
+	}
+
+	return &Client{
+		projectID:      credentials.ProjectID,
+		hostProjectID:  hostProject,
+		region:         region,
+		zone:           zone,
+		instanceType:   instanceType,
+		computeService: computeService,
+		httpClient:     httpClient,
+		dnsService:     dnsService,
+		tags:           tags,
+		logger:         logger,
+	}, nil
+}
+
+// validateEgress performs validation process for egress
+// Basic workflow is:
+// - run the probe instance in the subnetwork
+// - make sure the network validator verified the endpoint profile of the platform
+// - find unreachable endpoints & parse output
+// - report the path Google API traffic goes through: PSC, Private Google Access or the public internet
+// - return `c.output` which stores the execution results
+func (c *Client) validateEgress(ctx context.Context, opts options.EgressOptions) *output.Output {
+	probeOutput, err := c.runProbe(ctx, opts)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	if helpers.ValidatorIgnoredProfile(probeOutput, PlatformName) {
+		return c.output.AddError(fmt.Errorf("network validator image %s doesn't support endpoint profiles, "+
+			"it verified the AWS endpoints instead of the %s ones", helpers.NetworkValidatorImage, PlatformName)) // fatal
+	}
+	unreachable := c.addEgressResults(probeOutput)
+	c.verifyGoogleAPIsPath(ctx, opts.SubnetID, unreachable)
+
+	return &c.output
+}
+
+// runProbe launches the probe instance in the subnetwork and returns the serial port output of the network validator
+// Basic workflow is:
+// - generate the startup script running the network validator image
+// - pick a zone of the region and create the instance
+// - once the creation is accepted, wait for the operation to complete
+// - gather the serial port output once the startup script has completed
+// - delete the instance, even if its creation did not complete
+func (c *Client) runProbe(ctx context.Context, opts options.EgressOptions) (string, error) {
+	c.logger.Debug(ctx, "Using configured timeout of %s for each egress request", opts.Timeout.String())
+	startupScript := os.Expand(helpers.StartupScriptTemplate, func(varName string) string {
+		return map[string]string{
+			"PLATFORM":                 PlatformName,
+			"GCP_REGION":               c.region,
+			"USERDATA_BEGIN":           "USERDATA BEGIN",
+			"USERDATA_END":             userdataEndVerifier,
+			"VALIDATOR_START_VERIFIER": "VALIDATOR START",
+			"VALIDATOR_END_VERIFIER":   "VALIDATOR END",
+			"VALIDATOR_IMAGE":          helpers.NetworkValidatorImage,
+			"TIMEOUT":                  opts.Timeout.String(),
+		}[varName]
+	})
+	c.logger.Debug(ctx, "Generated startup script:\n---\n%s\n---", startupScript)
+
+	zone, err := c.pickZone(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	name, err := probeName()
+	if err != nil {
+		return "", err
+	}
+	op, err := c.createInstance(ctx, zone, name, startupScript, opts)
+	if err != nil {
+		return "", err
+	}
+	// try to delete the created instance whatever happens, the operation may time out after the instance was created
+	defer c.deleteInstance(ctx, zone, name)
+
+	c.logger.Debug(ctx, "Waiting for instance %s to be created", name)
+	if err := c.waitForZoneOperation(ctx, zone, op); err != nil {
+		return "", err
+	}
+
+	c.logger.Info(ctx, "Gathering and parsing serial port output...")
+	return c.getProbeOutput(ctx, zone, name)
+}
+
+// probeName returns a random name for the probe instance, so that concurrent runs don't collide
+func probeName() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("unable to generate the instance name: %w", err)
+	}
+
+	return fmt.Sprintf("osd-network-verifier-%x", suffix), nil
+}
+
+// pickZone returns the client's zone if set, or else the first zone of the client's region, in lexical order
+func (c *Client) pickZone(ctx context.Context) (string, error) {
+	if c.zone != "" {
+		return c.zone, nil
+	}
+	region, err := c.computeService.Regions.Get(c.projectID, c.region).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to get region %s: %w", c.region, err)
+	}
+	if len(region.Zones) == 0 {
+		return "", fmt.Errorf("region %s has no zone", c.region)
+	}
+	zones := make([]string, 0, len(region.Zones))
+	for _, z := range region.Zones {
+		// zones are given as URLs, e.g. https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b
+		zones = append(zones, path.Base(z))
+	}
+	sort.Strings(zones)
+
+	return zones[0], nil
+}
+
+// subnetworkURL returns the partial URL of the subnetwork, which may be given by name or URL
+func (c *Client) subnetworkURL(subnetwork string) string {
+	if strings.Contains(subnetwork, "/") {
+		return subnetwork
+	}
+
+	return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", c.networkProject(), c.region, subnetwork)
+}
+
+// createInstance requests the creation of the probe instance without external address, so that egress goes through
+// the subnetwork's Cloud NAT or proxy like the cluster's nodes. The instance and its disk are labelled with the cloud tags.
+// It returns the insert operation without waiting for it to complete.
+func (c *Client) createInstance(ctx context.Context, zone, name, startupScript string, opts options.EgressOptions) (*computev1.Operation, error) {
+	image := opts.CloudImageID
+	if image == "" {
+		image = defaultImage
+	}
+	disk := &computev1.AttachedDisk{
+		AutoDelete: true,
+		Boot:       true,
+		InitializeParams: &computev1.AttachedDiskInitializeParams{
+			SourceImage: image,
+			// label the boot disk too, so that it can be found and accounted for like the instance
+			Labels: labels(c.tags),
+		},
+	}
+	if opts.KmsKeyID != "" {
+		disk.DiskEncryptionKey = &computev1.CustomerEncryptionKey{KmsKeyName: opts.KmsKeyID}
+	}
+
+	instance := &computev1.Instance{
+		Name:        name,
+		Description: "osd-network-verifier egress probe",
+		MachineType: fmt.Sprintf("zones/%s/machineTypes/%s", zone, c.instanceType),
+		Disks:       []*computev1.AttachedDisk{disk},
+		Labels:      labels(c.tags),
+		Tags:        &computev1.Tags{Items: networkTags(c.tags)},
+		NetworkInterfaces: []*computev1.NetworkInterface{{
+			Subnetwork: c.subnetworkURL(opts.SubnetID),
+		}},
+		Metadata: &computev1.Metadata{
+			Items: []*computev1.MetadataItems{{Key: "startup-script", Value: &startupScript}},
+		},
+	}
+
+	c.logger.Info(ctx, "Creating instance %s in zone %s", name, zone)
+	op, err := c.computeService.Instances.Insert(c.projectID, zone, instance).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create instance %s: %w", name, err)
+	}
+
+	return op, nil
+}
+
+// waitForZoneOperation polls the operation until it is done, and returns the first error it reported
+func (c *Client) waitForZoneOperation(ctx context.Context, zone string, op *computev1.Operation) error {
+	err := helpers.PollImmediate(operationPollInterval, operationPollTimeout, func() (bool, error) {
+		if op.Status == "DONE" {
+			return true, nil
+		}
+		var err error
+		op, err = c.computeService.ZoneOperations.Get(c.projectID, zone, op.Name).Context(ctx).Do()
+		if err != nil {
+			return false, err
+		}
+
+		return op.Status == "DONE", nil
+	})
+	if err != nil {
+		return err
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		e := op.Error.Errors[0]
+		return fmt.Errorf("operation %s on %s failed: %s: %s", op.OperationType, path.Base(op.TargetLink), e.Code, e.Message)
+	}
+
+	return nil
+}
+
+// getProbeOutput waits for the startup script of the instance to complete and returns its serial port output
+func (c *Client) getProbeOutput(ctx context.Context, zone, name string) (string, error) {
+	reVerify := regexp.MustCompile(userdataEndVerifier)
+
+	var contents strings.Builder
+	var start int64
+	err := helpers.PollImmediate(serialPortPollInterval, serialPortPollTimeout, func() (bool, error) {
+		out, err := c.computeService.Instances.GetSerialPortOutput(c.projectID, zone, name).Port(1).Start(start).Context(ctx).Do()
+		if err != nil {
+			return false, err
+		}
+		// the serial port output is read incrementally, from where the previous read stopped
+		contents.WriteString(out.Contents)
+		start = out.Next
+
+		if !reVerify.MatchString(contents.String()) {
+			c.logger.Debug(ctx, "Serial port output of %s doesn't contain the end of the startup script yet, continuing to wait...", name)
+			return false, nil
+		}
+		c.logger.Debug(ctx, "Full serial port output:\n---\n%s\n---", contents.String())
+
+		return true, nil
+	})
+
+	return contents.String(), err
+}
+
+// addEgressResults reports the endpoints the probe could not reach, and returns them
+func (c *Client) addEgressResults(probeOutput string) []string {
+	// check output failures, report as exception if they occurred
+	var rgx = regexp.MustCompile(`(?m)^(.*Cannot.*)|(.*Could not.*)|(.*Failed.*)|(.*command not found.*)`)
+	if len(rgx.FindAllStringSubmatch(probeOutput, -1)) > 0 {
+		c.output.AddException(handledErrors.NewGenericError(
+			"internet connectivity problem: please ensure there's internet access in given vpc subnets"))
+	}
+
+	reUnreachableErrors := regexp.MustCompile(`Unable to reach (\S+)`)
+	unreachable := reUnreachableErrors.FindAllString(probeOutput, -1)
+	c.output.SetEgressFailures(unreachable)
+
+	return unreachable
+}
+
+// deleteInstance deletes the probe instance, without waiting for the deletion to complete.
+// An instance whose creation failed is not found, which is not an error.
+// uses c.output to store result of the execution
+func (c *Client) deleteInstance(ctx context.Context, zone, name string) {
+	c.logger.Info(ctx, "Deleting instance %s", name)
+	_, err := c.computeService.Instances.Delete(c.projectID, zone, name).Context(ctx).Do()
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		c.logger.Debug(ctx, "Instance %s was not created, there is nothing to delete", name)
+		return
+	}
+	c.output.AddError(err)
+}
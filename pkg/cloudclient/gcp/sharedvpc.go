@@ -0,0 +1,122 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+const (
+	// networkUserRole lets the identities of a service project create instances in a Shared VPC subnetwork
+	networkUserRole = "roles/compute.networkUser"
+	// subnetworkUsePermission is the permission of networkUserRole the probe instance needs
+	subnetworkUsePermission = "compute.subnetworks.use"
+)
+
+// networkProject returns the project owning the network resources: the Shared VPC host project if any,
+// or else the project of the credentials
+func (c *Client) networkProject() string {
+	if c.hostProjectID != "" {
+		return c.hostProjectID
+	}
+
+	return c.projectID
+}
+
+// sharedVPC returns true if the network belongs to another project than the probe instances
+func (c *Client) sharedVPC() bool {
+	return c.networkProject() != c.projectID
+}
+
+// checkSharedVPC verifies the project is a service project of the Shared VPC host project, allowed to use the subnetworks
+// Basic workflow is:
+// - ask Compute API for the host project the project is attached to, and ensure it is the expected one
+// - ensure the credentials have compute.subnetworks.use on every subnetwork, so that instances can be created in it
+// - ensure a service account of the project is granted roles/compute.networkUser on every subnetwork
+func (c *Client) checkSharedVPC(ctx context.Context, subnets []*computev1.Subnetwork) {
+	host, err := c.computeService.Projects.GetXpnHost(c.projectID).Context(ctx).Do()
+	switch {
+	case err != nil:
+		c.addUnknownFinding("shared-vpc", c.projectID, "get the Shared VPC host project", err)
+	case host.Name == "":
+		c.output.AddFinding(output.Finding{Check: "shared-vpc", Resource: c.projectID, Status: output.StatusFailed,
+			Message: fmt.Sprintf("project is not attached to a Shared VPC host project, it cannot use the network of project %s", c.networkProject())})
+	case host.Name != c.networkProject():
+		c.output.AddFinding(output.Finding{Check: "shared-vpc", Resource: c.projectID, Status: output.StatusFailed,
+			Message: fmt.Sprintf("project is attached to Shared VPC host project %s, not %s", host.Name, c.networkProject())})
+	default:
+		c.output.AddFinding(output.Finding{Check: "shared-vpc", Resource: c.projectID, Status: output.StatusPassed,
+			Message: fmt.Sprintf("service project of Shared VPC host project %s", host.Name)})
+	}
+
+	serviceAccounts := c.serviceAccountSuffixes(ctx)
+	for _, subnet := range subnets {
+		c.checkSubnetIam(ctx, subnet, serviceAccounts)
+	}
+}
+
+// serviceAccountSuffixes returns the suffixes of the IAM members of the project's service accounts: the user-managed ones,
+// and the Google APIs and Compute Engine default service accounts, which are named after the project number
+func (c *Client) serviceAccountSuffixes(ctx context.Context) []string {
+	suffixes := []string{"@" + c.projectID + ".iam.gserviceaccount.com"}
+	project, err := c.computeService.Projects.Get(c.projectID).Context(ctx).Do()
+	if err != nil {
+		c.logger.Debug(ctx, "Unable to get the number of project %s, only its user-managed service accounts are looked for: %s", c.projectID, err)
+		return suffixes
+	}
+
+	return append(suffixes,
+		fmt.Sprintf("serviceAccount:%d@cloudservices.gserviceaccount.com", project.Id),
+		fmt.Sprintf("serviceAccount:%d-compute@developer.gserviceaccount.com", project.Id))
+}
+
+// checkSubnetIam ensures the credentials can use the subnetwork, and that a service account of the project
+// is granted roles/compute.networkUser on it. A role granted on the whole host project isn't visible on the
+// subnetwork's policy, the missing grant is reported as a warning.
+func (c *Client) checkSubnetIam(ctx context.Context, subnet *computev1.Subnetwork, serviceAccounts []string) {
+	perms, err := c.computeService.Subnetworks.TestIamPermissions(c.networkProject(), c.region, subnet.Name,
+		&computev1.TestPermissionsRequest{Permissions: []string{subnetworkUsePermission}}).Context(ctx).Do()
+	if err != nil {
+		c.addUnknownFinding("subnet-iam", subnet.Name, "test the permissions on the subnetwork", err)
+		return
+	}
+	if !helpers.Contains(perms.Permissions, subnetworkUsePermission) {
+		c.output.AddFinding(output.Finding{Check: "subnet-iam", Resource: subnet.Name, Status: output.StatusFailed,
+			Message: fmt.Sprintf("the credentials lack %s on the subnetwork, grant %s on it to the identities of project %s",
+				subnetworkUsePermission, networkUserRole, c.projectID)})
+		return
+	}
+
+	policy, err := c.computeService.Subnetworks.GetIamPolicy(c.networkProject(), c.region, subnet.Name).Context(ctx).Do()
+	if err != nil {
+		// service project identities usually can't read the policies of the host project, the grant is only checked when visible
+		c.addUncheckedFinding(ctx, "subnet-iam", subnet.Name, "get the IAM policy of the subnetwork", err)
+		return
+	}
+	var granted []string
+	for _, binding := range policy.Bindings {
+		if binding.Role != networkUserRole {
+			continue
+		}
+		for _, member := range binding.Members {
+			for _, suffix := range serviceAccounts {
+				if strings.HasSuffix(member, suffix) {
+					granted = append(granted, strings.TrimPrefix(member, "serviceAccount:"))
+					break
+				}
+			}
+		}
+	}
+	if len(granted) == 0 {
+		c.output.AddFinding(output.Finding{Check: "subnet-iam", Resource: subnet.Name, Status: output.StatusWarning,
+			Message: fmt.Sprintf("no service account of project %s is granted %s on the subnetwork, the cluster cannot use it unless the role is granted on host project %s",
+				c.projectID, networkUserRole, c.networkProject())})
+		return
+	}
+	c.output.AddFinding(output.Finding{Check: "subnet-iam", Resource: subnet.Name, Status: output.StatusPassed,
+		Message: fmt.Sprintf("%s is granted to %s", networkUserRole, strings.Join(granted, ", "))})
+}
@@ -0,0 +1,101 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/internal/fakeapi"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+const serviceProjectPath = "/projects/my-service-project"
+
+func TestCheckSharedVPC(t *testing.T) {
+	subnetsPath := projectPath + "/regions/us-east1/subnetworks/"
+	api := fakeapi.New(t).
+		On("GET", serviceProjectPath+"/getXpnHost", fakeapi.Response{Body: computev1.Project{Name: "my-project"}}).
+		On("GET", serviceProjectPath, fakeapi.Response{Body: computev1.Project{Name: "my-service-project", Id: 123456}}).
+		On("POST", subnetsPath+"control-plane/testIamPermissions", fakeapi.Response{Body: computev1.TestPermissionsResponse{Permissions: []string{"compute.subnetworks.use"}}}).
+		On("POST", subnetsPath+"compute/testIamPermissions", fakeapi.Response{Body: computev1.TestPermissionsResponse{Permissions: []string{"compute.subnetworks.use"}}}).
+		On("POST", subnetsPath+"other/testIamPermissions", fakeapi.Response{Body: computev1.TestPermissionsResponse{}}).
+		On("POST", subnetsPath+"restricted/testIamPermissions", fakeapi.Response{Body: computev1.TestPermissionsResponse{Permissions: []string{"compute.subnetworks.use"}}}).
+		On("GET", subnetsPath+"restricted/getIamPolicy", googleAPIError(403, "forbidden", "Required 'compute.subnetworks.getIamPolicy' permission")).
+		On("GET", subnetsPath+"control-plane/getIamPolicy", fakeapi.Response{Body: computev1.Policy{Bindings: []*computev1.Binding{
+			{Role: "roles/compute.networkUser", Members: []string{
+				"serviceAccount:installer@my-service-project.iam.gserviceaccount.com",
+				"serviceAccount:123456@cloudservices.gserviceaccount.com",
+				"serviceAccount:9123456@cloudservices.gserviceaccount.com",
+			}},
+		}}}).
+		On("GET", subnetsPath+"compute/getIamPolicy", fakeapi.Response{Body: computev1.Policy{Bindings: []*computev1.Binding{
+			{Role: "roles/compute.networkViewer", Members: []string{"serviceAccount:installer@my-service-project.iam.gserviceaccount.com"}},
+			{Role: "roles/compute.networkUser", Members: []string{"serviceAccount:installer@other-project.iam.gserviceaccount.com"}},
+		}}})
+
+	cli := Client{
+		projectID:      "my-service-project",
+		hostProjectID:  "my-project",
+		region:         "us-east1",
+		computeService: newFakeComputeService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	assert.True(t, cli.sharedVPC())
+	cli.checkSharedVPC(context.TODO(), []*computev1.Subnetwork{
+		testSubnetwork("control-plane", "10.0.0.0/24", true),
+		testSubnetwork("compute", "10.0.1.0/24", true),
+		testSubnetwork("other", "10.0.2.0/24", true),
+		testSubnetwork("restricted", "10.0.3.0/24", true),
+	})
+
+	assert.Equal(t, []output.Finding{
+		{Check: "shared-vpc", Resource: "my-service-project", Status: output.StatusPassed,
+			Message: "service project of Shared VPC host project my-project"},
+		{Check: "subnet-iam", Resource: "control-plane", Status: output.StatusPassed,
+			Message: "roles/compute.networkUser is granted to installer@my-service-project.iam.gserviceaccount.com, 123456@cloudservices.gserviceaccount.com"},
+		{Check: "subnet-iam", Resource: "compute", Status: output.StatusWarning,
+			Message: "no service account of project my-service-project is granted roles/compute.networkUser on the subnetwork, " +
+				"the cluster cannot use it unless the role is granted on host project my-project"},
+		{Check: "subnet-iam", Resource: "other", Status: output.StatusFailed,
+			Message: "the credentials lack compute.subnetworks.use on the subnetwork, grant roles/compute.networkUser on it to the identities of project my-service-project"},
+		{Check: "subnet-iam", Resource: "restricted", Status: output.StatusUnknown,
+			Message: "unable to get the IAM policy of the subnetwork: access denied (forbidden)"},
+	}, cli.output.Findings())
+	// the IAM policy of a host project subnetwork is usually not visible to the service project
+	_, _, errs := cli.output.Parse()
+	assert.Empty(t, errs)
+}
+
+func TestCheckSharedVPCNotAttached(t *testing.T) {
+	api := fakeapi.New(t).
+		On("GET", serviceProjectPath+"/getXpnHost", fakeapi.Response{Body: computev1.Project{}}).
+		On("GET", serviceProjectPath, fakeapi.Response{Body: computev1.Project{Name: "my-service-project", Id: 123456}})
+
+	cli := Client{
+		projectID:      "my-service-project",
+		hostProjectID:  "my-project",
+		region:         "us-east1",
+		computeService: newFakeComputeService(t, api),
+		logger:         &ocmlog.StdLogger{},
+	}
+	cli.checkSharedVPC(context.TODO(), nil)
+
+	assert.Equal(t, []output.Finding{
+		{Check: "shared-vpc", Resource: "my-service-project", Status: output.StatusFailed,
+			Message: "project is not attached to a Shared VPC host project, it cannot use the network of project my-project"},
+	}, cli.output.Findings())
+	assert.False(t, cli.output.IsSuccessful())
+}
+
+func TestNetworkProject(t *testing.T) {
+	cli := Client{projectID: "my-project"}
+	assert.Equal(t, "my-project", cli.networkProject())
+	assert.False(t, cli.sharedVPC())
+	assert.Equal(t, "projects/my-project/regions/us-east1/subnetworks/compute", (&Client{projectID: "my-project", region: "us-east1"}).subnetworkURL("compute"))
+
+	cli = Client{projectID: "my-service-project", hostProjectID: "my-project", region: "us-east1"}
+	assert.Equal(t, "projects/my-project/regions/us-east1/subnetworks/compute", cli.subnetworkURL("compute"))
+	assert.Equal(t, "projects/my-project/global/networks/my-network", cli.networkKey("my-network"))
+}
@@ -0,0 +1,124 @@
+// Package fakeapi serves canned JSON responses to the REST API clients of the cloud providers in tests
+package fakeapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Response is a canned response of the fake server, with status 200 unless set.
+// Header values starting with "/" are URLs of the fake server, e.g. the Location of an operation.
+type Response struct {
+	Status int
+	Header map[string]string
+	Body   interface{}
+}
+
+// Request is a request received by the fake server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+// Server serves canned JSON responses to REST API requests.
+// Responses are keyed by "METHOD /path", where the path may hold path.Match patterns,
+// and are served in order, the last one being repeated.
+type Server struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses map[string][]Response
+	requests  []Request
+	server    *httptest.Server
+}
+
+// New returns a fake server without responses, failing the test on the requests it has no response for
+func New(t *testing.T) *Server {
+	return &Server{t: t, responses: map[string][]Response{}}
+}
+
+// On adds responses to the requests matching the method and path pattern
+func (s *Server) On(method, pattern string, responses ...Response) *Server {
+	key := method + " " + pattern
+	s.responses[key] = append(s.responses[key], responses...)
+	return s
+}
+
+// Received returns the requests received for the method and path pattern
+func (s *Server) Received(method, pattern string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matching []Request
+	for _, r := range s.requests {
+		if ok, _ := path.Match(pattern, r.Path); ok && r.Method == method {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
+// Start serves the fake API until the test completes, and returns its test server. Later calls return the same server.
+func (s *Server) Start() *httptest.Server {
+	if s.server == nil {
+		s.server = httptest.NewServer(s)
+		s.t.Cleanup(s.server.Close)
+	}
+	return s.server
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Body: body})
+
+	for key, responses := range s.responses {
+		parts := strings.SplitN(key, " ", 2)
+		method, pattern := parts[0], parts[1]
+		if ok, _ := path.Match(pattern, r.URL.Path); !ok || method != r.Method {
+			continue
+		}
+		response := responses[0]
+		if len(responses) > 1 {
+			s.responses[key] = responses[1:]
+		}
+		if response.Status == 0 {
+			response.Status = http.StatusOK
+		}
+		for name, value := range response.Header {
+			if strings.HasPrefix(value, "/") {
+				value = "http://" + r.Host + value
+			}
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(response.Status)
+		if response.Body == nil {
+			return
+		}
+		if err := json.NewEncoder(w).Encode(response.Body); err != nil {
+			s.t.Errorf("unable to encode the response to %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		return
+	}
+
+	s.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// JSON decodes a JSON document written as a string, so that the responses read like the API documentation
+func JSON(t *testing.T, doc string) interface{} {
+	var body interface{}
+	if err := json.Unmarshal([]byte(doc), &body); err != nil {
+		t.Fatalf("invalid JSON body %s: %v", doc, err)
+	}
+	return body
+}
@@ -0,0 +1,132 @@
+// Package validatortest runs the network validator of build/bin in tests, so that the probes are tested against the
+// output of the validator NetworkValidatorImage is built from rather than against hand-written output
+package validatortest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ResolvedAddress is the address the fake resolver answers every query with
+const ResolvedAddress = "203.0.113.10"
+
+// Result is the output of a validator run, between the markers the probe scripts print around it
+type Result struct {
+	Output string
+	// Reachable and Unreachable are the endpoints of the platform's profile, the first one listening and the other one not
+	Reachable   string
+	Unreachable string
+	// Resolver is the address of the fake resolver the validator queried
+	Resolver string
+}
+
+// Run builds the network validator and runs it as the probe scripts do, with the platform's profile holding a listening
+// and a closed local endpoint, and a fake resolver answering every query
+func Run(t *testing.T, platform string) Result {
+	t.Helper()
+	dir := t.TempDir()
+	validator := filepath.Join(dir, "network-validator")
+	build := exec.Command("go", "build", "-o", validator, "github.com/openshift/osd-network-verifier/build/bin")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("unable to build the network validator: %v\n%s", err, out)
+	}
+
+	reachable := listen(t)
+	unreachable := closedPort(t)
+	resolver := serveDNS(t)
+	config := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(config, []byte(fmt.Sprintf("endpoints: []\nprofiles:\n  %s:\n"+
+		"  - host: 127.0.0.1\n    ports: [%d, %d]\n", platform, reachable, unreachable)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := exec.Command(validator, "--config="+config, "--timeout=1s", "--max-retries=1", "--resolver="+resolver)
+	run.Env = append(os.Environ(), "PLATFORM="+platform)
+	out, err := run.Output()
+	if err != nil {
+		t.Fatalf("unable to run the network validator: %v\n%s", err, out)
+	}
+
+	return Result{
+		Output:      "USERDATA BEGIN\n" + string(out) + "USERDATA END\n",
+		Reachable:   net.JoinHostPort("127.0.0.1", strconv.Itoa(reachable)),
+		Unreachable: net.JoinHostPort("127.0.0.1", strconv.Itoa(unreachable)),
+		Resolver:    resolver,
+	}
+}
+
+// listen accepts TCP connections on a local port until the test ends
+func listen(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// serveDNS answers every A query with ResolvedAddress until the test ends, and returns the address of the resolver
+func serveDNS(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var resolved [4]byte
+	copy(resolved[:], net.ParseIP(ResolvedAddress).To4())
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			answer := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.Header.ID, Response: true, RecursionAvailable: true},
+				Questions: query.Questions,
+				Answers: []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: resolved},
+				}},
+			}
+			if packed, err := answer.Pack(); err == nil {
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
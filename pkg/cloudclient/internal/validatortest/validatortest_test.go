@@ -0,0 +1,22 @@
+package validatortest
+
+import (
+	"testing"
+
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	for _, platform := range []string{"aws", "gcp", "azure"} {
+		result := Run(t, platform)
+
+		assert.Contains(t, result.Output, helpers.ValidatorProfileLine(platform), platform)
+		assert.False(t, helpers.ValidatorIgnoredProfile(result.Output, platform), platform)
+		assert.Contains(t, result.Output, "DNS resolver: "+result.Resolver, platform)
+		assert.Contains(t, result.Output, "DNS result 127.0.0.1 via "+result.Resolver+": OK "+ResolvedAddress, platform)
+		assert.Contains(t, result.Output, "Not all endpoints were reachable", platform)
+		assert.Contains(t, result.Output, "Unable to reach "+result.Unreachable, platform)
+		assert.NotContains(t, result.Output, "Unable to reach "+result.Reachable, platform)
+	}
+}
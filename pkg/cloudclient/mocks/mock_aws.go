@@ -6,55 +6,160 @@ package mocks
 
 import (
 	context "context"
+	reflect "reflect"
+
 	ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	aws "github.com/aws/aws-sdk-go/aws"
+	request "github.com/aws/aws-sdk-go/aws/request"
+	route53 "github.com/aws/aws-sdk-go/service/route53"
+	route53resolver "github.com/aws/aws-sdk-go/service/route53resolver"
 	gomock "github.com/golang/mock/gomock"
-	reflect "reflect"
 )
 
-// MockEC2Client is a mock of EC2Client interface
+// MockEC2Client is a mock of EC2Client interface.
 type MockEC2Client struct {
 	ctrl     *gomock.Controller
 	recorder *MockEC2ClientMockRecorder
 }
 
-// MockEC2ClientMockRecorder is the mock recorder for MockEC2Client
+// MockEC2ClientMockRecorder is the mock recorder for MockEC2Client.
 type MockEC2ClientMockRecorder struct {
 	mock *MockEC2Client
 }
 
-// NewMockEC2Client creates a new mock instance
+// NewMockEC2Client creates a new mock instance.
 func NewMockEC2Client(ctrl *gomock.Controller) *MockEC2Client {
 	mock := &MockEC2Client{ctrl: ctrl}
 	mock.recorder = &MockEC2ClientMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockEC2Client) EXPECT() *MockEC2ClientMockRecorder {
 	return m.recorder
 }
 
-// RunInstances mocks base method
-func (m *MockEC2Client) RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+// CreateNetworkInsightsPath mocks base method.
+func (m *MockEC2Client) CreateNetworkInsightsPath(ctx context.Context, input *ec2.CreateNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkInsightsPathOutput, error) {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{ctx, params}
+	varargs := []interface{}{ctx, input}
 	for _, a := range optFns {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "RunInstances", varargs...)
-	ret0, _ := ret[0].(*ec2.RunInstancesOutput)
+	ret := m.ctrl.Call(m, "CreateNetworkInsightsPath", varargs...)
+	ret0, _ := ret[0].(*ec2.CreateNetworkInsightsPathOutput)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RunInstances indicates an expected call of RunInstances
-func (mr *MockEC2ClientMockRecorder) RunInstances(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+// CreateNetworkInsightsPath indicates an expected call of CreateNetworkInsightsPath.
+func (mr *MockEC2ClientMockRecorder) CreateNetworkInsightsPath(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{ctx, params}, optFns...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunInstances", reflect.TypeOf((*MockEC2Client)(nil).RunInstances), varargs...)
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNetworkInsightsPath", reflect.TypeOf((*MockEC2Client)(nil).CreateNetworkInsightsPath), varargs...)
+}
+
+// CreateNetworkInterface mocks base method.
+func (m *MockEC2Client) CreateNetworkInterface(ctx context.Context, input *ec2.CreateNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkInterfaceOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateNetworkInterface", varargs...)
+	ret0, _ := ret[0].(*ec2.CreateNetworkInterfaceOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNetworkInterface indicates an expected call of CreateNetworkInterface.
+func (mr *MockEC2ClientMockRecorder) CreateNetworkInterface(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNetworkInterface", reflect.TypeOf((*MockEC2Client)(nil).CreateNetworkInterface), varargs...)
+}
+
+// DeleteNetworkInsightsAnalysis mocks base method.
+func (m *MockEC2Client) DeleteNetworkInsightsAnalysis(ctx context.Context, input *ec2.DeleteNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsAnalysisOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteNetworkInsightsAnalysis", varargs...)
+	ret0, _ := ret[0].(*ec2.DeleteNetworkInsightsAnalysisOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteNetworkInsightsAnalysis indicates an expected call of DeleteNetworkInsightsAnalysis.
+func (mr *MockEC2ClientMockRecorder) DeleteNetworkInsightsAnalysis(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetworkInsightsAnalysis", reflect.TypeOf((*MockEC2Client)(nil).DeleteNetworkInsightsAnalysis), varargs...)
+}
+
+// DeleteNetworkInsightsPath mocks base method.
+func (m *MockEC2Client) DeleteNetworkInsightsPath(ctx context.Context, input *ec2.DeleteNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsPathOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteNetworkInsightsPath", varargs...)
+	ret0, _ := ret[0].(*ec2.DeleteNetworkInsightsPathOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DescribeInstanceStatus mocks base method
+// DeleteNetworkInsightsPath indicates an expected call of DeleteNetworkInsightsPath.
+func (mr *MockEC2ClientMockRecorder) DeleteNetworkInsightsPath(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetworkInsightsPath", reflect.TypeOf((*MockEC2Client)(nil).DeleteNetworkInsightsPath), varargs...)
+}
+
+// DeleteNetworkInterface mocks base method.
+func (m *MockEC2Client) DeleteNetworkInterface(ctx context.Context, input *ec2.DeleteNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteNetworkInterface", varargs...)
+	ret0, _ := ret[0].(*ec2.DeleteNetworkInterfaceOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteNetworkInterface indicates an expected call of DeleteNetworkInterface.
+func (mr *MockEC2ClientMockRecorder) DeleteNetworkInterface(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetworkInterface", reflect.TypeOf((*MockEC2Client)(nil).DeleteNetworkInterface), varargs...)
+}
+
+// DescribeDhcpOptions mocks base method.
+func (m *MockEC2Client) DescribeDhcpOptions(ctx context.Context, input *ec2.DescribeDhcpOptionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeDhcpOptionsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeDhcpOptions", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeDhcpOptionsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeDhcpOptions indicates an expected call of DescribeDhcpOptions.
+func (mr *MockEC2ClientMockRecorder) DescribeDhcpOptions(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeDhcpOptions", reflect.TypeOf((*MockEC2Client)(nil).DescribeDhcpOptions), varargs...)
+}
+
+// DescribeInstanceStatus mocks base method.
 func (m *MockEC2Client) DescribeInstanceStatus(ctx context.Context, input *ec2.DescribeInstanceStatusInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceStatusOutput, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, input}
@@ -67,14 +172,14 @@ func (m *MockEC2Client) DescribeInstanceStatus(ctx context.Context, input *ec2.D
 	return ret0, ret1
 }
 
-// DescribeInstanceStatus indicates an expected call of DescribeInstanceStatus
+// DescribeInstanceStatus indicates an expected call of DescribeInstanceStatus.
 func (mr *MockEC2ClientMockRecorder) DescribeInstanceStatus(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, input}, optFns...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeInstanceStatus", reflect.TypeOf((*MockEC2Client)(nil).DescribeInstanceStatus), varargs...)
 }
 
-// DescribeInstanceTypes mocks base method
+// DescribeInstanceTypes mocks base method.
 func (m *MockEC2Client) DescribeInstanceTypes(ctx context.Context, input *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, input}
@@ -87,14 +192,194 @@ func (m *MockEC2Client) DescribeInstanceTypes(ctx context.Context, input *ec2.De
 	return ret0, ret1
 }
 
-// DescribeInstanceTypes indicates an expected call of DescribeInstanceTypes
+// DescribeInstanceTypes indicates an expected call of DescribeInstanceTypes.
 func (mr *MockEC2ClientMockRecorder) DescribeInstanceTypes(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, input}, optFns...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeInstanceTypes", reflect.TypeOf((*MockEC2Client)(nil).DescribeInstanceTypes), varargs...)
 }
 
-// GetConsoleOutput mocks base method
+// DescribeInternetGateways mocks base method.
+func (m *MockEC2Client) DescribeInternetGateways(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeInternetGateways", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeInternetGatewaysOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeInternetGateways indicates an expected call of DescribeInternetGateways.
+func (mr *MockEC2ClientMockRecorder) DescribeInternetGateways(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeInternetGateways", reflect.TypeOf((*MockEC2Client)(nil).DescribeInternetGateways), varargs...)
+}
+
+// DescribeNetworkAcls mocks base method.
+func (m *MockEC2Client) DescribeNetworkAcls(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeNetworkAcls", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeNetworkAclsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeNetworkAcls indicates an expected call of DescribeNetworkAcls.
+func (mr *MockEC2ClientMockRecorder) DescribeNetworkAcls(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeNetworkAcls", reflect.TypeOf((*MockEC2Client)(nil).DescribeNetworkAcls), varargs...)
+}
+
+// DescribeNetworkInsightsAnalyses mocks base method.
+func (m *MockEC2Client) DescribeNetworkInsightsAnalyses(ctx context.Context, input *ec2.DescribeNetworkInsightsAnalysesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInsightsAnalysesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeNetworkInsightsAnalyses", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeNetworkInsightsAnalysesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeNetworkInsightsAnalyses indicates an expected call of DescribeNetworkInsightsAnalyses.
+func (mr *MockEC2ClientMockRecorder) DescribeNetworkInsightsAnalyses(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeNetworkInsightsAnalyses", reflect.TypeOf((*MockEC2Client)(nil).DescribeNetworkInsightsAnalyses), varargs...)
+}
+
+// DescribeRouteTables mocks base method.
+func (m *MockEC2Client) DescribeRouteTables(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeRouteTables", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeRouteTablesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeRouteTables indicates an expected call of DescribeRouteTables.
+func (mr *MockEC2ClientMockRecorder) DescribeRouteTables(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeRouteTables", reflect.TypeOf((*MockEC2Client)(nil).DescribeRouteTables), varargs...)
+}
+
+// DescribeSecurityGroups mocks base method.
+func (m *MockEC2Client) DescribeSecurityGroups(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeSecurityGroups", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeSecurityGroupsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeSecurityGroups indicates an expected call of DescribeSecurityGroups.
+func (mr *MockEC2ClientMockRecorder) DescribeSecurityGroups(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeSecurityGroups", reflect.TypeOf((*MockEC2Client)(nil).DescribeSecurityGroups), varargs...)
+}
+
+// DescribeSubnets mocks base method.
+func (m *MockEC2Client) DescribeSubnets(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeSubnets", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeSubnetsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeSubnets indicates an expected call of DescribeSubnets.
+func (mr *MockEC2ClientMockRecorder) DescribeSubnets(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeSubnets", reflect.TypeOf((*MockEC2Client)(nil).DescribeSubnets), varargs...)
+}
+
+// DescribeVpcAttribute mocks base method.
+func (m *MockEC2Client) DescribeVpcAttribute(ctx context.Context, input *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeVpcAttribute", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeVpcAttributeOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeVpcAttribute indicates an expected call of DescribeVpcAttribute.
+func (mr *MockEC2ClientMockRecorder) DescribeVpcAttribute(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeVpcAttribute", reflect.TypeOf((*MockEC2Client)(nil).DescribeVpcAttribute), varargs...)
+}
+
+// DescribeVpcEndpoints mocks base method.
+func (m *MockEC2Client) DescribeVpcEndpoints(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeVpcEndpoints", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeVpcEndpointsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeVpcEndpoints indicates an expected call of DescribeVpcEndpoints.
+func (mr *MockEC2ClientMockRecorder) DescribeVpcEndpoints(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeVpcEndpoints", reflect.TypeOf((*MockEC2Client)(nil).DescribeVpcEndpoints), varargs...)
+}
+
+// DescribeVpcs mocks base method.
+func (m *MockEC2Client) DescribeVpcs(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeVpcs", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeVpcsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeVpcs indicates an expected call of DescribeVpcs.
+func (mr *MockEC2ClientMockRecorder) DescribeVpcs(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeVpcs", reflect.TypeOf((*MockEC2Client)(nil).DescribeVpcs), varargs...)
+}
+
+// GetConsoleOutput mocks base method.
 func (m *MockEC2Client) GetConsoleOutput(ctx context.Context, input *ec2.GetConsoleOutputInput, optFns ...func(*ec2.Options)) (*ec2.GetConsoleOutputOutput, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, input}
@@ -107,14 +392,74 @@ func (m *MockEC2Client) GetConsoleOutput(ctx context.Context, input *ec2.GetCons
 	return ret0, ret1
 }
 
-// GetConsoleOutput indicates an expected call of GetConsoleOutput
+// GetConsoleOutput indicates an expected call of GetConsoleOutput.
 func (mr *MockEC2ClientMockRecorder) GetConsoleOutput(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, input}, optFns...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsoleOutput", reflect.TypeOf((*MockEC2Client)(nil).GetConsoleOutput), varargs...)
 }
 
-// TerminateInstances mocks base method
+// GetManagedPrefixListEntries mocks base method.
+func (m *MockEC2Client) GetManagedPrefixListEntries(ctx context.Context, input *ec2.GetManagedPrefixListEntriesInput, optFns ...func(*ec2.Options)) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetManagedPrefixListEntries", varargs...)
+	ret0, _ := ret[0].(*ec2.GetManagedPrefixListEntriesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetManagedPrefixListEntries indicates an expected call of GetManagedPrefixListEntries.
+func (mr *MockEC2ClientMockRecorder) GetManagedPrefixListEntries(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManagedPrefixListEntries", reflect.TypeOf((*MockEC2Client)(nil).GetManagedPrefixListEntries), varargs...)
+}
+
+// RunInstances mocks base method.
+func (m *MockEC2Client) RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RunInstances", varargs...)
+	ret0, _ := ret[0].(*ec2.RunInstancesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunInstances indicates an expected call of RunInstances.
+func (mr *MockEC2ClientMockRecorder) RunInstances(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunInstances", reflect.TypeOf((*MockEC2Client)(nil).RunInstances), varargs...)
+}
+
+// StartNetworkInsightsAnalysis mocks base method.
+func (m *MockEC2Client) StartNetworkInsightsAnalysis(ctx context.Context, input *ec2.StartNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.StartNetworkInsightsAnalysisOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "StartNetworkInsightsAnalysis", varargs...)
+	ret0, _ := ret[0].(*ec2.StartNetworkInsightsAnalysisOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartNetworkInsightsAnalysis indicates an expected call of StartNetworkInsightsAnalysis.
+func (mr *MockEC2ClientMockRecorder) StartNetworkInsightsAnalysis(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartNetworkInsightsAnalysis", reflect.TypeOf((*MockEC2Client)(nil).StartNetworkInsightsAnalysis), varargs...)
+}
+
+// TerminateInstances mocks base method.
 func (m *MockEC2Client) TerminateInstances(ctx context.Context, input *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, input}
@@ -127,29 +472,115 @@ func (m *MockEC2Client) TerminateInstances(ctx context.Context, input *ec2.Termi
 	return ret0, ret1
 }
 
-// TerminateInstances indicates an expected call of TerminateInstances
+// TerminateInstances indicates an expected call of TerminateInstances.
 func (mr *MockEC2ClientMockRecorder) TerminateInstances(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, input}, optFns...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminateInstances", reflect.TypeOf((*MockEC2Client)(nil).TerminateInstances), varargs...)
 }
 
-// DescribeVpcAttribute mocks base method
-func (m *MockEC2Client) DescribeVpcAttribute(ctx context.Context, input *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error) {
+// MockRoute53Client is a mock of Route53Client interface.
+type MockRoute53Client struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoute53ClientMockRecorder
+}
+
+// MockRoute53ClientMockRecorder is the mock recorder for MockRoute53Client.
+type MockRoute53ClientMockRecorder struct {
+	mock *MockRoute53Client
+}
+
+// NewMockRoute53Client creates a new mock instance.
+func NewMockRoute53Client(ctrl *gomock.Controller) *MockRoute53Client {
+	mock := &MockRoute53Client{ctrl: ctrl}
+	mock.recorder = &MockRoute53ClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoute53Client) EXPECT() *MockRoute53ClientMockRecorder {
+	return m.recorder
+}
+
+// ListHostedZonesByVPCWithContext mocks base method.
+func (m *MockRoute53Client) ListHostedZonesByVPCWithContext(ctx aws.Context, input *route53.ListHostedZonesByVPCInput, opts ...request.Option) (*route53.ListHostedZonesByVPCOutput, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, input}
-	for _, a := range optFns {
+	for _, a := range opts {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "DescribeVpcAttribute", varargs...)
-	ret0, _ := ret[0].(*ec2.DescribeVpcAttributeOutput)
+	ret := m.ctrl.Call(m, "ListHostedZonesByVPCWithContext", varargs...)
+	ret0, _ := ret[0].(*route53.ListHostedZonesByVPCOutput)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// DescribeVpcAttribute indicates an expected call of DescribeVpcAttribute
-func (mr *MockEC2ClientMockRecorder) DescribeVpcAttribute(ctx, input interface{}, optFns ...interface{}) *gomock.Call {
+// ListHostedZonesByVPCWithContext indicates an expected call of ListHostedZonesByVPCWithContext.
+func (mr *MockRoute53ClientMockRecorder) ListHostedZonesByVPCWithContext(ctx, input interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{ctx, input}, optFns...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeVpcAttribute", reflect.TypeOf((*MockEC2Client)(nil).DescribeVpcAttribute), varargs...)
+	varargs := append([]interface{}{ctx, input}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHostedZonesByVPCWithContext", reflect.TypeOf((*MockRoute53Client)(nil).ListHostedZonesByVPCWithContext), varargs...)
+}
+
+// MockResolverClient is a mock of ResolverClient interface.
+type MockResolverClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockResolverClientMockRecorder
+}
+
+// MockResolverClientMockRecorder is the mock recorder for MockResolverClient.
+type MockResolverClientMockRecorder struct {
+	mock *MockResolverClient
+}
+
+// NewMockResolverClient creates a new mock instance.
+func NewMockResolverClient(ctrl *gomock.Controller) *MockResolverClient {
+	mock := &MockResolverClient{ctrl: ctrl}
+	mock.recorder = &MockResolverClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResolverClient) EXPECT() *MockResolverClientMockRecorder {
+	return m.recorder
+}
+
+// GetResolverRuleWithContext mocks base method.
+func (m *MockResolverClient) GetResolverRuleWithContext(ctx aws.Context, input *route53resolver.GetResolverRuleInput, opts ...request.Option) (*route53resolver.GetResolverRuleOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetResolverRuleWithContext", varargs...)
+	ret0, _ := ret[0].(*route53resolver.GetResolverRuleOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResolverRuleWithContext indicates an expected call of GetResolverRuleWithContext.
+func (mr *MockResolverClientMockRecorder) GetResolverRuleWithContext(ctx, input interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResolverRuleWithContext", reflect.TypeOf((*MockResolverClient)(nil).GetResolverRuleWithContext), varargs...)
+}
+
+// ListResolverRuleAssociationsWithContext mocks base method.
+func (m *MockResolverClient) ListResolverRuleAssociationsWithContext(ctx aws.Context, input *route53resolver.ListResolverRuleAssociationsInput, opts ...request.Option) (*route53resolver.ListResolverRuleAssociationsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, input}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListResolverRuleAssociationsWithContext", varargs...)
+	ret0, _ := ret[0].(*route53resolver.ListResolverRuleAssociationsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListResolverRuleAssociationsWithContext indicates an expected call of ListResolverRuleAssociationsWithContext.
+func (mr *MockResolverClientMockRecorder) ListResolverRuleAssociationsWithContext(ctx, input interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, input}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListResolverRuleAssociationsWithContext", reflect.TypeOf((*MockResolverClient)(nil).ListResolverRuleAssociationsWithContext), varargs...)
 }
@@ -9,6 +9,8 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	options "github.com/openshift/osd-network-verifier/pkg/options"
+	output "github.com/openshift/osd-network-verifier/pkg/output"
 )
 
 // MockCloudClient is a mock of CloudClient interface.
@@ -35,29 +37,80 @@ func (m *MockCloudClient) EXPECT() *MockCloudClientMockRecorder {
 }
 
 // ByoVPCValidator mocks base method.
-func (m *MockCloudClient) ByoVPCValidator(ctx context.Context) error {
+func (m *MockCloudClient) ByoVPCValidator(ctx context.Context, opts options.ByoVPCOptions) *output.Output {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ByoVPCValidator", ctx)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "ByoVPCValidator", ctx, opts)
+	ret0, _ := ret[0].(*output.Output)
 	return ret0
 }
 
 // ByoVPCValidator indicates an expected call of ByoVPCValidator.
-func (mr *MockCloudClientMockRecorder) ByoVPCValidator(ctx interface{}) *gomock.Call {
+func (mr *MockCloudClientMockRecorder) ByoVPCValidator(ctx, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByoVPCValidator", reflect.TypeOf((*MockCloudClient)(nil).ByoVPCValidator), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByoVPCValidator", reflect.TypeOf((*MockCloudClient)(nil).ByoVPCValidator), ctx, opts)
 }
 
 // ValidateEgress mocks base method.
-func (m *MockCloudClient) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID string) error {
+func (m *MockCloudClient) ValidateEgress(ctx context.Context, opts options.EgressOptions) *output.Output {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ValidateEgress", ctx, vpcSubnetID, cloudImageID)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "ValidateEgress", ctx, opts)
+	ret0, _ := ret[0].(*output.Output)
 	return ret0
 }
 
 // ValidateEgress indicates an expected call of ValidateEgress.
-func (mr *MockCloudClientMockRecorder) ValidateEgress(ctx, vpcSubnetID, cloudImageID interface{}) *gomock.Call {
+func (mr *MockCloudClientMockRecorder) ValidateEgress(ctx, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateEgress", reflect.TypeOf((*MockCloudClient)(nil).ValidateEgress), ctx, vpcSubnetID, cloudImageID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateEgress", reflect.TypeOf((*MockCloudClient)(nil).ValidateEgress), ctx, opts)
+}
+
+// VerifyDns mocks base method.
+func (m *MockCloudClient) VerifyDns(ctx context.Context, opts options.DnsOptions) *output.Output {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyDns", ctx, opts)
+	ret0, _ := ret[0].(*output.Output)
+	return ret0
+}
+
+// VerifyDns indicates an expected call of VerifyDns.
+func (mr *MockCloudClientMockRecorder) VerifyDns(ctx, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyDns", reflect.TypeOf((*MockCloudClient)(nil).VerifyDns), ctx, opts)
+}
+
+// MockCredentials is a mock of Credentials interface.
+type MockCredentials struct {
+	ctrl     *gomock.Controller
+	recorder *MockCredentialsMockRecorder
+}
+
+// MockCredentialsMockRecorder is the mock recorder for MockCredentials.
+type MockCredentialsMockRecorder struct {
+	mock *MockCredentials
+}
+
+// NewMockCredentials creates a new mock instance.
+func NewMockCredentials(ctrl *gomock.Controller) *MockCredentials {
+	mock := &MockCredentials{ctrl: ctrl}
+	mock.recorder = &MockCredentialsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCredentials) EXPECT() *MockCredentialsMockRecorder {
+	return m.recorder
+}
+
+// Platform mocks base method.
+func (m *MockCredentials) Platform() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Platform")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Platform indicates an expected call of Platform.
+func (mr *MockCredentialsMockRecorder) Platform() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Platform", reflect.TypeOf((*MockCredentials)(nil).Platform))
 }
@@ -0,0 +1,94 @@
+package cloudclient
+
+import (
+	"context"
+	"fmt"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	awsCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/aws"
+	azureCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/azure"
+	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Names of the platforms supported out of the box
+const (
+	PlatformAWS   = awsCloudClient.PlatformName
+	PlatformGCP   = gcpCloudClient.PlatformName
+	PlatformAzure = azureCloudClient.PlatformName
+)
+
+// AWSCredentials are the credentials accepted by the AWS provider, see awsCloudClient.Credentials
+type AWSCredentials = awsCloudClient.Credentials
+
+// AWSRole is a role assumed by the AWS provider, see awsCloudClient.Role
+type AWSRole = awsCloudClient.Role
+
+// DefaultAWSRoleSessionName is used for assumed roles when no session name is given
+const DefaultAWSRoleSessionName = awsCloudClient.DefaultRoleSessionName
+
+// GCPCredentials are the credentials accepted by the GCP provider
+type GCPCredentials struct {
+	Credentials *google.Credentials
+}
+
+func (GCPCredentials) Platform() string { return PlatformGCP }
+
+// AzureCredentials are the credentials accepted by the Azure provider: a source of Azure Resource Manager tokens,
+// and the subscription the probe VMs are created in
+type AzureCredentials struct {
+	TokenSource    oauth2.TokenSource
+	SubscriptionID string
+}
+
+func (AzureCredentials) Platform() string { return PlatformAzure }
+
+func init() {
+	Register(PlatformAWS, newAWSClient)
+	Register(PlatformGCP, newGCPClient)
+	Register(PlatformAzure, newAzureClient)
+}
+
+func newAWSClient(ctx context.Context, logger ocmlog.Logger, creds Credentials, config Config) (CloudClient, error) {
+	c, ok := creds.(AWSCredentials)
+	if !ok {
+		return nil, fmt.Errorf("unsupported credentials type %T", creds)
+	}
+
+	client, err := awsCloudClient.NewClient(ctx, logger, c, config.Region, config.InstanceType, config.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func newGCPClient(ctx context.Context, logger ocmlog.Logger, creds Credentials, config Config) (CloudClient, error) {
+	c, ok := creds.(GCPCredentials)
+	if !ok || c.Credentials == nil {
+		return nil, fmt.Errorf("unsupported credentials type %T", creds)
+	}
+
+	client, err := gcpCloudClient.NewClient(ctx, logger, c.Credentials, config.HostProject, config.Region, config.Zone, config.InstanceType, config.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func newAzureClient(ctx context.Context, logger ocmlog.Logger, creds Credentials, config Config) (CloudClient, error) {
+	c, ok := creds.(AzureCredentials)
+	if !ok || c.TokenSource == nil {
+		return nil, fmt.Errorf("unsupported credentials type %T", creds)
+	}
+
+	client, err := azureCloudClient.NewClient(ctx, logger, c.TokenSource, c.SubscriptionID, config.Region, config.InstanceType, config.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
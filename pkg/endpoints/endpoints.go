@@ -0,0 +1,44 @@
+package endpoints
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/openshift/osd-network-verifier/build/config"
+	"gopkg.in/yaml.v2"
+)
+
+// Endpoint is a host the cluster needs to reach and the ports it is reached on
+type Endpoint struct {
+	Host        string `yaml:"host"`
+	Ports       []int  `yaml:"ports"`
+	TLSDisabled bool   `yaml:"tlsDisabled"`
+}
+
+type endpointsConfig struct {
+	Endpoints []Endpoint            `yaml:"endpoints"`
+	Profiles  map[string][]Endpoint `yaml:"profiles"`
+}
+
+// Parse reads an endpoint config in the format of build/config/config.yaml, returning the endpoints shared by
+// every platform and the ones of the platform's profile, and expanding ${VAR} references with the given variables
+func Parse(data []byte, platform string, variables map[string]string) ([]Endpoint, error) {
+	expanded := os.Expand(string(data), func(varName string) string {
+		return variables[varName]
+	})
+	c := endpointsConfig{}
+	if err := yaml.Unmarshal([]byte(expanded), &c); err != nil {
+		return nil, err
+	}
+	profile, ok := c.Profiles[platform]
+	if !ok {
+		return nil, fmt.Errorf("no endpoint profile for platform %q", platform)
+	}
+
+	return append(c.Endpoints, profile...), nil
+}
+
+// Default returns the endpoints the network validator verifies on the platform, see build/config/config.yaml
+func Default(platform string, variables map[string]string) ([]Endpoint, error) {
+	return Parse(config.EndpointsYAML, platform, variables)
+}
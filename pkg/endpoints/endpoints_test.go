@@ -0,0 +1,56 @@
+package endpoints
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	endpoints, err := Default("aws", map[string]string{"AWS_REGION": "us-east-1"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, endpoints)
+
+	hosts := map[string][]int{}
+	for _, e := range endpoints {
+		assert.NotContains(t, e.Host, "$", "variables must be expanded")
+		hosts[e.Host] = e.Ports
+	}
+	assert.Equal(t, []int{443}, hosts["ec2.us-east-1.amazonaws.com"])
+	assert.Equal(t, []int{443, 80}, hosts["sso.redhat.com"])
+}
+
+func TestDefaultGCP(t *testing.T) {
+	endpoints, err := Default("gcp", map[string]string{"GCP_REGION": "us-east1"})
+	assert.NoError(t, err)
+
+	hosts := map[string][]int{}
+	for _, e := range endpoints {
+		assert.NotContains(t, e.Host, "$", "variables must be expanded")
+		assert.NotContains(t, e.Host, "ec2.", "AWS endpoints must not be verified on GCP")
+		hosts[e.Host] = e.Ports
+	}
+	assert.Equal(t, []int{443}, hosts["compute.googleapis.com"])
+	assert.Equal(t, []int{443}, hosts["us-east1-docker.pkg.dev"])
+	assert.Equal(t, []int{443, 80}, hosts["sso.redhat.com"])
+}
+
+func TestDefaultAzure(t *testing.T) {
+	endpoints, err := Default("azure", map[string]string{"AZURE_REGION": "eastus"})
+	assert.NoError(t, err)
+
+	hosts := map[string][]int{}
+	for _, e := range endpoints {
+		assert.NotContains(t, e.Host, "$", "variables must be expanded")
+		assert.NotEqual(t, "compute.googleapis.com", e.Host, "GCP endpoints must not be verified on Azure")
+		hosts[e.Host] = e.Ports
+	}
+	assert.Equal(t, []int{443}, hosts["management.azure.com"])
+	assert.Equal(t, []int{443}, hosts["arosvc.eastus.data.azurecr.io"])
+	assert.Equal(t, []int{443, 80}, hosts["sso.redhat.com"])
+}
+
+func TestParseUnknownPlatform(t *testing.T) {
+	_, err := Parse([]byte("endpoints:\n  - host: quay.io\n    ports: [443]\nprofiles:\n  aws: []\n"), "openstack", nil)
+	assert.EqualError(t, err, `no endpoint profile for platform "openstack"`)
+}
@@ -0,0 +1,48 @@
+package endpoints
+
+import (
+	"context"
+	"net"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// Hosts returns the normalized host of every endpoint
+func Hosts(eps []Endpoint) []string {
+	var hosts []string
+	for _, e := range eps {
+		hosts = append(hosts, helpers.NormalizeDomain(e.Host))
+	}
+
+	return hosts
+}
+
+// ResolveIPv4 returns the IPv4 addresses of every endpoint host found by lookup, unresolvable hosts are reported as
+// warnings of the check
+func ResolveIPv4(ctx context.Context, logger ocmlog.Logger, out *output.Output, check string, eps []Endpoint, lookup func(string) ([]net.IP, error)) map[string][]net.IP {
+	addresses := map[string][]net.IP{}
+	for _, e := range eps {
+		if _, done := addresses[e.Host]; done {
+			continue
+		}
+		ips, err := lookup(e.Host)
+		if err != nil {
+			logger.Debug(ctx, "Unable to resolve %s: %s", e.Host, err)
+		}
+		var ipv4 []net.IP
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				ipv4 = append(ipv4, ip)
+			}
+		}
+		if len(ipv4) == 0 {
+			out.AddFinding(output.Finding{Check: check, Resource: e.Host, Status: output.StatusWarning,
+				Message: "host could not be resolved to an IPv4 address and was not evaluated"})
+		}
+		addresses[e.Host] = ipv4
+	}
+
+	return addresses
+}
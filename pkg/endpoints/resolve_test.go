@@ -0,0 +1,47 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts(t *testing.T) {
+	assert.Equal(t, []string{"api.example.com", "quay.io"}, Hosts([]Endpoint{{Host: "API.example.com."}, {Host: "quay.io"}}))
+}
+
+func TestResolveIPv4(t *testing.T) {
+	lookups := 0
+	lookup := func(host string) ([]net.IP, error) {
+		lookups++
+		switch host {
+		case "quay.io":
+			return []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("203.0.113.10")}, nil
+		case "ipv6.example.com":
+			return []net.IP{net.ParseIP("2001:db8::2")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	eps := []Endpoint{{Host: "quay.io", Ports: []int{443}}, {Host: "quay.io", Ports: []int{80}},
+		{Host: "ipv6.example.com"}, {Host: "unknown.example.com"}}
+
+	out := output.Output{}
+	addresses := ResolveIPv4(context.TODO(), &ocmlog.StdLogger{}, &out, "firewall-egress", eps, lookup)
+
+	assert.Equal(t, 3, lookups, "every host is looked up once")
+	assert.Equal(t, []net.IP{net.ParseIP("203.0.113.10")}, addresses["quay.io"])
+	assert.Empty(t, addresses["ipv6.example.com"])
+	assert.Empty(t, addresses["unknown.example.com"])
+	var unresolved []string
+	for _, f := range out.Findings() {
+		assert.Equal(t, "firewall-egress", f.Check)
+		assert.Equal(t, output.StatusWarning, f.Status)
+		unresolved = append(unresolved, f.Resource)
+	}
+	assert.Equal(t, []string{"ipv6.example.com", "unknown.example.com"}, unresolved)
+}
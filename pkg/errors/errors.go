@@ -21,6 +21,18 @@ func NewEgressURLError(message string) error {
 	}
 }
 
+type ConfigurationError struct {
+	e string
+}
+
+func (e *ConfigurationError) Error() string { return e.e }
+
+func NewConfigurationError(message string) error {
+	return &ConfigurationError{
+		e: fmt.Sprintf("configuration error: %s", message),
+	}
+}
+
 type GenericError struct {
 	message string
 }
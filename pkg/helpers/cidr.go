@@ -0,0 +1,36 @@
+package helpers
+
+import (
+	"fmt"
+	"net"
+)
+
+// ParseCIDRs parses every given CIDR, returning an error naming the first invalid one
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// CIDRContains returns true if inner is entirely within outer
+func CIDRContains(outer, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	if outerBits != innerBits || innerOnes < outerOnes {
+		return false
+	}
+
+	return outer.Contains(inner.IP)
+}
+
+// CIDROverlaps returns true if the two networks share at least one address
+func CIDROverlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
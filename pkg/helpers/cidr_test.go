@@ -0,0 +1,35 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func TestCIDRHelpers(t *testing.T) {
+	nets, err := ParseCIDRs("10.0.0.0/16", "10.0.1.0/24", "10.1.0.0/24", "10.0.0.0/8", "fd00::/8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vpc, subnet, outside, wide, v6 := nets[0], nets[1], nets[2], nets[3], nets[4]
+
+	tests := []struct {
+		name   string
+		got    bool
+		expect bool
+	}{
+		{name: "vpc contains subnet", got: CIDRContains(vpc, subnet), expect: true},
+		{name: "subnet does not contain vpc", got: CIDRContains(subnet, vpc), expect: false},
+		{name: "vpc does not contain outside", got: CIDRContains(vpc, outside), expect: false},
+		{name: "ipv4 does not contain ipv6", got: CIDRContains(wide, v6), expect: false},
+		{name: "wide overlaps subnet", got: CIDROverlaps(subnet, wide), expect: true},
+		{name: "vpc does not overlap outside", got: CIDROverlaps(vpc, outside), expect: false},
+	}
+	for _, test := range tests {
+		if test.got != test.expect {
+			t.Errorf("%s: expected %t", test.name, test.expect)
+		}
+	}
+
+	if _, err := ParseCIDRs("10.0.0.0/33"); err == nil {
+		t.Errorf("expected an error for an invalid CIDR")
+	}
+}
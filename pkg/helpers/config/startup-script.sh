@@ -0,0 +1,11 @@
+#!/bin/bash
+# Startup script of the GCP probe instance, it runs on Container-Optimized OS which ships docker.
+# The output is copied to the first serial port, where the client collects it.
+{
+  echo "${USERDATA_BEGIN}"
+  docker pull ${VALIDATOR_IMAGE}
+  # Use `|| echo` to ignore failure exit codes, we want the script to continue either way
+  docker run --env "PLATFORM=${PLATFORM}" --env "GCP_REGION=${GCP_REGION}" -e "START_VERIFIER=${VALIDATOR_START_VERIFIER}" -e "END_VERIFIER=${VALIDATOR_END_VERIFIER}" ${VALIDATOR_IMAGE} --timeout=${TIMEOUT} || echo "Failed to successfully run the docker container"
+  echo "${USERDATA_END}"
+} > /var/log/userdata-output 2>&1
+cat /var/log/userdata-output > /dev/ttyS0
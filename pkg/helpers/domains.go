@@ -0,0 +1,77 @@
+package helpers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NormalizeDomain lowercases the domain and removes its trailing dot, "." becomes ""
+func NormalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+// DomainCovers returns true if name is the domain or one of its subdomains, "" covers every name
+func DomainCovers(domain, name string) bool {
+	return domain == "" || name == domain || strings.HasSuffix(name, "."+domain)
+}
+
+// CoveredNames returns the names covered by the domain
+func CoveredNames(domain string, names []string) []string {
+	var covered []string
+	for _, name := range names {
+		if DomainCovers(domain, name) {
+			covered = append(covered, name)
+		}
+	}
+
+	return covered
+}
+
+// WithoutNames returns the names which are not excluded
+func WithoutNames(names, excluded []string) []string {
+	var kept []string
+	for _, name := range names {
+		if !Contains(excluded, name) {
+			kept = append(kept, name)
+		}
+	}
+
+	return kept
+}
+
+// Contains returns true if value is one of the values
+func Contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PortRangesContain returns true if the port is within any of the ranges, given as "443", "8000-9000" or "*".
+// Invalid ranges never match.
+func PortRangesContain(ranges []string, port int) bool {
+	for _, r := range ranges {
+		if r == "*" {
+			return true
+		}
+		bounds := strings.SplitN(r, "-", 2)
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		if from <= port && port <= to {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainHelpers(t *testing.T) {
+	assert.Equal(t, "example.com", NormalizeDomain("Example.COM."))
+	assert.Equal(t, "", NormalizeDomain("."))
+
+	assert.True(t, DomainCovers("example.com", "example.com"))
+	assert.True(t, DomainCovers("example.com", "api.example.com"))
+	assert.True(t, DomainCovers("", "api.example.com"), "the root covers every name")
+	assert.False(t, DomainCovers("example.com", "badexample.com"))
+	assert.False(t, DomainCovers("api.example.com", "example.com"))
+
+	names := []string{"api.example.com", "example.org", "example.com"}
+	assert.Equal(t, []string{"api.example.com", "example.com"}, CoveredNames("example.com", names))
+	assert.Empty(t, CoveredNames("example.net", names))
+	assert.Equal(t, []string{"example.org"}, WithoutNames(names, []string{"api.example.com", "example.com"}))
+}
+
+func TestPortRangesContain(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []string
+		port   int
+		expect bool
+	}{
+		{name: "single port", ranges: []string{"443"}, port: 443, expect: true},
+		{name: "other port", ranges: []string{"80"}, port: 443, expect: false},
+		{name: "within range", ranges: []string{"22", "400-500"}, port: 443, expect: true},
+		{name: "range bounds", ranges: []string{"443-443"}, port: 443, expect: true},
+		{name: "any port", ranges: []string{"*"}, port: 443, expect: true},
+		{name: "invalid range", ranges: []string{"https", "400-x"}, port: 443, expect: false},
+		{name: "no ranges", ranges: nil, port: 443, expect: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expect, PortRangesContain(test.ranges, test.port), test.name)
+	}
+}
@@ -10,6 +10,12 @@ import (
 //go:embed config/userdata.yaml
 var UserdataTemplate string
 
+//go:embed config/startup-script.sh
+var StartupScriptTemplate string
+
+//go:embed config/custom-data.yaml
+var CustomDataTemplate string
+
 func PollImmediate(interval time.Duration, timeout time.Duration, condition func() (bool, error)) error {
 
 	var totalTime time.Duration = 0
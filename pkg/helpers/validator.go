@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NetworkValidatorImage is the network validator image the probes run. Its tag is the one `make osd-container-image-build`
+// gives the image built at the last commit changing build/, since the probes parse the output of build/bin/network-validator.go
+// and verify the endpoints of build/config/config.yaml: bump it whenever either changes.
+const NetworkValidatorImage = "quay.io/app-sre/osd-network-verifier:v0.1.34-7b4bd24"
+
+// ValidatorProfileLine returns the line the network validator prints before verifying the endpoints of the platform's profile
+func ValidatorProfileLine(platform string) string {
+	return fmt.Sprintf("Using the %s endpoint profile", platform)
+}
+
+// ValidatorIgnoredProfile returns true if the network validator completed without verifying the endpoint profile of the platform.
+// Validator images predating the endpoint profiles, e.g. a stale image, ignore the platform and verify the AWS endpoints.
+func ValidatorIgnoredProfile(probeOutput, platform string) bool {
+	completed := strings.Contains(probeOutput, "Success!") || strings.Contains(probeOutput, "Not all endpoints were reachable")
+
+	return completed && !strings.Contains(probeOutput, ValidatorProfileLine(platform))
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestValidatorIgnoredProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		probeOutput string
+		expect      bool
+	}{
+		{name: "profile verified", probeOutput: "Using the gcp endpoint profile\nValidating quay.io:443\nSuccess!\n", expect: false},
+		{name: "profile ignored", probeOutput: "Validating quay.io:443\nSuccess!\n", expect: true},
+		{name: "profile ignored with failures", probeOutput: "Not all endpoints were reachable:\nUnable to reach quay.io:443\n", expect: true},
+		{name: "other profile", probeOutput: "Using the aws endpoint profile\nSuccess!\n", expect: true},
+		{name: "validator did not complete", probeOutput: "Failed to successfully run the docker container\n", expect: false},
+	}
+	for _, test := range tests {
+		if got := ValidatorIgnoredProfile(test.probeOutput, "gcp"); got != test.expect {
+			t.Errorf("%s: expected %t", test.name, test.expect)
+		}
+	}
+}
+
+func TestNetworkValidatorImageIsCurrent(t *testing.T) {
+	// the image is built from build/, so its tag must name the last commit changing it
+	out, err := exec.Command("git", "rev-parse", "--is-shallow-repository").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "false" {
+		t.Skip("the history of build/ is not available")
+	}
+	out, err = exec.Command("git", "log", "-1", "--format=%h", "--abbrev=7", "--", "../../build").Output()
+	if err != nil {
+		t.Fatalf("unable to read the history of build/: %v", err)
+	}
+	commit := strings.TrimSpace(string(out))
+	if !strings.HasSuffix(NetworkValidatorImage, "-"+commit) {
+		t.Errorf("%s was not built from the last commit changing build/, %s: build and push the image, then bump its tag",
+			NetworkValidatorImage, commit)
+	}
+}
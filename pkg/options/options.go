@@ -0,0 +1,365 @@
+package options
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+)
+
+// Version identifies the revision of an options struct. New fields are only ever added to
+// the structs in this package, so callers built against an older version keep working.
+type Version string
+
+const (
+	// V1 is the first revision of the options structs
+	V1 Version = "v1"
+
+	// CurrentVersion is assumed when the caller leaves the version empty
+	CurrentVersion = V1
+
+	// DefaultEgressTimeout is the timeout for individual egress requests when none is given
+	DefaultEgressTimeout = 2 * time.Second
+
+	// DefaultServiceCIDR and DefaultPodCIDR are the OpenShift defaults for the cluster networks
+	DefaultServiceCIDR = "172.30.0.0/16"
+	DefaultPodCIDR     = "10.128.0.0/14"
+
+	// DefaultExpectedNodes covers the control plane, infra and worker nodes of a minimal multi-AZ cluster
+	DefaultExpectedNodes = 9
+	// DefaultLoadBalancerIPs is the number of free addresses AWS requires in each subnet of a load balancer
+	DefaultLoadBalancerIPs = 8
+)
+
+var supportedVersions = map[Version]bool{
+	V1: true,
+}
+
+func validateVersion(v Version) error {
+	if !supportedVersions[v] {
+		return fmt.Errorf("unsupported options version %q", v)
+	}
+
+	return nil
+}
+
+// EgressMode selects how egress is verified
+type EgressMode string
+
+const (
+	// EgressModeInstance launches a probe instance that connects to every endpoint
+	EgressModeInstance EgressMode = "instance"
+	// EgressModeStatic evaluates the network configuration without launching anything
+	EgressModeStatic EgressMode = "static"
+	// EgressModeReachability runs the cloud provider's path analysis from the subnet to the internet
+	EgressModeReachability EgressMode = "reachability"
+)
+
+var supportedEgressModes = map[EgressMode]bool{
+	EgressModeInstance:     true,
+	EgressModeStatic:       true,
+	EgressModeReachability: true,
+}
+
+// EgressOptions holds the parameters of an egress verification
+type EgressOptions struct {
+	Version Version
+
+	// Mode defaults to EgressModeInstance
+	Mode EgressMode
+	// SubnetID is the subnet the probe instance is launched into
+	SubnetID string
+	// SecurityGroupIDs are the (optional) security groups of the probe, defaults to the VPC's default security group
+	SecurityGroupIDs []string
+	// Endpoints are the (optional) egress endpoints to verify in static mode, defaults to build/config/config.yaml
+	Endpoints []endpoints.Endpoint
+	// CloudImageID is the (optional) image used by the probe instance
+	CloudImageID string
+	// KmsKeyID is the (optional) key used to encrypt the probe instance's root volume
+	KmsKeyID string
+	// Timeout applies to each individual egress request
+	Timeout time.Duration
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *EgressOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+	if o.Mode == "" {
+		o.Mode = EgressModeInstance
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultEgressTimeout
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *EgressOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if !supportedEgressModes[o.Mode] {
+		return fmt.Errorf("unsupported egress mode %q", o.Mode)
+	}
+	if o.SubnetID == "" {
+		return fmt.Errorf("a subnet ID is required for egress verification")
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("egress timeout must not be negative, got %s", o.Timeout)
+	}
+
+	return nil
+}
+
+// DnsOptions holds the parameters of a VPC DNS verification
+type DnsOptions struct {
+	Version Version
+
+	// VpcID is the VPC (or network) under test
+	VpcID string
+	// SubnetID is an (optional) subnet of the VPC, when set a probe instance launched into it
+	// verifies that every egress endpoint resolves through the VPC resolver
+	SubnetID string
+	// CloudImageID is the (optional) image used by the probe instance
+	CloudImageID string
+	// KmsKeyID is the (optional) key used to encrypt the probe instance's root volume
+	KmsKeyID string
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *DnsOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *DnsOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if o.VpcID == "" {
+		return fmt.Errorf("a VPC ID is required for DNS verification")
+	}
+
+	return nil
+}
+
+// ByoVPCOptions holds the parameters of a BYOVPC configuration verification
+type ByoVPCOptions struct {
+	Version Version
+
+	// VpcID is the (optional) VPC the subnets must belong to. If empty, the VPC of the first subnet is used.
+	VpcID string
+	// SubnetIDs are the subnets the cluster will be installed into
+	SubnetIDs []string
+	// Private is true for PrivateLink clusters, which only use private subnets.
+	// Public clusters need a public and a private subnet in every availability zone.
+	Private bool
+	// MachineCIDR is the (optional) machine network, it must contain every subnet
+	MachineCIDR string
+	// ServiceCIDR and PodCIDR must not overlap with the VPC
+	ServiceCIDR string
+	PodCIDR     string
+	// ExpectedNodes is the number of nodes the cluster is expected to scale to, spread across the private subnets
+	ExpectedNodes int
+	// LoadBalancerIPs is the number of addresses kept free in every subnet for the load balancers
+	LoadBalancerIPs int
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *ByoVPCOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+	if o.ServiceCIDR == "" {
+		o.ServiceCIDR = DefaultServiceCIDR
+	}
+	if o.PodCIDR == "" {
+		o.PodCIDR = DefaultPodCIDR
+	}
+	if o.ExpectedNodes == 0 {
+		o.ExpectedNodes = DefaultExpectedNodes
+	}
+	if o.LoadBalancerIPs == 0 {
+		o.LoadBalancerIPs = DefaultLoadBalancerIPs
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *ByoVPCOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if len(o.SubnetIDs) == 0 {
+		return fmt.Errorf("at least one subnet ID is required for BYOVPC verification")
+	}
+	for _, cidr := range []string{o.MachineCIDR, o.ServiceCIDR, o.PodCIDR} {
+		if cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+	}
+	if o.ExpectedNodes < 0 || o.LoadBalancerIPs < 0 {
+		return fmt.Errorf("expected nodes and load balancer IPs must not be negative, got %d and %d", o.ExpectedNodes, o.LoadBalancerIPs)
+	}
+
+	return nil
+}
+
+// RoutesOptions holds the parameters of a route table path analysis
+type RoutesOptions struct {
+	Version Version
+
+	// SubnetIDs are the subnets whose default routes are analyzed
+	SubnetIDs []string
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *RoutesOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *RoutesOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if len(o.SubnetIDs) == 0 {
+		return fmt.Errorf("at least one subnet ID is required for route analysis")
+	}
+
+	return nil
+}
+
+// NetworkACLOptions holds the parameters of a static network ACL evaluation
+type NetworkACLOptions struct {
+	Version Version
+
+	// SubnetIDs are the subnets whose network ACLs are evaluated
+	SubnetIDs []string
+	// Endpoints are the (optional) egress endpoints to evaluate, defaults to build/config/config.yaml
+	Endpoints []endpoints.Endpoint
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *NetworkACLOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *NetworkACLOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if len(o.SubnetIDs) == 0 {
+		return fmt.Errorf("at least one subnet ID is required for network ACL evaluation")
+	}
+
+	return nil
+}
+
+// SecurityGroupOptions holds the parameters of a static security group evaluation
+type SecurityGroupOptions struct {
+	Version Version
+
+	// SecurityGroupIDs are evaluated together, as if they were all attached to the same instance
+	SecurityGroupIDs []string
+	// VpcID is used to look up the VPC's default security group when no security group is given
+	VpcID string
+	// Endpoints are the (optional) egress endpoints to evaluate, defaults to build/config/config.yaml
+	Endpoints []endpoints.Endpoint
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *SecurityGroupOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *SecurityGroupOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if len(o.SecurityGroupIDs) == 0 && o.VpcID == "" {
+		return fmt.Errorf("either security group IDs or a VPC ID is required for security group evaluation")
+	}
+
+	return nil
+}
+
+// VpcEndpointOptions holds the parameters of a VPC endpoint verification
+type VpcEndpointOptions struct {
+	Version Version
+
+	// VpcID is the VPC whose endpoints are verified
+	VpcID string
+	// SubnetIDs are the (optional) subnets whose route tables must be associated with the gateway endpoints
+	SubnetIDs []string
+	// Endpoints are the (optional) egress endpoints mapped to the VPC endpoints, defaults to build/config/config.yaml
+	Endpoints []endpoints.Endpoint
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *VpcEndpointOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *VpcEndpointOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if o.VpcID == "" {
+		return fmt.Errorf("a VPC ID is required for VPC endpoint verification")
+	}
+
+	return nil
+}
+
+// FirewallOptions holds the parameters of a static GCP firewall evaluation
+type FirewallOptions struct {
+	Version Version
+
+	// VpcID is the network whose firewall rules and hierarchical firewall policies are evaluated
+	VpcID string
+	// NetworkTags are the (optional) network tags of the cluster's instances, rules targeting other tags are ignored.
+	// Defaults to the network tags the client derives from its cloud tags.
+	NetworkTags []string
+	// ServiceAccount is the (optional) service account email of the cluster's instances,
+	// rules targeting other service accounts are ignored
+	ServiceAccount string
+	// Endpoints are the (optional) egress endpoints to evaluate, defaults to build/config/config.yaml
+	Endpoints []endpoints.Endpoint
+}
+
+// SetDefaults fills in every field the caller left empty
+func (o *FirewallOptions) SetDefaults() {
+	if o.Version == "" {
+		o.Version = CurrentVersion
+	}
+}
+
+// Validate returns an error describing the first invalid field, if any
+func (o *FirewallOptions) Validate() error {
+	if err := validateVersion(o.Version); err != nil {
+		return err
+	}
+	if o.VpcID == "" {
+		return fmt.Errorf("a network is required for firewall evaluation")
+	}
+
+	return nil
+}
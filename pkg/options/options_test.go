@@ -0,0 +1,66 @@
+package options
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEgressOptionsDefaults(t *testing.T) {
+	opts := EgressOptions{SubnetID: "subnet-id"}
+	opts.SetDefaults()
+	if opts.Version != CurrentVersion {
+		t.Errorf("unexpected version: %v", opts.Version)
+	}
+	if opts.Mode != EgressModeInstance {
+		t.Errorf("unexpected mode: %v", opts.Mode)
+	}
+	if opts.Timeout != DefaultEgressTimeout {
+		t.Errorf("unexpected timeout: %v", opts.Timeout)
+	}
+	if err := opts.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// explicit values must not be overridden
+	opts = EgressOptions{SubnetID: "subnet-id", Timeout: 5 * time.Second}
+	opts.SetDefaults()
+	if opts.Timeout != 5*time.Second {
+		t.Errorf("timeout should not have been overridden: %v", opts.Timeout)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      interface{ Validate() error }
+		expectErr bool
+	}{
+		{name: "egress without subnet", opts: &EgressOptions{Version: V1, Mode: EgressModeInstance}, expectErr: true},
+		{name: "egress with negative timeout", opts: &EgressOptions{Version: V1, Mode: EgressModeInstance, SubnetID: "s", Timeout: -time.Second}, expectErr: true},
+		{name: "egress with unknown version", opts: &EgressOptions{Version: "v0", Mode: EgressModeInstance, SubnetID: "s"}, expectErr: true},
+		{name: "egress with unknown mode", opts: &EgressOptions{Version: V1, Mode: "telepathy", SubnetID: "s"}, expectErr: true},
+		{name: "egress", opts: &EgressOptions{Version: V1, Mode: EgressModeStatic, SubnetID: "s"}},
+		{name: "dns without vpc", opts: &DnsOptions{Version: V1}, expectErr: true},
+		{name: "dns", opts: &DnsOptions{Version: V1, VpcID: "vpc"}},
+		{name: "byovpc without version", opts: &ByoVPCOptions{SubnetIDs: []string{"s"}}, expectErr: true},
+		{name: "byovpc without subnets", opts: &ByoVPCOptions{Version: V1}, expectErr: true},
+		{name: "byovpc with invalid cidr", opts: &ByoVPCOptions{Version: V1, SubnetIDs: []string{"s"}, PodCIDR: "10.0.0.0"}, expectErr: true},
+		{name: "byovpc with negative node count", opts: &ByoVPCOptions{Version: V1, SubnetIDs: []string{"s"}, ExpectedNodes: -1}, expectErr: true},
+		{name: "byovpc", opts: &ByoVPCOptions{Version: V1, SubnetIDs: []string{"s"}, MachineCIDR: "10.0.0.0/16"}},
+		{name: "security groups without groups or vpc", opts: &SecurityGroupOptions{Version: V1}, expectErr: true},
+		{name: "security groups of the default vpc group", opts: &SecurityGroupOptions{Version: V1, VpcID: "vpc"}},
+		{name: "vpc endpoints without vpc", opts: &VpcEndpointOptions{Version: V1, SubnetIDs: []string{"s"}}, expectErr: true},
+		{name: "vpc endpoints", opts: &VpcEndpointOptions{Version: V1, VpcID: "vpc"}},
+		{name: "firewall without network", opts: &FirewallOptions{Version: V1, NetworkTags: []string{"worker"}}, expectErr: true},
+		{name: "firewall", opts: &FirewallOptions{Version: V1, VpcID: "network"}},
+	}
+	for _, test := range tests {
+		err := test.opts.Validate()
+		if test.expectErr && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+		if !test.expectErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
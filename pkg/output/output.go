@@ -6,14 +6,40 @@ import (
 	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
 )
 
+// Status is the outcome of verifying a single requirement
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusWarning Status = "warning"
+	StatusFailed  Status = "failed"
+	StatusUnknown Status = "unknown"
+)
+
+// Finding is the result of verifying a single requirement against a single resource
+type Finding struct {
+	// Check names the requirement, e.g. "subnet-in-vpc"
+	Check string
+	// Resource is the ID of the cloud resource the finding applies to
+	Resource string
+	Status   Status
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s %s: %s", f.Status, f.Check, f.Resource, f.Message)
+}
+
 // Output can be used when showcasing validation results at the end of the execution.
 // `failures` represents the failed validation tests
 // `exceptions` is to show edge cases where onv couldn't be ended up as expected
 // `errors` is collection of unhandled errors
+// `findings` holds the outcome of every individual requirement that was checked
 type Output struct {
 	failures   []error
 	exceptions []error
 	errors     []error
+	findings   []Finding
 }
 
 // AddError adds error as generic to the list of errors
@@ -37,6 +63,30 @@ func (o *Output) SetEgressFailures(failures []string) {
 	}
 }
 
+// AddFinding records the outcome of a requirement check, failed findings are also added to the failures
+func (o *Output) AddFinding(f Finding) {
+	o.findings = append(o.findings, f)
+	if f.Status == StatusFailed {
+		o.failures = append(o.failures, handledErrors.NewConfigurationError(fmt.Sprintf("%s %s: %s", f.Check, f.Resource, f.Message)))
+	}
+}
+
+// AddUnknownFinding records a requirement that could not be verified because of a cloud API error, given by its class
+// and code, e.g. "access denied" and "AccessDenied". The error itself is not added, the caller decides whether it is fatal.
+func (o *Output) AddUnknownFinding(check, resource, action, class, code string) {
+	reason := class
+	if code != "" {
+		reason = fmt.Sprintf("%s (%s)", class, code)
+	}
+	o.AddFinding(Finding{Check: check, Resource: resource, Status: StatusUnknown,
+		Message: fmt.Sprintf("unable to %s: %s", action, reason)})
+}
+
+// Findings returns the outcome of every requirement that was checked
+func (o *Output) Findings() []Finding {
+	return o.findings
+}
+
 // IsSuccessful checks whether the output contains any item, returns false if there's any
 func (o *Output) IsSuccessful() bool {
 	if len(o.errors) > 0 || len(o.exceptions) > 0 || len(o.failures) > 0 {
@@ -46,6 +96,13 @@ func (o *Output) IsSuccessful() bool {
 	return true
 }
 
+func (o *Output) printFindings() {
+	fmt.Println("printing out checked requirements:")
+	for _, f := range o.findings {
+		fmt.Println(" - ", f)
+	}
+}
+
 func (o *Output) printFailures() {
 	fmt.Println("printing out failures:")
 	for _, v := range o.failures {
@@ -70,6 +127,9 @@ func (o *Output) printErrors() {
 // Summary can be used for printing out output structure
 func (o *Output) Summary() {
 	fmt.Println("Summary:")
+	if len(o.findings) > 0 {
+		o.printFindings()
+	}
 	if o.IsSuccessful() {
 		fmt.Println("All tests pass!")
 	} else {